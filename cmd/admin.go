@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/config"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+const (
+	// adminChatsPageSize — чатов на странице /admin_chats.
+	adminChatsPageSize = 20
+	// broadcastInterval — пауза между сообщениями рассылки: Bot API допускает около 30 сообщений
+	// в секунду в разные чаты, рассылка идёт заметно медленнее, чтобы не мешать работе бота.
+	broadcastInterval = 100 * time.Millisecond
+	// maxBroadcastRetryAfter — дольше этого рассылка не ждёт по ответу 429 Too Many Requests.
+	maxBroadcastRetryAfter = time.Minute
+)
+
+// broadcasting — идёт рассылка.
+var broadcasting atomic.Bool
+
+// broadcastDraft — текст рассылки между предпросмотром и подтверждением (сессия sessionBroadcast).
+type broadcastDraft struct {
+	Text string `json:"text"`
+}
+
+// isAdmin сообщает, является ли пользователь администратором бота (ADMIN_ID).
+func isAdmin(userID int64) bool {
+	cfg := config.GetConfig()
+	return cfg != nil && cfg.IsAdmin(userID)
+}
+
+// ──────────────────────────── /admin_* ────────────────────────────
+
+// handleAdmin обрабатывает команды администратора бота. Они доступны только администраторам
+// (ADMIN_ID) в личном чате с ботом; для остальных бот ведёт себя так, будто команды нет.
+//
+//	/admin_stats              — сводка по всем чатам
+//	/admin_chats [страница]   — чаты с числом событий
+//	/admin_broadcast <текст>  — рассылка во все чаты (после предпросмотра)
+//	/admin_purge_chat <id>    — удаление всех данных чата (после подтверждения)
+func handleAdmin(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+	command, rest, _ := strings.Cut(normalizeCommand(msg.Text), " ")
+	if msg.From == nil || msg.Chat.Type != tgmodels.ChatTypePrivate || !isAdmin(msg.From.ID) {
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	rest = strings.TrimSpace(rest)
+	log := WithComponent("admin").WithField("user_id", msg.From.ID)
+
+	switch command {
+	case "/admin_stats":
+		log.Info("Запрошена сводка")
+		sendAdminStats(ctx, b, msg.Chat.ID)
+	case "/admin_chats":
+		page := 1
+		if rest != "" {
+			p, err := strconv.Atoi(rest)
+			if err != nil || p < 1 {
+				sendMessage(ctx, b, msg.Chat.ID, "Используйте формат: /admin_chats [номер страницы]")
+				return
+			}
+			page = p
+		}
+		text, kb, err := adminChatsPage(ctx, page)
+		if err != nil {
+			log.Errorf("Ошибка получения списка чатов: %v", err)
+			sendMessage(ctx, b, msg.Chat.ID, "Ошибка при получении списка чатов")
+			return
+		}
+		sendAdminMessage(ctx, b, msg.Chat.ID, text, kb)
+	case "/admin_broadcast":
+		previewBroadcast(ctx, b, msg, rest)
+	case "/admin_purge_chat":
+		confirmPurgeChat(ctx, b, msg.Chat.ID, rest)
+	default:
+		handleDynamicOrUnknown(ctx, b, update)
+	}
+}
+
+// sendAdminMessage отправляет сообщение администратору с HTML-разметкой и необязательной клавиатурой.
+func sendAdminMessage(ctx context.Context, b *bot.Bot, chatID int64, text string, kb *tgmodels.InlineKeyboardMarkup) {
+	params := &bot.SendMessageParams{ChatID: chatID, Text: text, ParseMode: tgmodels.ParseModeHTML}
+	if kb != nil {
+		params.ReplyMarkup = kb
+	}
+	if _, err := b.SendMessage(ctx, params); err != nil {
+		logger.Errorf("Ошибка отправки сообщения администратору chat_id=%d: %v", chatID, err)
+	}
+}
+
+// sendAdminStats отправляет сводку по всем чатам и работе бота.
+func sendAdminStats(ctx context.Context, b *bot.Bot, chatID int64) {
+	st, err := store.Stats(ctx)
+	if err != nil {
+		logger.Errorf("Ошибка получения сводки: %v", err)
+		sendMessage(ctx, b, chatID, "Ошибка при получении сводки")
+		return
+	}
+	sendAdminMessage(ctx, b, chatID, fmt.Sprintf("📊 <b>Сводка</b>\n"+
+		"Чатов: %d\n"+
+		"Пользователей: %d\n"+
+		"Событий: %d (активных %d, прошедших %d)\n"+
+		"Отправлено напоминаний: %d\n"+
+		"Отправлено дайджестов: %d\n\n"+
+		"Работает: %s, обработано обновлений: %d",
+		st.Chats, st.Users, st.Events, st.ActiveEvents, st.Events-st.ActiveEvents,
+		st.RemindersSent, st.DigestsSent,
+		time.Since(ready.startedAt).Round(time.Second), inflight.handled()), nil)
+}
+
+// adminChatsPage формирует страницу списка чатов с кнопками перехода между страницами.
+func adminChatsPage(ctx context.Context, page int) (string, *tgmodels.InlineKeyboardMarkup, error) {
+	chats, total, err := store.ListChatStats(ctx, (page-1)*adminChatsPageSize, adminChatsPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+	pages := max(1, (total+adminChatsPageSize-1)/adminChatsPageSize)
+	if len(chats) == 0 {
+		return fmt.Sprintf("Страница %d пуста (всего чатов: %d, страниц: %d)", page, total, pages), nil, nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "💬 <b>Чаты</b> — %d, страница %d из %d\n", total, page, pages)
+	for _, c := range chats {
+		fmt.Fprintf(&sb, "\n<code>%d</code> — %d %s", c.ChatID, c.Events, pluralRu(c.Events, "событие", "события", "событий"))
+		if c.Events > 0 {
+			fmt.Fprintf(&sb, " (активных %d)", c.ActiveEvents)
+		}
+	}
+
+	var row []tgmodels.InlineKeyboardButton
+	if page > 1 {
+		row = append(row, tgmodels.InlineKeyboardButton{Text: "◀", CallbackData: fmt.Sprintf("adm:chats:%d", page-1)})
+	}
+	if page < pages {
+		row = append(row, tgmodels.InlineKeyboardButton{Text: "▶", CallbackData: fmt.Sprintf("adm:chats:%d", page+1)})
+	}
+	if len(row) == 0 {
+		return sb.String(), nil, nil
+	}
+	return sb.String(), &tgmodels.InlineKeyboardMarkup{InlineKeyboard: [][]tgmodels.InlineKeyboardButton{row}}, nil
+}
+
+// previewBroadcast показывает рассылку так, как её получат чаты, и число получателей (dry-run).
+// Отправка — только после нажатия кнопки; текст до этого хранится в сессии.
+func previewBroadcast(ctx context.Context, b *bot.Bot, msg *tgmodels.Message, text string) {
+	chatID := msg.Chat.ID
+	if text == "" {
+		sendMessage(ctx, b, chatID, "Используйте формат: /admin_broadcast текст объявления\n"+
+			"Бот покажет предпросмотр и число получателей, отправка — после подтверждения.")
+		return
+	}
+	chats, err := store.ListKnownChats(ctx)
+	if err != nil {
+		logger.Errorf("Ошибка получения списка чатов для рассылки: %v", err)
+		sendMessage(ctx, b, chatID, "Ошибка при получении списка чатов")
+		return
+	}
+
+	saveSession(ctx, chatID, msg.From.ID, sessionBroadcast, broadcastDraft{Text: text})
+	kb := &tgmodels.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgmodels.InlineKeyboardButton{{
+			{Text: fmt.Sprintf("📣 Отправить в %d %s", len(chats), pluralRu(len(chats), "чат", "чата", "чатов")), CallbackData: "adm:bc:send"},
+			{Text: "❌ Отмена", CallbackData: "adm:bc:cancel"},
+		}},
+	}
+	sendAdminMessage(ctx, b, chatID, fmt.Sprintf("📣 <b>Предпросмотр рассылки</b> — получат %d %s:\n\n%s",
+		len(chats), pluralRu(len(chats), "чат", "чата", "чатов"), html.EscapeString(text)), kb)
+}
+
+// confirmPurgeChat показывает, что будет удалено, и запрашивает подтверждение очистки чата.
+func confirmPurgeChat(ctx context.Context, b *bot.Bot, chatID int64, arg string) {
+	target, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		sendMessage(ctx, b, chatID, "Используйте формат: /admin_purge_chat chat_id\nid чатов — в /admin_chats")
+		return
+	}
+	events, err := store.ListEvents(ctx, target)
+	if err != nil {
+		logger.Errorf("Ошибка получения событий чата %d: %v", target, err)
+		sendMessage(ctx, b, chatID, "Ошибка при получении событий чата")
+		return
+	}
+	kb := &tgmodels.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgmodels.InlineKeyboardButton{{
+			{Text: "🗑 Да, удалить", CallbackData: fmt.Sprintf("adm:purge:%d", target)},
+			{Text: "❌ Нет", CallbackData: "adm:cancel"},
+		}},
+	}
+	sendAdminMessage(ctx, b, chatID, fmt.Sprintf("Удалить все данные чата <code>%d</code>: %d %s с напоминаниями и отсчётами, "+
+		"настройки, дайджесты и незавершённые диалоги?",
+		target, len(events), pluralRu(len(events), "событие", "события", "событий")), kb)
+}
+
+// ──────────────────────────── кнопки ────────────────────────────
+
+// handleAdminCallback обрабатывает кнопки команд администратора:
+// adm:chats:<страница>, adm:bc:send, adm:bc:cancel, adm:purge:<chat_id>, adm:cancel.
+func handleAdminCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	cb := update.CallbackQuery
+	if cb == nil || cb.Message.Message == nil {
+		return
+	}
+	chatID := cb.Message.Message.Chat.ID
+	messageID := cb.Message.Message.ID
+	answer, answered := "", false
+	reply := func() {
+		if !answered {
+			answered = true
+			b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID, Text: answer})
+		}
+	}
+	defer reply()
+
+	if cb.Message.Message.Chat.Type != tgmodels.ChatTypePrivate || !isAdmin(cb.From.ID) {
+		answer = "⛔ Только для администратора бота"
+		return
+	}
+	log := WithComponent("admin").WithField("user_id", cb.From.ID)
+
+	edit := func(text string, kb *tgmodels.InlineKeyboardMarkup) {
+		params := &bot.EditMessageTextParams{ChatID: chatID, MessageID: messageID, Text: text, ParseMode: tgmodels.ParseModeHTML}
+		if kb != nil {
+			params.ReplyMarkup = kb
+		}
+		if _, err := b.EditMessageText(ctx, params); err != nil && !isNotModified(err) {
+			log.Errorf("Ошибка обновления сообщения: %v", err)
+		}
+	}
+
+	parts := strings.Split(cb.Data, ":")
+	switch {
+	case len(parts) == 3 && parts[1] == "chats":
+		page, err := strconv.Atoi(parts[2])
+		if err != nil || page < 1 {
+			return
+		}
+		text, kb, err := adminChatsPage(ctx, page)
+		if err != nil {
+			log.Errorf("Ошибка получения списка чатов: %v", err)
+			answer = "Ошибка при получении списка чатов"
+			return
+		}
+		edit(text, kb)
+
+	case cb.Data == "adm:bc:cancel", cb.Data == "adm:cancel":
+		dropSession(ctx, chatID, cb.From.ID, sessionBroadcast)
+		edit("❌ Отменено", nil)
+
+	case cb.Data == "adm:bc:send":
+		var draft broadcastDraft
+		if !loadSession(ctx, chatID, cb.From.ID, sessionBroadcast, &draft) {
+			answer = "Предпросмотр устарел, повторите /admin_broadcast"
+			edit("⌛ Предпросмотр устарел, повторите /admin_broadcast", nil)
+			return
+		}
+		// Одновременно идёт не больше одной рассылки: повторное нажатие не отправит её дважды
+		if !broadcasting.CompareAndSwap(false, true) {
+			answer = "Рассылка уже идёт"
+			return
+		}
+		defer broadcasting.Store(false)
+		dropSession(ctx, chatID, cb.From.ID, sessionBroadcast)
+		// Кнопку нужно подтвердить сразу, рассылка может занять минуты
+		answer = "Рассылка начата"
+		reply()
+
+		edit("📣 Рассылка отправляется…", nil)
+		log.Info("Рассылка начата")
+		res := broadcast(ctx, b, draft.Text)
+		log.WithField("sent", res.sent).WithField("blocked", res.blocked).WithField("failed", res.failed).
+			Infof("Рассылка завершена за %s", res.took.Round(time.Second))
+		edit(res.String(), nil)
+
+	case len(parts) == 3 && parts[1] == "purge":
+		target, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return
+		}
+		// Закреплённые отсчёты больше не обновятся — открепляем их до удаления событий
+		events, err := store.ListEvents(ctx, target)
+		if err != nil {
+			log.Errorf("Ошибка получения событий чата %d: %v", target, err)
+		}
+		for _, e := range events {
+			if pin, err := store.GetPin(ctx, e.ID); err == nil {
+				stopPin(ctx, b, pin, fmt.Sprintf("🗑 Событие <b>%s</b> удалено", html.EscapeString(e.DisplayTitle())))
+			}
+		}
+
+		n, err := store.PurgeChat(ctx, target)
+		if err != nil {
+			log.Errorf("Ошибка удаления данных чата %d: %v", target, err)
+			answer = "Ошибка при удалении данных чата"
+			return
+		}
+		log.Infof("Данные чата %d удалены (%d событий)", target, n)
+		// Команды событий пропадают из меню чата
+		syncChatCommands(ctx, b, target)
+		edit(fmt.Sprintf("🗑 Данные чата <code>%d</code> удалены: %d %s", target, n, pluralRu(n, "событие", "события", "событий")), nil)
+	}
+}
+
+// ──────────────────────────── рассылка ────────────────────────────
+
+// broadcastResult — итог рассылки.
+type broadcastResult struct {
+	total, sent, blocked, failed int
+	stopped                      bool // прервана остановкой бота
+	took                         time.Duration
+}
+
+func (r broadcastResult) String() string {
+	text := fmt.Sprintf("📣 <b>Рассылка завершена</b> за %s\nОтправлено: %d из %d", r.took.Round(time.Second), r.sent, r.total)
+	if r.stopped {
+		text = fmt.Sprintf("📣 <b>Рассылка прервана остановкой бота</b>\nОтправлено: %d из %d", r.sent, r.total)
+	}
+	if r.blocked > 0 {
+		text += fmt.Sprintf("\nБот удалён из чата или заблокирован: %d", r.blocked)
+	}
+	if r.failed > 0 {
+		text += fmt.Sprintf("\nОшибок: %d (подробности в логе)", r.failed)
+	}
+	return text
+}
+
+// broadcast отправляет текст во все известные чаты с паузой broadcastInterval между сообщениями.
+// При ответе 429 ждёт указанное Telegram время и повторяет отправку один раз.
+// Останавливается, если бот получил сигнал остановки.
+func broadcast(ctx context.Context, b *bot.Bot, text string) broadcastResult {
+	log := WithComponent("admin")
+	start := time.Now()
+	chats, err := store.ListKnownChats(ctx)
+	if err != nil {
+		log.Errorf("Ошибка получения списка чатов для рассылки: %v", err)
+		return broadcastResult{took: time.Since(start)}
+	}
+
+	res := broadcastResult{total: len(chats)}
+	ticker := time.NewTicker(broadcastInterval)
+	defer ticker.Stop()
+	for _, chatID := range chats {
+		if ready.stopping.Load() {
+			res.stopped = true
+			break
+		}
+		<-ticker.C
+
+		params := &bot.SendMessageParams{ChatID: chatID, Text: text}
+		_, err := b.SendMessage(ctx, params)
+		var tooMany *bot.TooManyRequestsError
+		if errors.As(err, &tooMany) {
+			wait := min(time.Duration(tooMany.RetryAfter)*time.Second, maxBroadcastRetryAfter)
+			log.Warnf("Рассылка: лимит Bot API, пауза %s", wait)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+			}
+			// За время паузы бот мог получить сигнал остановки
+			if ready.stopping.Load() || ctx.Err() != nil {
+				res.stopped = true
+				break
+			}
+			_, err = b.SendMessage(ctx, params)
+		}
+		switch {
+		case err == nil:
+			res.sent++
+		case errors.Is(err, bot.ErrorForbidden):
+			res.blocked++
+		default:
+			res.failed++
+			log.WithField("chat_id", chatID).Errorf("Ошибка отправки рассылки: %v", err)
+		}
+	}
+	res.took = time.Since(start)
+	return res
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/storage"
+)
+
+const testAdminID = 1000 // ADMIN_ID из .env в TestMain
+
+func TestPurgeChatStopsPinsAndResetsMenu(t *testing.T) {
+	const chatID = -700
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+	e := ownedEvent(t, chatID, 1, "party", time.Now().Add(24*time.Hour))
+	if err := store.SetPin(ctx, &storage.Pin{EventID: e.ID, ChatID: chatID, MessageID: 42, NextUpdateAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	handleAdminCallback(ctx, b, callbackQuery(testAdminID, testAdminID, fmt.Sprintf("adm:purge:%d", chatID)))
+
+	var unpinned, menuReset bool
+	for _, c := range ft.calls {
+		switch {
+		case c.Method == "unpinChatMessage" && c.Params["chat_id"] == fmt.Sprint(chatID) && c.Params["message_id"] == "42":
+			unpinned = true
+		case c.Method == "setMyCommands" && strings.Contains(c.Params["scope"], fmt.Sprint(chatID)) && !strings.Contains(c.Params["commands"], "party"):
+			menuReset = true
+		}
+	}
+	if !unpinned {
+		t.Error("pinned countdown not unpinned before purge")
+	}
+	if !menuReset {
+		t.Error("chat command menu not reset after purge")
+	}
+	if _, err := store.GetEventByID(ctx, e.ID); err == nil {
+		t.Error("event survived purge")
+	}
+	expectContains(t, "purge", ft.lastSent(testAdminID), "удалены")
+}
+
+func TestBroadcastStopsDuringRetryWait(t *testing.T) {
+	b, ft := newTestBot(t)
+	ownedEvent(t, -700, 1, "party", time.Now().Add(24*time.Hour))
+	ft.limited["sendMessage"] = 30
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan broadcastResult)
+	go func() { done <- broadcast(ctx, b, "hello") }()
+	time.Sleep(2 * broadcastInterval)
+	cancel()
+
+	select {
+	case res := <-done:
+		if !res.stopped || res.sent != 0 {
+			t.Errorf("result = %+v, want stopped without sends", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("broadcast kept waiting for retry_after after the context was cancelled")
+	}
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/TheReshkin/timer-bot/internal/storage"
 	"github.com/go-telegram/bot"
 	tgmodels "github.com/go-telegram/bot/models"
 )
@@ -15,63 +17,72 @@ import (
 
 // pendingEvent хранит данные о событии, для которого ещё не выбрана дата/время.
 type pendingEvent struct {
-	Name        string
+	Name        string // имя команды (slug)
+	Title       string // название, как его ввёл пользователь
 	Description string
 	ChatID      int64
 	UserID      int64
 	Date        string // "YYYY-MM-DD" — заполняется после выбора дня
 	Hour        int    // 0-23, -1 пока не выбран
-}
 
-var (
-	pendingEvents   = make(map[string]*pendingEvent)
-	pendingEventsMu sync.Mutex
+	// Recurrence — правило повторения (RRULE), пусто — однократное событие.
+	// RecurrenceSet — правило уже указано в команде (repeat=...), шаг выбора повторения пропускается.
+	Recurrence    string
+	RecurrenceSet bool
 
-	// awaitingName: пользователи, ожидающие ввода названия события после /set_date
-	awaitingName   = make(map[string]bool)
-	awaitingNameMu sync.Mutex
-)
+	// EditEventID — id существующего события, дата которого меняется через /edit (0 — создание).
+	EditEventID int64
 
-func pendingKey(chatID, userID int64) string {
-	return fmt.Sprintf("%d:%d", chatID, userID)
+	// At — дата, распознанная из текста и ожидающая подтверждения (нулевое значение — выбор в календаре).
+	At time.Time
+
+	// MessageID — сообщение с календарём или подтверждением; при истечении сессии оно помечается устаревшим.
+	MessageID int
 }
 
-func setPending(chatID, userID int64, pe *pendingEvent) {
-	pendingEventsMu.Lock()
-	defer pendingEventsMu.Unlock()
-	pendingEvents[pendingKey(chatID, userID)] = pe
+func setPending(ctx context.Context, chatID, userID int64, pe *pendingEvent) {
+	saveSession(ctx, chatID, userID, sessionPending, pe)
 }
 
-func getPending(chatID, userID int64) *pendingEvent {
-	pendingEventsMu.Lock()
-	defer pendingEventsMu.Unlock()
-	return pendingEvents[pendingKey(chatID, userID)]
+// getPending возвращает событие, ожидающее выбора даты, или nil, если сессии нет.
+// Изменения возвращённого значения нужно сохранить через setPending.
+func getPending(ctx context.Context, chatID, userID int64) *pendingEvent {
+	var pe pendingEvent
+	if !loadSession(ctx, chatID, userID, sessionPending, &pe) {
+		return nil
+	}
+	return &pe
 }
 
-func deletePending(chatID, userID int64) {
-	pendingEventsMu.Lock()
-	defer pendingEventsMu.Unlock()
-	delete(pendingEvents, pendingKey(chatID, userID))
+func deletePending(ctx context.Context, chatID, userID int64) {
+	dropSession(ctx, chatID, userID, sessionPending)
 }
 
 // ──────────────────────────── ожидание ввода названия события ────────────────────────────
 
-func setAwaitingName(chatID, userID int64) {
-	awaitingNameMu.Lock()
-	defer awaitingNameMu.Unlock()
-	awaitingName[pendingKey(chatID, userID)] = true
+// awaitingName — ожидание названия события в ответ на запрос бота после /set_date.
+type awaitingName struct {
+	PromptID int // сообщение с запросом названия
+}
+
+// setAwaitingName отмечает, что ответ пользователя на сообщение promptID — название события.
+func setAwaitingName(ctx context.Context, chatID, userID int64, promptID int) {
+	saveSession(ctx, chatID, userID, sessionAwaitingName, awaitingName{PromptID: promptID})
+}
+
+func isAwaitingName(ctx context.Context, chatID, userID int64) bool {
+	var v awaitingName
+	return loadSession(ctx, chatID, userID, sessionAwaitingName, &v)
 }
 
-func isAwaitingName(chatID, userID int64) bool {
-	awaitingNameMu.Lock()
-	defer awaitingNameMu.Unlock()
-	return awaitingName[pendingKey(chatID, userID)]
+// isNameReply сообщает, что msg — ответ автора /set_date на запрос названия события.
+func isNameReply(ctx context.Context, msg *tgmodels.Message) bool {
+	var v awaitingName
+	return msg.From != nil && loadSession(ctx, msg.Chat.ID, msg.From.ID, sessionAwaitingName, &v) && repliesTo(msg, v.PromptID)
 }
 
-func clearAwaitingName(chatID, userID int64) {
-	awaitingNameMu.Lock()
-	defer awaitingNameMu.Unlock()
-	delete(awaitingName, pendingKey(chatID, userID))
+func clearAwaitingName(ctx context.Context, chatID, userID int64) {
+	dropSession(ctx, chatID, userID, sessionAwaitingName)
 }
 
 // ──────────────────────────── генерация inline-календаря ────────────────────────────
@@ -84,16 +95,17 @@ var russianMonths = [12]string{
 
 var shortWeekdays = [7]string{"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"}
 
-// today возвращает сегодняшнюю дату без времени.
-func today() time.Time {
-	now := time.Now()
+// today возвращает сегодняшнюю дату в часовом поясе loc без времени.
+// Сетка календаря строится в UTC, поэтому и результат — полночь UTC той же календарной даты.
+func today(loc *time.Location) time.Time {
+	now := time.Now().In(loc)
 	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 }
 
-// buildCalendar создаёт inline-клавиатуру с календарём. Прошедшие даты неактивны.
-func buildCalendar(year int, month time.Month) *tgmodels.InlineKeyboardMarkup {
+// buildCalendar создаёт inline-клавиатуру с календарём. Прошедшие (в часовом поясе loc) даты неактивны.
+func buildCalendar(year int, month time.Month, loc *time.Location) *tgmodels.InlineKeyboardMarkup {
 	rows := [][]tgmodels.InlineKeyboardButton{}
-	todayDate := today()
+	todayDate := today(loc)
 
 	// Не даём листать назад дальше текущего месяца
 	canPrev := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).After(
@@ -219,25 +231,28 @@ func buildMinutePicker(dateStr string, hour int) *tgmodels.InlineKeyboardMarkup
 
 // ──────────────────────────── отправка / обновление ────────────────────────────
 
-func sendCalendar(ctx context.Context, b *bot.Bot, chatID int64, eventName string, year int, month time.Month) {
-	kb := buildCalendar(year, month)
-	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+// sendCalendar отправляет календарь и возвращает id сообщения (0 при ошибке).
+func sendCalendar(ctx context.Context, b *bot.Bot, chatID int64, eventName string, year int, month time.Month) int {
+	kb := buildCalendar(year, month, chatLocation(ctx, chatID))
+	msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:      chatID,
-		Text:        fmt.Sprintf("📅 Выберите дату для события <b>%s</b>:", eventName),
+		Text:        fmt.Sprintf("📅 Выберите дату для события <b>%s</b>:", html.EscapeString(eventName)),
 		ParseMode:   tgmodels.ParseModeHTML,
 		ReplyMarkup: kb,
 	})
 	if err != nil {
 		logger.Errorf("Ошибка отправки календаря: %v", err)
+		return 0
 	}
+	return msg.ID
 }
 
 func editCalendar(ctx context.Context, b *bot.Bot, chatID int64, messageID int, eventName string, year int, month time.Month) {
-	kb := buildCalendar(year, month)
+	kb := buildCalendar(year, month, chatLocation(ctx, chatID))
 	_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
 		MessageID:   messageID,
-		Text:        fmt.Sprintf("📅 Выберите дату для события <b>%s</b>:", eventName),
+		Text:        fmt.Sprintf("📅 Выберите дату для события <b>%s</b>:", html.EscapeString(eventName)),
 		ParseMode:   tgmodels.ParseModeHTML,
 		ReplyMarkup: kb,
 	})
@@ -251,7 +266,7 @@ func editToHourPicker(ctx context.Context, b *bot.Bot, chatID int64, messageID i
 	b.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
 		MessageID:   messageID,
-		Text:        fmt.Sprintf("🕐 Выберите час для события <b>%s</b> (%s):", eventName, dateStr),
+		Text:        fmt.Sprintf("🕐 Выберите час для события <b>%s</b> (%s):", html.EscapeString(eventName), dateStr),
 		ParseMode:   tgmodels.ParseModeHTML,
 		ReplyMarkup: kb,
 	})
@@ -262,17 +277,52 @@ func editToMinutePicker(ctx context.Context, b *bot.Bot, chatID int64, messageID
 	b.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
 		MessageID:   messageID,
-		Text:        fmt.Sprintf("🕐 Выберите минуты для события <b>%s</b> (%s %02d:??):", eventName, dateStr, hour),
+		Text:        fmt.Sprintf("🕐 Выберите минуты для события <b>%s</b> (%s %02d:??):", html.EscapeString(eventName), dateStr, hour),
 		ParseMode:   tgmodels.ParseModeHTML,
 		ReplyMarkup: kb,
 	})
 }
 
+// sendDateConfirmation показывает, как понята дата, введённая словами, и предлагает
+// сохранить событие или выбрать дату в календаре. Возвращает id сообщения (0 при ошибке).
+func sendDateConfirmation(ctx context.Context, b *bot.Bot, chatID int64, pe *pendingEvent) int {
+	loc := chatLocation(ctx, chatID)
+	at := pe.At.In(loc)
+	text := fmt.Sprintf("📅 Событие <b>%s</b>\nДата: %s, %s\n",
+		html.EscapeString(pe.Title), russianWeekdays[at.Weekday()], formatEventTime(at, loc))
+	if pe.Recurrence != "" {
+		text += fmt.Sprintf("🔁 Повторяется %s\n", describeRecurrence(pe.Recurrence))
+	}
+	if !at.After(time.Now()) {
+		text += "⚠️ Эта дата уже прошла\n"
+	}
+	text += "Сохранить?"
+
+	kb := &tgmodels.InlineKeyboardMarkup{InlineKeyboard: [][]tgmodels.InlineKeyboardButton{
+		{
+			{Text: "✅ Сохранить", CallbackData: "cal:confirm"},
+			{Text: "📅 Другая дата", CallbackData: "cal:pick"},
+		},
+		{{Text: "❌ Отмена", CallbackData: "cal:cancel"}},
+	}}
+	msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        text,
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: kb,
+	})
+	if err != nil {
+		logger.Errorf("Ошибка отправки подтверждения даты chat_id=%d: %v", chatID, err)
+		return 0
+	}
+	return msg.ID
+}
+
 // ──────────────────────────── обработчик callback query ────────────────────────────
 
 func handleCalendarCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 	cb := update.CallbackQuery
-	if cb == nil {
+	if cb == nil || cb.Message.Message == nil {
 		return
 	}
 
@@ -281,14 +331,21 @@ func handleCalendarCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Up
 	userID := cb.From.ID
 	messageID := cb.Message.Message.ID
 
+	answer := ""
 	defer func() {
-		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID, Text: answer})
 	}()
 
-	pe := getPending(chatID, userID)
+	pe := getPending(ctx, chatID, userID)
+	// Сессия привязана к своему календарю: кнопки старого календаря того же пользователя
+	// не должны подтверждать, менять или отменять новую сессию
+	if pe != nil && pe.MessageID != messageID {
+		answer = "Этот календарь устарел"
+		return
+	}
 	getName := func() string {
 		if pe != nil {
-			return pe.Name
+			return pe.Title
 		}
 		return ""
 	}
@@ -297,12 +354,36 @@ func handleCalendarCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Up
 	case data == "cal:ignore":
 		return
 
+	// ──── подтверждение даты, распознанной из текста ────
+	case data == "cal:confirm":
+		if pe == nil || pe.At.IsZero() {
+			return
+		}
+		at := pe.At.In(chatLocation(ctx, chatID))
+		createPendingEvent(ctx, b, chatID, userID, messageID, pe, at.Format("2006-01-02 15:04"))
+		return
+
+	case data == "cal:pick":
+		if pe == nil {
+			return
+		}
+		at := pe.At.In(chatLocation(ctx, chatID))
+		pe.At = time.Time{}
+		pe.MessageID = messageID
+		setPending(ctx, chatID, userID, pe)
+		editCalendar(ctx, b, chatID, messageID, pe.Title, at.Year(), at.Month())
+		return
+
 	case data == "cal:cancel":
-		deletePending(chatID, userID)
+		text := "❌ Создание события отменено."
+		if pe != nil && pe.EditEventID != 0 {
+			text = "❌ Изменение даты отменено."
+		}
+		deletePending(ctx, chatID, userID)
 		b.EditMessageText(ctx, &bot.EditMessageTextParams{
 			ChatID:    chatID,
 			MessageID: messageID,
-			Text:      "❌ Создание события отменено.",
+			Text:      text,
 		})
 		return
 
@@ -326,8 +407,10 @@ func handleCalendarCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Up
 		if pe != nil {
 			pe.Date = ""
 			pe.Hour = -1
+			pe.MessageID = messageID
+			setPending(ctx, chatID, userID, pe)
 		}
-		now := time.Now()
+		now := time.Now().In(chatLocation(ctx, chatID))
 		editCalendar(ctx, b, chatID, messageID, getName(), now.Year(), now.Month())
 		return
 
@@ -336,6 +419,8 @@ func handleCalendarCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Up
 		dateStr := strings.TrimPrefix(data, "cal:back_to_hours:")
 		if pe != nil {
 			pe.Hour = -1
+			pe.MessageID = messageID
+			setPending(ctx, chatID, userID, pe)
 		}
 		editToHourPicker(ctx, b, chatID, messageID, getName(), dateStr)
 		return
@@ -353,7 +438,9 @@ func handleCalendarCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Up
 		}
 		pe.Date = dateStr
 		pe.Hour = -1
-		editToHourPicker(ctx, b, chatID, messageID, pe.Name, dateStr)
+		pe.MessageID = messageID
+		setPending(ctx, chatID, userID, pe)
+		editToHourPicker(ctx, b, chatID, messageID, pe.Title, dateStr)
 		return
 
 	// ──── выбор часа → переход к минутам ────
@@ -370,10 +457,20 @@ func handleCalendarCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Up
 			return
 		}
 		pe.Hour = hour
-		editToMinutePicker(ctx, b, chatID, messageID, pe.Name, dateStr, hour)
+		pe.MessageID = messageID
+		setPending(ctx, chatID, userID, pe)
+		editToMinutePicker(ctx, b, chatID, messageID, pe.Title, dateStr, hour)
+		return
+
+	// ──── назад к минутам из выбора повторения ────
+	case strings.HasPrefix(data, "cal:back_to_min:"):
+		var dateStr string
+		var hour int
+		fmt.Sscanf(data, "cal:back_to_min:%10s:%d", &dateStr, &hour)
+		editToMinutePicker(ctx, b, chatID, messageID, getName(), dateStr, hour)
 		return
 
-	// ──── выбор минут → создание события ────
+	// ──── выбор минут → выбор повторения (или создание события) ────
 	case strings.HasPrefix(data, "cal:min:"):
 		var dateStr string
 		var hour, minute int
@@ -387,33 +484,94 @@ func handleCalendarCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Up
 			return
 		}
 
-		formattedDate := fmt.Sprintf("%s %02d:%02d", dateStr, hour, minute)
+		// Изменение даты существующего события через /edit
+		if pe.EditEventID != 0 {
+			applyPendingDate(ctx, b, chatID, userID, messageID, pe, fmt.Sprintf("%s %02d:%02d", dateStr, hour, minute))
+			return
+		}
+		// Повторение уже указано в команде — создаём сразу
+		if pe.RecurrenceSet {
+			createPendingEvent(ctx, b, chatID, userID, messageID, pe, fmt.Sprintf("%s %02d:%02d", dateStr, hour, minute))
+			return
+		}
+		editToRecurrencePicker(ctx, b, chatID, messageID, pe.Title, dateStr, hour, minute)
+		return
 
-		// Создание события в БД
-		if err := store.CreateEvent(ctx, chatID, pe.Name, formattedDate, pe.Description); err != nil {
+	// ──── выбор повторения → создание события ────
+	case strings.HasPrefix(data, "cal:rep:"):
+		// cal:rep:YYYY-MM-DD:H:M:kind
+		parts := strings.Split(strings.TrimPrefix(data, "cal:rep:"), ":")
+		if len(parts) != 4 {
+			return
+		}
+		var hour, minute int
+		fmt.Sscanf(parts[1]+":"+parts[2], "%d:%d", &hour, &minute)
+		if pe == nil {
 			b.EditMessageText(ctx, &bot.EditMessageTextParams{
 				ChatID:    chatID,
 				MessageID: messageID,
-				Text:      fmt.Sprintf("❌ Ошибка создания события: %s", err),
+				Text:      "⚠️ Сессия истекла. Используйте /set_date заново.",
 			})
-			deletePending(chatID, userID)
 			return
 		}
 
-		// Привязка к пользователю
-		event, err := store.GetEvent(ctx, chatID, pe.Name)
-		if err == nil && event != nil {
-			_ = store.AddEventToUser(ctx, chatID, userID, event.ID)
+		rule, err := normalizeRecurrence(parts[3])
+		if err != nil {
+			return
 		}
+		pe.Recurrence = rule
+		createPendingEvent(ctx, b, chatID, userID, messageID, pe, fmt.Sprintf("%s %02d:%02d", parts[0], hour, minute))
+	}
+}
 
-		logger.Infof("Событие создано через календарь: %s → %s (chat_id=%d)", pe.Name, formattedDate, chatID)
+// createPendingEvent сохраняет событие, собранное в календаре, и завершает сессию.
+func createPendingEvent(ctx context.Context, b *bot.Bot, chatID, userID int64, messageID int, pe *pendingEvent, formattedDate string) {
+	defer deletePending(ctx, chatID, userID)
 
+	date, err := parseEventDate(formattedDate, chatLocation(ctx, chatID))
+	if err != nil {
+		logger.Errorf("Ошибка парсинга даты из календаря %q: %v", formattedDate, err)
+		return
+	}
+
+	// Создание события в БД
+	event := &storage.Event{
+		ChatID:      chatID,
+		Name:        pe.Name,
+		Title:       pe.Title,
+		Date:        date,
+		Description: pe.Description,
+		Recurrence:  pe.Recurrence,
+	}
+	if err := store.CreateEvent(ctx, event); err != nil {
+		text := fmt.Sprintf("❌ Ошибка создания события: %s", err)
+		if errors.Is(err, storage.ErrEventExists) {
+			text = fmt.Sprintf("❌ Событие /%s уже существует в этом чате", pe.Name)
+		}
 		b.EditMessageText(ctx, &bot.EditMessageTextParams{
 			ChatID:    chatID,
 			MessageID: messageID,
-			Text:      fmt.Sprintf("✅ Событие <b>%s</b> создано на %s!\nИспользуйте /%s для информации.", pe.Name, formattedDate, pe.Name),
-			ParseMode: tgmodels.ParseModeHTML,
+			Text:      text,
 		})
-		deletePending(chatID, userID)
+		return
 	}
+
+	// Привязка к пользователю
+	_ = store.AddEventToUser(ctx, chatID, userID, event.ID)
+
+	logger.Infof("Событие создано через календарь: %s → %s (chat_id=%d)", pe.Name, formattedDate, chatID)
+
+	text := fmt.Sprintf("✅ Событие <b>%s</b> создано на %s!", html.EscapeString(pe.Title), formattedDate)
+	if pe.Recurrence != "" {
+		text += fmt.Sprintf("\n🔁 Повторяется %s.", describeRecurrence(pe.Recurrence))
+	}
+	text += fmt.Sprintf("\nИспользуйте /%s для информации.", pe.Name)
+
+	b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+		Text:      text,
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+	syncChatCommands(ctx, b, chatID)
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/eventname"
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+// Ограничения Telegram на меню команд.
+const (
+	maxMenuCommands          = 100
+	maxCommandDescriptionLen = 256
+)
+
+// staticCommands — системные команды меню. В чатах с событиями они идут первыми,
+// так как меню чата полностью заменяет глобальное.
+var staticCommands = []tgmodels.BotCommand{
+	{Command: "set_date", Description: "📅 Добавить событие (календарь или дата)"},
+	{Command: "list", Description: "📋 Список всех событий"},
+	{Command: "active", Description: "✅ Активные события"},
+	{Command: "outdated", Description: "⏰ Устаревшие события"},
+	{Command: "remind", Description: "⏰ Напоминания о событии"},
+	{Command: "repeat", Description: "🔁 Повторение события"},
+	{Command: "timezone", Description: "🕐 Часовой пояс чата"},
+	{Command: "digest", Description: "🗓 Дайджест предстоящих событий"},
+	{Command: "edit", Description: "✏️ Изменить событие"},
+	{Command: "delete", Description: "🗑 Удалить событие"},
+	{Command: "pin", Description: "📌 Закрепить отсчёт до события"},
+	{Command: "unpin", Description: "📍 Открепить отсчёт"},
+	{Command: "publish", Description: "🔗 Опубликовать события для других чатов"},
+	{Command: "subscribe", Description: "🔗 Подписки на подборки событий"},
+	{Command: "help", Description: "❓ Справка по командам"},
+}
+
+// chatMenuCommands составляет меню чата: системные команды, затем события —
+// сначала ближайшие предстоящие, затем прошедшие (недавние первыми), всего не больше 100.
+// События, имя которых не годится для команды Telegram или совпадает с системной командой, пропускаются.
+func chatMenuCommands(events []storage.Event, loc *time.Location, now time.Time) []tgmodels.BotCommand {
+	type entry struct {
+		event    storage.Event
+		date     time.Time
+		upcoming bool
+	}
+
+	taken := make(map[string]bool, len(staticCommands))
+	for _, c := range staticCommands {
+		taken[c.Command] = true
+	}
+
+	var entries []entry
+	for _, e := range events {
+		if !eventname.Valid(e.Name) || eventname.IsReserved(e.Name) || taken[e.Name] {
+			continue
+		}
+		date, upcoming, err := eventOccurrence(e, loc, now)
+		if err != nil {
+			date, upcoming = e.Date, false
+		}
+		entries = append(entries, entry{event: e, date: date, upcoming: upcoming})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.upcoming != b.upcoming {
+			return a.upcoming
+		}
+		if a.upcoming {
+			return a.date.Before(b.date)
+		}
+		return a.date.After(b.date)
+	})
+
+	commands := append([]tgmodels.BotCommand(nil), staticCommands...)
+	for _, en := range entries {
+		if len(commands) >= maxMenuCommands {
+			break
+		}
+		desc := "📌 " + formatEventTime(en.date, loc)
+		if en.event.Title != "" && en.event.Title != en.event.Name {
+			desc = "📌 " + en.event.Title + " · " + formatEventTime(en.date, loc)
+		}
+		if en.event.Description != "" {
+			desc += " · " + en.event.Description
+		}
+		commands = append(commands, tgmodels.BotCommand{
+			Command:     en.event.Name,
+			Description: truncateRunes(desc, maxCommandDescriptionLen),
+		})
+	}
+	return commands
+}
+
+// truncateRunes обрезает строку до n символов (не байт), добавляя многоточие.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// syncChatCommands обновляет меню команд чата после создания, переименования,
+// изменения даты или удаления события. В чате без событий остаются только системные команды.
+func syncChatCommands(ctx context.Context, b *bot.Bot, chatID int64) {
+	events, err := store.ListEvents(ctx, chatID)
+	if err != nil {
+		logger.Errorf("Ошибка получения событий для меню чата %d: %v", chatID, err)
+		return
+	}
+
+	// В чате без событий меню состоит из системных команд. DeleteMyCommands здесь не подходит:
+	// go-telegram/bot отправляет его с одним scope без параметров, и такой запрос удалил бы
+	// глобальное меню вместо меню чата.
+	scope := &tgmodels.BotCommandScopeChat{ChatID: chatID}
+	commands := chatMenuCommands(events, chatLocation(ctx, chatID), time.Now())
+	if _, err := b.SetMyCommands(ctx, &bot.SetMyCommandsParams{Commands: commands, Scope: scope}); err != nil {
+		logger.Errorf("Ошибка установки меню команд чата %d: %v", chatID, err)
+		return
+	}
+	logger.Debugf("Меню команд чата %d обновлено (%d)", chatID, len(commands))
+}
+
+// syncAllChatCommands обновляет меню всех чатов с событиями — при старте,
+// чтобы меню подхватили изменившийся список системных команд и порядок событий.
+// Между чатами выдерживается пауза, чтобы не упереться в лимиты Bot API.
+func syncAllChatCommands(ctx context.Context, b *bot.Bot) {
+	chats, err := store.ListEventChats(ctx)
+	if err != nil {
+		logger.Errorf("Ошибка получения списка чатов для меню команд: %v", err)
+		return
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for _, chatID := range chats {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		syncChatCommands(ctx, b, chatID)
+	}
+	logger.Infof("Меню команд обновлено в %d %s", len(chats), pluralRu(len(chats), "чате", "чатах", "чатах"))
+}
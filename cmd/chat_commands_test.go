@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncChatCommandsWithoutEventsResetsOnlyChatScope(t *testing.T) {
+	const chatID = -800
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+	e := ownedEvent(t, chatID, 1, "party", time.Now().Add(24*time.Hour))
+	if err := store.DeleteEvent(ctx, chatID, e.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	syncChatCommands(ctx, b, chatID)
+
+	// Меню по умолчанию (без scope) общее для всех чатов: его нельзя ни удалять, ни перезаписывать
+	for _, c := range ft.calls {
+		if c.Method == "deleteMyCommands" {
+			t.Fatalf("deleteMyCommands sent with params %v: the library drops the scope", c.Params)
+		}
+		if c.Method == "setMyCommands" && c.Params["scope"] == "" {
+			t.Fatalf("setMyCommands sent for the default scope: %v", c.Params)
+		}
+	}
+	if len(ft.calls) != 1 || ft.calls[0].Method != "setMyCommands" {
+		t.Fatalf("calls = %+v, want one setMyCommands", ft.calls)
+	}
+	params := ft.calls[0].Params
+	if !strings.Contains(params["scope"], fmt.Sprint(chatID)) {
+		t.Errorf("scope = %q, want chat %d", params["scope"], chatID)
+	}
+	if strings.Contains(params["commands"], "party") || !strings.Contains(params["commands"], "set_date") {
+		t.Errorf("commands = %s, want only static commands", params["commands"])
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/eventname"
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+const (
+	// collectionCodeAlphabet — символы кода подборки: строчные латинские буквы без l и o и цифры без 0 и 1,
+	// чтобы код было удобно переписать. Ровно 32 символа — случайный байт делится без перекоса.
+	collectionCodeAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+	collectionCodeLen      = 8
+	// collectionCodeAttempts — сколько раз подбирать новый код, если сгенерированный уже занят.
+	collectionCodeAttempts = 5
+)
+
+const manageChatDeniedText = "⛔ Публиковать события чата и подписывать его на подборки может только администратор чата"
+
+// listedEvent — событие в списке чата: своё или из подборки, на которую чат подписан.
+type listedEvent struct {
+	storage.Event
+	loc    *time.Location // часовой пояс чата события
+	source string         // название подборки; пусто — событие самого чата
+}
+
+// line возвращает строку списка событий; события подборок помечены 🔗 и названием подборки.
+func (le listedEvent) line(loc *time.Location) string {
+	line := fmt.Sprintf("- %s: %s (/%s)", le.DisplayTitle(), displayDateIn(le.Event, le.loc, loc), le.Name)
+	if le.source != "" {
+		line += fmt.Sprintf(" 🔗 %s", le.source)
+	}
+	return line + "\n"
+}
+
+// listChatEvents возвращает события чата, а за ними — события подборок, на которые он подписан.
+// Подборку, которую не удалось прочитать, пропускает: список чата важнее.
+func listChatEvents(ctx context.Context, chatID int64) ([]listedEvent, error) {
+	events, err := store.ListEvents(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	loc := chatLocation(ctx, chatID)
+	listed := make([]listedEvent, 0, len(events))
+	for _, e := range events {
+		listed = append(listed, listedEvent{Event: e, loc: loc})
+	}
+
+	subs, err := store.ListSubscriptions(ctx, chatID)
+	if err != nil {
+		logger.Errorf("Ошибка получения подписок чата %d: %v", chatID, err)
+		return listed, nil
+	}
+	for _, c := range subs {
+		shared, err := store.ListEvents(ctx, c.ChatID)
+		if err != nil {
+			logger.Errorf("Ошибка получения событий подборки %s: %v", c.Code, err)
+			continue
+		}
+		sourceLoc := chatLocation(ctx, c.ChatID)
+		for _, e := range shared {
+			listed = append(listed, listedEvent{Event: e, loc: sourceLoc, source: collectionTitle(c)})
+		}
+	}
+	return listed, nil
+}
+
+// findSubscribedEvent — findChatEvent для подборок, на которые подписан чат.
+func findSubscribedEvent(ctx context.Context, chatID int64, name string) (*storage.Event, error) {
+	event, err := store.FindSubscribedEvent(ctx, chatID, name)
+	if errors.Is(err, storage.ErrNotFound) {
+		if slug := eventname.Slug(name); slug != "" && slug != name {
+			return store.FindSubscribedEvent(ctx, chatID, slug)
+		}
+	}
+	return event, err
+}
+
+// collectionTitle возвращает название подборки для сообщений.
+func collectionTitle(c storage.Collection) string {
+	if c.Title == "" {
+		return "«" + c.Code + "»"
+	}
+	return "«" + c.Title + "»"
+}
+
+// chatTitle возвращает название чата для подборки. У личного чата названия нет: имя пользователя
+// увидели бы все подписчики, поэтому такая подборка называется по коду (см. collectionTitle).
+func chatTitle(chat tgmodels.Chat) string {
+	if chat.Type == tgmodels.ChatTypePrivate {
+		return ""
+	}
+	return chat.Title
+}
+
+// newCollectionCode возвращает случайный код подборки.
+func newCollectionCode() string {
+	buf := make([]byte, collectionCodeLen)
+	rand.Read(buf)
+	for i, c := range buf {
+		buf[i] = collectionCodeAlphabet[int(c)%len(collectionCodeAlphabet)]
+	}
+	return string(buf)
+}
+
+// canManageChat сообщает, может ли автор сообщения менять настройки чата, публиковать его события и подписки:
+// в личном чате — всегда, в группе — только администратор (в том числе анонимный).
+func canManageChat(ctx context.Context, b *bot.Bot, msg *tgmodels.Message) bool {
+	if msg.Chat.Type == tgmodels.ChatTypePrivate {
+		return true
+	}
+	if msg.SenderChat != nil && msg.SenderChat.ID == msg.Chat.ID {
+		return true
+	}
+	if msg.From == nil {
+		return false
+	}
+	return isChatAdmin(ctx, b, msg.Chat.ID, msg.From.ID)
+}
+
+// ──────────────────────────── /publish ────────────────────────────
+
+// handlePublish публикует события чата как подборку, на которую могут подписаться другие чаты:
+//
+//	/publish      — опубликовать (или показать код уже опубликованной подборки)
+//	/publish off  — снять с публикации, подписки других чатов отменяются
+func handlePublish(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil {
+		return
+	}
+	parts := strings.Fields(normalizeCommand(update.Message.Text))
+	if len(parts) == 0 || parts[0] != "/publish" {
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	msg := update.Message
+	chatID := msg.Chat.ID
+
+	if len(parts) > 2 || (len(parts) == 2 && parts[1] != "off") {
+		sendMessage(ctx, b, chatID, "Используйте формат: /publish или /publish off")
+		return
+	}
+	if !canManageChat(ctx, b, msg) {
+		sendMessage(ctx, b, chatID, manageChatDeniedText)
+		return
+	}
+
+	if len(parts) == 2 {
+		deleted, err := store.DeleteCollection(ctx, chatID)
+		if err != nil {
+			logger.Errorf("Ошибка снятия подборки чата %d с публикации: %v", chatID, err)
+			sendMessage(ctx, b, chatID, "Ошибка при снятии подборки с публикации")
+			return
+		}
+		if !deleted {
+			sendMessage(ctx, b, chatID, "События чата не опубликованы")
+			return
+		}
+		logger.Infof("Подборка чата %d снята с публикации", chatID)
+		sendMessage(ctx, b, chatID, "🔒 Подборка снята с публикации, подписки других чатов отменены.\n"+
+			"Если опубликовать её снова, код будет новым.")
+		return
+	}
+
+	c, created, err := publishCollection(ctx, msg.Chat)
+	if err != nil {
+		logger.Errorf("Ошибка публикации подборки чата %d: %v", chatID, err)
+		sendMessage(ctx, b, chatID, "Ошибка при публикации подборки")
+		return
+	}
+	text := "🔗 События чата уже опубликованы."
+	if created {
+		logger.Infof("Чат %d опубликовал подборку %s", chatID, c.Code)
+		text = "🔗 События чата опубликованы: в подборку входят все его события, в том числе будущие."
+	}
+	sendMessage(ctx, b, chatID, fmt.Sprintf("%s\nКод подборки: %s\n\n"+
+		"Подписаться из другого чата: /subscribe %s\nСнять с публикации: /publish off", text, c.Code, c.Code))
+}
+
+// publishCollection возвращает подборку чата, создавая её с новым кодом, если её ещё нет.
+// created == true, если подборка создана сейчас.
+func publishCollection(ctx context.Context, chat tgmodels.Chat) (c *storage.Collection, created bool, err error) {
+	for range collectionCodeAttempts {
+		c, err = store.GetCollection(ctx, chat.ID)
+		if !errors.Is(err, storage.ErrNotFound) {
+			return c, false, err
+		}
+		c = &storage.Collection{ChatID: chat.ID, Code: newCollectionCode(), Title: chatTitle(chat)}
+		created, err = store.CreateCollection(ctx, c)
+		switch {
+		case errors.Is(err, storage.ErrCodeTaken):
+			continue
+		case err != nil:
+			return nil, false, err
+		case created:
+			return c, true, nil
+		}
+		// Подборку только что создал параллельный /publish — вернём её на следующей итерации
+	}
+	return nil, false, fmt.Errorf("не удалось подобрать свободный код за %d попыток", collectionCodeAttempts)
+}
+
+// ──────────────────────────── /subscribe ────────────────────────────
+
+// handleSubscribe подписывает чат на подборку другого чата или показывает подписки:
+//
+//	/subscribe         — подборки, на которые подписан чат
+//	/subscribe <код>   — подписаться: события подборки появятся в /list, /active и /outdated
+func handleSubscribe(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil {
+		return
+	}
+	parts := strings.Fields(normalizeCommand(update.Message.Text))
+	if len(parts) == 0 || parts[0] != "/subscribe" {
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	msg := update.Message
+	chatID := msg.Chat.ID
+
+	if len(parts) == 1 {
+		sendSubscriptions(ctx, b, chatID)
+		return
+	}
+	if len(parts) != 2 {
+		sendMessage(ctx, b, chatID, "Используйте формат: /subscribe код_подборки")
+		return
+	}
+	if !canManageChat(ctx, b, msg) {
+		sendMessage(ctx, b, chatID, manageChatDeniedText)
+		return
+	}
+
+	c, ok := collectionByCode(ctx, b, chatID, parts[1])
+	if !ok {
+		return
+	}
+	if c.ChatID == chatID {
+		sendMessage(ctx, b, chatID, "Это подборка этого же чата — её события и так в списке")
+		return
+	}
+	added, err := store.Subscribe(ctx, chatID, c.ChatID)
+	if errors.Is(err, storage.ErrNotFound) {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Подборка с кодом %s не найдена", c.Code))
+		return
+	}
+	if err != nil {
+		logger.Errorf("Ошибка подписки чата %d на подборку %s: %v", chatID, c.Code, err)
+		sendMessage(ctx, b, chatID, "Ошибка при подписке на подборку")
+		return
+	}
+	if !added {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Чат уже подписан на подборку %s", collectionTitle(*c)))
+		return
+	}
+	logger.Infof("Чат %d подписался на подборку %s", chatID, c.Code)
+	sendMessage(ctx, b, chatID, fmt.Sprintf("✅ Чат подписан на подборку %s: её события появятся в /list с пометкой 🔗.\n"+
+		"Отписаться: /unsubscribe %s", collectionTitle(*c), c.Code))
+}
+
+// handleUnsubscribe отменяет подписку чата на подборку: /unsubscribe <код>.
+func handleUnsubscribe(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil {
+		return
+	}
+	parts := strings.Fields(normalizeCommand(update.Message.Text))
+	if len(parts) == 0 || parts[0] != "/unsubscribe" {
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	msg := update.Message
+	chatID := msg.Chat.ID
+
+	if len(parts) != 2 {
+		sendMessage(ctx, b, chatID, "Используйте формат: /unsubscribe код_подборки\nПодписки чата — в /subscribe")
+		return
+	}
+	if !canManageChat(ctx, b, msg) {
+		sendMessage(ctx, b, chatID, manageChatDeniedText)
+		return
+	}
+
+	c, ok := collectionByCode(ctx, b, chatID, parts[1])
+	if !ok {
+		return
+	}
+	removed, err := store.Unsubscribe(ctx, chatID, c.ChatID)
+	if err != nil {
+		logger.Errorf("Ошибка отписки чата %d от подборки %s: %v", chatID, c.Code, err)
+		sendMessage(ctx, b, chatID, "Ошибка при отписке от подборки")
+		return
+	}
+	if !removed {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Чат не подписан на подборку %s", collectionTitle(*c)))
+		return
+	}
+	logger.Infof("Чат %d отписался от подборки %s", chatID, c.Code)
+	sendMessage(ctx, b, chatID, fmt.Sprintf("Чат отписан от подборки %s", collectionTitle(*c)))
+}
+
+// collectionByCode ищет подборку по коду, введённому пользователем. Если подборки нет
+// или произошла ошибка, сообщает об этом в чат и возвращает false.
+func collectionByCode(ctx context.Context, b *bot.Bot, chatID int64, code string) (*storage.Collection, bool) {
+	code = strings.ToLower(code)
+	c, err := store.GetCollectionByCode(ctx, code)
+	if errors.Is(err, storage.ErrNotFound) {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Подборка с кодом %s не найдена", code))
+		return nil, false
+	}
+	if err != nil {
+		logger.Errorf("Ошибка поиска подборки %s: %v", code, err)
+		sendMessage(ctx, b, chatID, "Ошибка при поиске подборки")
+		return nil, false
+	}
+	return c, true
+}
+
+// sendSubscriptions отправляет список подборок, на которые подписан чат.
+func sendSubscriptions(ctx context.Context, b *bot.Bot, chatID int64) {
+	subs, err := store.ListSubscriptions(ctx, chatID)
+	if err != nil {
+		logger.Errorf("Ошибка получения подписок чата %d: %v", chatID, err)
+		sendMessage(ctx, b, chatID, "Ошибка при получении подписок")
+		return
+	}
+	if len(subs) == 0 {
+		sendMessage(ctx, b, chatID, "Чат не подписан ни на одну подборку.\n"+
+			"Подписаться: /subscribe код_подборки (код выдаёт /publish в чате с событиями)")
+		return
+	}
+	msg := "Подписки чата:\n"
+	for _, c := range subs {
+		msg += fmt.Sprintf("- %s (код %s) — /unsubscribe %s\n", collectionTitle(c), c.Code, c.Code)
+	}
+	sendMessage(ctx, b, chatID, msg)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+func TestPublishPrivateChatHidesUserName(t *testing.T) {
+	const userID = 900
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+
+	update := textMessage(userID, userID, "/publish")
+	update.Message.Chat.FirstName, update.Message.Chat.LastName = "Иван", "Петров"
+	handlePublish(ctx, b, update)
+
+	c, err := store.GetCollection(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Title != "" {
+		t.Errorf("private chat collection title = %q, want empty", c.Title)
+	}
+
+	// Подписчик видит подборку под кодом
+	ft.members[1] = string(tgmodels.ChatMemberTypeAdministrator)
+	handleSubscribe(ctx, b, textMessage(-901, 1, "/subscribe "+c.Code))
+	text := ft.lastSent(-901)
+	expectContains(t, "subscribe", text, c.Code)
+	if strings.Contains(text, "Иван") {
+		t.Errorf("subscriber sees the publisher's name: %q", text)
+	}
+}
+
+func TestPublishGroupKeepsTitle(t *testing.T) {
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+	ft.members[1] = string(tgmodels.ChatMemberTypeOwner)
+
+	update := textMessage(-902, 1, "/publish")
+	update.Message.Chat.Title = "Клуб"
+	handlePublish(ctx, b, update)
+
+	c, err := store.GetCollection(ctx, -902)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Title != "Клуб" {
+		t.Errorf("group collection title = %q, want %q", c.Title, "Клуб")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+// canManageEvent проверяет, может ли пользователь удалять и изменять событие:
+// это разрешено его автору (запись в user_events) и администраторам чата.
+// В личном чате пользователь — единственный участник, поэтому ему разрешено всё.
+func canManageEvent(ctx context.Context, b *bot.Bot, chat tgmodels.Chat, userID int64, event *storage.Event) bool {
+	if chat.Type == tgmodels.ChatTypePrivate {
+		return true
+	}
+
+	owner, err := store.IsEventOwner(ctx, event.ID, userID)
+	if err != nil {
+		logger.Errorf("Ошибка проверки автора события %d: %v", event.ID, err)
+	}
+	if owner {
+		return true
+	}
+
+	return isChatAdmin(ctx, b, chat.ID, userID)
+}
+
+// isChatAdmin сообщает, является ли пользователь владельцем или администратором чата.
+func isChatAdmin(ctx context.Context, b *bot.Bot, chatID, userID int64) bool {
+	member, err := b.GetChatMember(ctx, &bot.GetChatMemberParams{ChatID: chatID, UserID: userID})
+	if err != nil {
+		logger.Errorf("Ошибка получения участника чата %d (user_id=%d): %v", chatID, userID, err)
+		return false
+	}
+	return member.Type == tgmodels.ChatMemberTypeOwner || member.Type == tgmodels.ChatMemberTypeAdministrator
+}
+
+// canManageFromMessage — canManageEvent для автора сообщения. Анонимные администраторы
+// пишут от имени самого чата, их права подтверждает Telegram.
+func canManageFromMessage(ctx context.Context, b *bot.Bot, msg *tgmodels.Message, event *storage.Event) bool {
+	if msg.SenderChat != nil && msg.SenderChat.ID == msg.Chat.ID {
+		return true
+	}
+	if msg.From == nil {
+		return false
+	}
+	return canManageEvent(ctx, b, msg.Chat, msg.From.ID, event)
+}
+
+const manageDeniedText = "⛔ Изменять и удалять событие может только его автор или администратор чата"
+
+// handleDelete запрашивает подтверждение удаления события: /delete <event_name>.
+func handleDelete(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil {
+		return
+	}
+	parts := splitArgs(normalizeCommand(update.Message.Text))
+	if len(parts) == 0 || parts[0] != "/delete" {
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	if len(parts) != 2 {
+		sendMessage(ctx, b, chatID, "Используйте формат: /delete event_name")
+		return
+	}
+
+	name := unquoteTitle(parts[1])
+	event, err := findChatEvent(ctx, chatID, name)
+	if err != nil {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Событие '%s' не найдено", name))
+		return
+	}
+
+	if !canManageFromMessage(ctx, b, update.Message, event) {
+		sendMessage(ctx, b, chatID, manageDeniedText)
+		return
+	}
+
+	loc := chatLocation(ctx, chatID)
+	kb := &tgmodels.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgmodels.InlineKeyboardButton{{
+			{Text: "🗑 Да, удалить", CallbackData: fmt.Sprintf("del:yes:%d", event.ID)},
+			{Text: "❌ Нет", CallbackData: fmt.Sprintf("del:no:%d", event.ID)},
+		}},
+	}
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        fmt.Sprintf("Удалить событие <b>%s</b> (%s) вместе с напоминаниями?", html.EscapeString(event.DisplayTitle()), displayDate(*event, loc)),
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: kb,
+	})
+	if err != nil {
+		logger.Errorf("Ошибка отправки подтверждения удаления chat_id=%d: %v", chatID, err)
+	}
+}
+
+// handleDeleteCallback обрабатывает кнопки подтверждения удаления: del:yes:<event_id>, del:no:<event_id>.
+// Права проверяются заново: нажать кнопку может любой участник чата.
+func handleDeleteCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	cb := update.CallbackQuery
+	if cb == nil || cb.Message.Message == nil {
+		return
+	}
+
+	chat := cb.Message.Message.Chat
+	messageID := cb.Message.Message.ID
+	answer := ""
+
+	defer func() {
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID, Text: answer})
+	}()
+
+	parts := strings.Split(cb.Data, ":")
+	if len(parts) != 3 {
+		return
+	}
+	eventID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return
+	}
+
+	edit := func(text string) {
+		b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chat.ID,
+			MessageID: messageID,
+			Text:      text,
+			ParseMode: tgmodels.ParseModeHTML,
+		})
+	}
+
+	// Событие должно принадлежать чату, из которого пришёл callback
+	event, err := store.GetEventByID(ctx, eventID)
+	if err != nil || event.ChatID != chat.ID {
+		answer = "Событие не найдено"
+		edit("Событие уже удалено")
+		return
+	}
+
+	if !canManageEvent(ctx, b, chat, cb.From.ID, event) {
+		answer = manageDeniedText
+		return
+	}
+
+	switch parts[1] {
+	case "no":
+		edit(fmt.Sprintf("Удаление события <b>%s</b> отменено", html.EscapeString(event.DisplayTitle())))
+	case "yes":
+		// Закреплённый отсчёт больше не обновится — помечаем его до удаления события
+		if pin, err := store.GetPin(ctx, event.ID); err == nil {
+			stopPin(ctx, b, pin, fmt.Sprintf("🗑 Событие <b>%s</b> удалено", html.EscapeString(event.DisplayTitle())))
+		}
+		// Напоминания, привязки к пользователям и закреплённый отсчёт удаляются каскадно
+		if err := store.DeleteEvent(ctx, chat.ID, event.Name); err != nil {
+			logger.Errorf("Ошибка удаления события '%s' (chat_id=%d): %v", event.Name, chat.ID, err)
+			answer = "Ошибка при удалении события"
+			return
+		}
+		logger.Infof("Событие удалено: %s (chat_id=%d, user_id=%d)", event.Name, chat.ID, cb.From.ID)
+		edit(fmt.Sprintf("🗑 Событие <b>%s</b> удалено", html.EscapeString(event.DisplayTitle())))
+		syncChatCommands(ctx, b, chat.ID)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDeleteEvent(t *testing.T) {
+	const (
+		chatID   = -500
+		authorID = 1
+		otherID  = 2
+	)
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+	e := ownedEvent(t, chatID, authorID, "party", time.Now().Add(24*time.Hour))
+
+	handleDelete(ctx, b, textMessage(chatID, otherID, "/delete party"))
+	if got := ft.lastSent(chatID); got != manageDeniedText {
+		t.Errorf("stranger /delete: got %q, want denial", got)
+	}
+	handleDeleteCallback(ctx, b, callbackQuery(chatID, otherID, fmt.Sprintf("del:yes:%d", e.ID)))
+	if _, err := store.GetEvent(ctx, chatID, "party"); err != nil {
+		t.Fatalf("stranger deleted the event: %v", err)
+	}
+
+	handleDelete(ctx, b, textMessage(chatID, authorID, "/delete party"))
+	expectContains(t, "confirmation", ft.lastSent(chatID), "party")
+	handleDeleteCallback(ctx, b, callbackQuery(chatID, authorID, fmt.Sprintf("del:no:%d", e.ID)))
+	if _, err := store.GetEvent(ctx, chatID, "party"); err != nil {
+		t.Fatalf("cancelled delete removed the event: %v", err)
+	}
+
+	handleDeleteCallback(ctx, b, callbackQuery(chatID, authorID, fmt.Sprintf("del:yes:%d", e.ID)))
+	expectContains(t, "delete", ft.lastSent(chatID), "удалено")
+	if _, err := store.GetEvent(ctx, chatID, "party"); err == nil {
+		t.Error("event still exists after delete")
+	}
+
+	// Повторное нажатие после удаления
+	handleDeleteCallback(ctx, b, callbackQuery(chatID, authorID, fmt.Sprintf("del:yes:%d", e.ID)))
+	if got := ft.answered(); got != "Событие не найдено" {
+		t.Errorf("second delete: answer %q", got)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/dateparse"
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+const (
+	// defaultDigestDays — на сколько дней вперёд дайджест показывает события, если не указано.
+	defaultDigestDays = 7
+	maxDigestDays     = 366
+	// missedDigestWindow — если выпуск должен был уйти раньше, чем столько времени назад
+	// (бот был недоступен), он пропускается: устаревший дайджест бесполезен.
+	missedDigestWindow = time.Hour
+)
+
+const digestDeniedText = "⛔ Настраивать дайджест чата может только администратор чата"
+
+// digestWeekdays — день недели еженедельного дайджеста для сообщений: «по понедельникам».
+var digestWeekdays = map[time.Weekday]string{
+	time.Monday: "по понедельникам", time.Tuesday: "по вторникам", time.Wednesday: "по средам",
+	time.Thursday: "по четвергам", time.Friday: "по пятницам", time.Saturday: "по субботам",
+	time.Sunday: "по воскресеньям",
+}
+
+// lastDigestSlot возвращает последний момент отправки дайджеста, не позже now.
+// Моменты считаются по «настенному» времени чата, поэтому переход на летнее время их не сдвигает.
+func lastDigestSlot(d storage.Digest, loc *time.Location, now time.Time) time.Time {
+	local := now.In(loc)
+	slot := time.Date(local.Year(), local.Month(), local.Day(), d.Minute/60, d.Minute%60, 0, 0, loc)
+	step := 1
+	if d.Schedule == storage.DigestWeekly {
+		step = 7
+		slot = slot.AddDate(0, 0, -((int(local.Weekday()) - int(d.Weekday) + 7) % 7))
+	}
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -step)
+	}
+	return slot
+}
+
+// nextDigestSlot возвращает ближайший момент отправки дайджеста после now.
+func nextDigestSlot(d storage.Digest, loc *time.Location, now time.Time) time.Time {
+	step := 1
+	if d.Schedule == storage.DigestWeekly {
+		step = 7
+	}
+	return lastDigestSlot(d, loc, now).AddDate(0, 0, step)
+}
+
+// describeDigest описывает расписание дайджеста: «каждый день в 09:00, события на 7 дней вперёд».
+func describeDigest(d storage.Digest) string {
+	when := "каждый день"
+	if d.Schedule == storage.DigestWeekly {
+		when = digestWeekdays[d.Weekday]
+	}
+	return fmt.Sprintf("%s в %02d:%02d, события на %d %s вперёд",
+		when, d.Minute/60, d.Minute%60, d.Days, pluralRu(d.Days, "день", "дня", "дней"))
+}
+
+// parseDigestArgs разбирает аргументы /digest после расписания:
+//
+//	daily 09:00 [7d]
+//	weekly mon 09:00 [14d]
+func parseDigestArgs(args []string) (storage.Digest, error) {
+	d := storage.Digest{Days: defaultDigestDays}
+	if len(args) == 0 {
+		return d, errors.New("не указано расписание")
+	}
+
+	switch strings.ToLower(args[0]) {
+	case storage.DigestDaily:
+		d.Schedule = storage.DigestDaily
+		args = args[1:]
+	case storage.DigestWeekly:
+		d.Schedule = storage.DigestWeekly
+		if len(args) < 2 {
+			return d, errors.New("не указан день недели")
+		}
+		wd, ok := dateparse.Weekday(args[1])
+		if !ok {
+			return d, fmt.Errorf("неизвестный день недели %q", args[1])
+		}
+		d.Weekday = wd
+		args = args[2:]
+	default:
+		return d, fmt.Errorf("неизвестное расписание %q (daily или weekly)", args[0])
+	}
+
+	if len(args) == 0 {
+		return d, errors.New("не указано время отправки")
+	}
+	at, err := time.Parse("15:04", args[0])
+	if err != nil {
+		return d, fmt.Errorf("некорректное время %q (ожидается ЧЧ:ММ)", args[0])
+	}
+	d.Minute = at.Hour()*60 + at.Minute()
+	args = args[1:]
+
+	if len(args) > 0 {
+		days, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(args[0]), "d"))
+		if err != nil || days < 1 || days > maxDigestDays {
+			return d, fmt.Errorf("некорректный период %q (от 1d до %dd)", args[0], maxDigestDays)
+		}
+		d.Days = days
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		return d, fmt.Errorf("лишние аргументы: %s", strings.Join(args, " "))
+	}
+	return d, nil
+}
+
+// digestText формирует дайджест событий чата на d.Days дней вперёд с отсчётом до каждого.
+// Возвращает пустую строку, если в этот период событий нет.
+func digestText(ctx context.Context, chatID int64, d storage.Digest, loc *time.Location, now time.Time) (string, error) {
+	events, err := store.ListEvents(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+
+	type entry struct {
+		event storage.Event
+		date  time.Time
+	}
+	until := now.AddDate(0, 0, d.Days)
+	var upcoming []entry
+	for _, e := range events {
+		// Для повторяющихся событий берём ближайшее вхождение
+		date, ok, err := eventOccurrence(e, loc, now)
+		if err != nil || !ok || date.After(until) {
+			continue
+		}
+		upcoming = append(upcoming, entry{e, date})
+	}
+	if len(upcoming) == 0 {
+		return "", nil
+	}
+	sort.Slice(upcoming, func(i, j int) bool { return upcoming[i].date.Before(upcoming[j].date) })
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🗓 Дайджест: события на %d %s вперёд\n", d.Days, pluralRu(d.Days, "день", "дня", "дней"))
+	for _, u := range upcoming {
+		left := "меньше минуты"
+		if shown, _ := countdownStep(u.date.Sub(now)); shown > 0 {
+			left = formatOffset(shown)
+		}
+		fmt.Fprintf(&sb, "\n• <b>%s</b> — %s, через %s /%s",
+			html.EscapeString(u.event.DisplayTitle()), formatEventTime(u.date, loc), left, u.event.Name)
+	}
+	return sb.String(), nil
+}
+
+// ──────────────────────────── /digest ────────────────────────────
+
+// handleDigest настраивает дайджест предстоящих событий чата:
+//
+//	/digest                     — текущее расписание
+//	/digest daily 09:00 [7d]    — каждый день в 09:00 по времени чата
+//	/digest weekly mon 09:00    — раз в неделю
+//	/digest off                 — выключить
+//
+// В группе менять расписание может только администратор, посмотреть — любой участник.
+func handleDigest(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil {
+		return
+	}
+	parts := strings.Fields(normalizeCommand(update.Message.Text))
+	if len(parts) == 0 || parts[0] != "/digest" {
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	settings, err := store.GetChatSettings(ctx, chatID)
+	if err != nil {
+		logger.Errorf("Ошибка получения настроек чата %d: %v", chatID, err)
+		sendMessage(ctx, b, chatID, "Ошибка при получении настроек чата")
+		return
+	}
+	loc := settingsLocation(settings)
+
+	const usage = "Используйте формат:\n" +
+		"/digest daily 09:00 — каждый день\n" +
+		"/digest weekly mon 09:00 — раз в неделю\n" +
+		"/digest daily 09:00 14d — события на 14 дней вперёд (по умолчанию 7)\n" +
+		"/digest off — выключить"
+
+	if len(parts) == 1 {
+		if settings.Digest.Schedule == "" {
+			sendMessage(ctx, b, chatID, "🗓 Дайджест выключен.\n"+usage)
+			return
+		}
+		sendMessage(ctx, b, chatID, fmt.Sprintf("🗓 Дайджест: %s (%s).\nСледующий: %s\n/digest off — выключить",
+			describeDigest(settings.Digest), loc, formatEventTime(nextDigestSlot(settings.Digest, loc, time.Now()), loc)))
+		return
+	}
+
+	if !canManageChat(ctx, b, update.Message) {
+		sendMessage(ctx, b, chatID, digestDeniedText)
+		return
+	}
+
+	if strings.ToLower(parts[1]) == "off" {
+		if err := store.SetChatDigest(ctx, chatID, storage.Digest{}); err != nil {
+			logger.Errorf("Ошибка выключения дайджеста чата %d: %v", chatID, err)
+			sendMessage(ctx, b, chatID, "Ошибка при сохранении дайджеста")
+			return
+		}
+		sendMessage(ctx, b, chatID, "🗓 Дайджест выключен")
+		return
+	}
+
+	d, err := parseDigestArgs(parts[1:])
+	if err != nil {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Ошибка: %s\n%s", err, usage))
+		return
+	}
+	if err := store.SetChatDigest(ctx, chatID, d); err != nil {
+		logger.Errorf("Ошибка сохранения дайджеста чата %d: %v", chatID, err)
+		sendMessage(ctx, b, chatID, "Ошибка при сохранении дайджеста")
+		return
+	}
+
+	// Уже прошедший сегодня выпуск отмечаем отправленным, иначе планировщик отправит его сразу
+	now := time.Now()
+	if _, err := store.ClaimDigest(ctx, chatID, lastDigestSlot(d, loc, now)); err != nil {
+		logger.Errorf("Ошибка записи дайджеста чата %d: %v", chatID, err)
+	}
+
+	logger.Infof("Дайджест чата %d: %s %v %d мин, %d дн.", chatID, d.Schedule, d.Weekday, d.Minute, d.Days)
+	sendMessage(ctx, b, chatID, fmt.Sprintf("✅ Дайджест: %s (%s).\nСледующий: %s",
+		describeDigest(d), loc, formatEventTime(nextDigestSlot(d, loc, now), loc)))
+}
+
+// ──────────────────────────── отправка ────────────────────────────
+
+// sendDigests отправляет наступившие выпуски дайджестов. Возвращает число отправленных.
+func (s *scheduler) sendDigests(ctx context.Context, now time.Time) (int, error) {
+	chats, err := store.ListDigestChats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, cs := range chats {
+		if ctx.Err() != nil {
+			break
+		}
+		if s.processDigest(ctx, cs, now) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// processDigest отправляет последний наступивший выпуск дайджеста чата, если он ещё не отправлен.
+// Выпуск отмечается до отправки (как напоминания), поэтому несколько реплик не дублируют его.
+func (s *scheduler) processDigest(ctx context.Context, cs storage.ChatSettings, now time.Time) bool {
+	log := WithComponent("scheduler").WithField("chat_id", cs.ChatID)
+
+	loc := settingsLocation(&cs)
+	slot := lastDigestSlot(cs.Digest, loc, now)
+	if now.Sub(slot) > missedDigestWindow {
+		return false
+	}
+
+	claimed, err := store.ClaimDigest(ctx, cs.ChatID, slot)
+	if err != nil {
+		log.Errorf("Ошибка записи дайджеста: %v", err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	text, err := digestText(ctx, cs.ChatID, cs.Digest, loc, now)
+	if err == nil && text == "" {
+		// Событий нет — выпуск считается отправленным, пустое сообщение чату не нужно
+		log.Debugf("Дайджест на %s пропущен: нет событий", formatEventTime(slot, loc))
+		return false
+	}
+	if err == nil {
+		_, err = s.b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    cs.ChatID,
+			Text:      text,
+			ParseMode: tgmodels.ParseModeHTML,
+		})
+	}
+	if err != nil {
+		log.Errorf("Ошибка отправки дайджеста: %v", err)
+		// Снимаем отметку, чтобы повторить попытку на следующем проходе
+		if err := store.ReleaseDigest(ctx, cs.ChatID, slot); err != nil {
+			log.Errorf("Ошибка снятия отметки дайджеста: %v", err)
+		}
+		return false
+	}
+
+	log.Infof("Дайджест отправлен (выпуск %s)", formatEventTime(slot, loc))
+	return true
+}
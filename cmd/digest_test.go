@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+func TestDigestRequiresChatAdmin(t *testing.T) {
+	const (
+		chatID   = -400
+		memberID = 1
+		adminID  = 2
+	)
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+	ft.members[adminID] = string(tgmodels.ChatMemberTypeAdministrator)
+
+	digest := func() string {
+		t.Helper()
+		settings, err := store.GetChatSettings(ctx, chatID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return settings.Digest.Schedule
+	}
+
+	handleDigest(ctx, b, textMessage(chatID, memberID, "/digest daily 09:00"))
+	if got := ft.lastSent(chatID); got != digestDeniedText {
+		t.Errorf("member /digest daily: got %q, want denial", got)
+	}
+	if s := digest(); s != "" {
+		t.Fatalf("member enabled digest: %q", s)
+	}
+
+	handleDigest(ctx, b, textMessage(chatID, adminID, "/digest daily 09:00"))
+	if s := digest(); s == "" {
+		t.Fatal("admin /digest daily: digest not enabled")
+	}
+
+	// Посмотреть расписание может любой участник, выключить — нет
+	handleDigest(ctx, b, textMessage(chatID, memberID, "/digest"))
+	expectContains(t, "member /digest", ft.lastSent(chatID), "Дайджест:")
+	handleDigest(ctx, b, textMessage(chatID, memberID, "/digest off"))
+	if got := ft.lastSent(chatID); got != digestDeniedText {
+		t.Errorf("member /digest off: got %q, want denial", got)
+	}
+	if s := digest(); s == "" {
+		t.Error("member disabled digest")
+	}
+}
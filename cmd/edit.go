@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/eventname"
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+// ──────────────────────────── ожидание ввода при редактировании ────────────────────────────
+
+// editInput — поле события, новое значение которого пользователь должен прислать текстом.
+type editInput struct {
+	EventID  int64
+	Field    string // "desc" или "name"
+	PromptID int    // сообщение с запросом нового значения
+}
+
+func setAwaitingEdit(ctx context.Context, chatID, userID int64, in editInput) {
+	saveSession(ctx, chatID, userID, sessionAwaitingEdit, in)
+}
+
+func getAwaitingEdit(ctx context.Context, chatID, userID int64) (editInput, bool) {
+	var in editInput
+	ok := loadSession(ctx, chatID, userID, sessionAwaitingEdit, &in)
+	return in, ok
+}
+
+// editReply возвращает ожидаемый ввод, если msg — ответ на запрос нового значения.
+func editReply(ctx context.Context, msg *tgmodels.Message) (editInput, bool) {
+	if msg.From == nil {
+		return editInput{}, false
+	}
+	in, ok := getAwaitingEdit(ctx, msg.Chat.ID, msg.From.ID)
+	return in, ok && repliesTo(msg, in.PromptID)
+}
+
+func clearAwaitingEdit(ctx context.Context, chatID, userID int64) {
+	dropSession(ctx, chatID, userID, sessionAwaitingEdit)
+}
+
+// ──────────────────────────── /edit ────────────────────────────
+
+// buildEditMenu формирует текст и inline-клавиатуру меню редактирования события.
+func buildEditMenu(event *storage.Event, loc *time.Location) (string, *tgmodels.InlineKeyboardMarkup) {
+	text := fmt.Sprintf("✏️ Событие <b>%s</b> (/%s)\nДата: %s\n",
+		html.EscapeString(event.DisplayTitle()), event.Name, displayDate(*event, loc))
+	if event.Description != "" {
+		text += fmt.Sprintf("Описание: %s\n", html.EscapeString(event.Description))
+	}
+	text += "Что изменить?"
+
+	kb := &tgmodels.InlineKeyboardMarkup{InlineKeyboard: [][]tgmodels.InlineKeyboardButton{
+		{
+			{Text: "📅 Дата", CallbackData: fmt.Sprintf("edit:date:%d", event.ID)},
+			{Text: "📝 Описание", CallbackData: fmt.Sprintf("edit:desc:%d", event.ID)},
+			{Text: "🏷 Название", CallbackData: fmt.Sprintf("edit:name:%d", event.ID)},
+		},
+		{{Text: "✅ Готово", CallbackData: fmt.Sprintf("edit:close:%d", event.ID)}},
+	}}
+	return text, kb
+}
+
+// handleEdit открывает меню редактирования события: /edit <event_name>.
+func handleEdit(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil {
+		return
+	}
+	parts := splitArgs(normalizeCommand(update.Message.Text))
+	if len(parts) == 0 || parts[0] != "/edit" {
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	if len(parts) != 2 {
+		sendMessage(ctx, b, chatID, "Используйте формат: /edit event_name")
+		return
+	}
+
+	name := unquoteTitle(parts[1])
+	event, err := findChatEvent(ctx, chatID, name)
+	if err != nil {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Событие '%s' не найдено", name))
+		return
+	}
+	if !canManageFromMessage(ctx, b, update.Message, event) {
+		sendMessage(ctx, b, chatID, manageDeniedText)
+		return
+	}
+
+	text, kb := buildEditMenu(event, chatLocation(ctx, chatID))
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        text,
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: kb,
+	})
+	if err != nil {
+		logger.Errorf("Ошибка отправки меню редактирования chat_id=%d: %v", chatID, err)
+	}
+}
+
+// handleEditCallback обрабатывает кнопки меню /edit: edit:date|desc|name|close:<event_id>.
+func handleEditCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	cb := update.CallbackQuery
+	if cb == nil || cb.Message.Message == nil {
+		return
+	}
+
+	chat := cb.Message.Message.Chat
+	userID := cb.From.ID
+	messageID := cb.Message.Message.ID
+	answer := ""
+
+	defer func() {
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID, Text: answer})
+	}()
+
+	parts := strings.Split(cb.Data, ":")
+	if len(parts) != 3 {
+		return
+	}
+	eventID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return
+	}
+
+	// Событие должно принадлежать чату, из которого пришёл callback
+	event, err := store.GetEventByID(ctx, eventID)
+	if err != nil || event.ChatID != chat.ID {
+		answer = "Событие не найдено"
+		return
+	}
+	if !canManageEvent(ctx, b, chat, userID, event) {
+		answer = manageDeniedText
+		return
+	}
+
+	switch parts[1] {
+	case "date":
+		// Дата выбирается тем же календарём, что и при создании; правило повторения сохраняется
+		setPending(ctx, chat.ID, userID, &pendingEvent{
+			Name:          event.Name,
+			Title:         event.DisplayTitle(),
+			Description:   event.Description,
+			ChatID:        chat.ID,
+			UserID:        userID,
+			Hour:          -1,
+			Recurrence:    event.Recurrence,
+			RecurrenceSet: true,
+			EditEventID:   event.ID,
+			MessageID:     messageID,
+		})
+		now := time.Now().In(chatLocation(ctx, chat.ID))
+		editCalendar(ctx, b, chat.ID, messageID, event.DisplayTitle(), now.Year(), now.Month())
+
+	case "desc", "name":
+		title := html.EscapeString(event.DisplayTitle())
+		prompt := fmt.Sprintf("📝 Ответьте на это сообщение новым описанием события <b>%s</b> («-» — без описания, /cancel — отмена)", title)
+		if parts[1] == "name" {
+			prompt = fmt.Sprintf("🏷 Ответьте на это сообщение новым названием события <b>%s</b> (/cancel — отмена)", title)
+		}
+		if promptID := sendPrompt(ctx, b, chat.ID, 0, prompt); promptID != 0 {
+			setAwaitingEdit(ctx, chat.ID, userID, editInput{EventID: event.ID, Field: parts[1], PromptID: promptID})
+		}
+
+	case "close":
+		b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chat.ID,
+			MessageID: messageID,
+			Text:      fmt.Sprintf("✅ Редактирование события <b>%s</b> завершено", html.EscapeString(event.DisplayTitle())),
+			ParseMode: tgmodels.ParseModeHTML,
+		})
+	}
+}
+
+// handleEditInput применяет новое описание или название, присланное после кнопки меню /edit.
+func handleEditInput(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	text := strings.TrimSpace(update.Message.Text)
+
+	in, ok := editReply(ctx, update.Message)
+	if !ok {
+		return
+	}
+	clearAwaitingEdit(ctx, chatID, userID)
+
+	if text == "" || text == "/cancel" {
+		sendMessage(ctx, b, chatID, "❌ Изменение отменено.")
+		return
+	}
+
+	event, err := store.GetEventByID(ctx, in.EventID)
+	if err != nil || event.ChatID != chatID {
+		sendMessage(ctx, b, chatID, "Событие не найдено")
+		return
+	}
+	oldName, oldTitle := event.Name, event.DisplayTitle()
+
+	switch in.Field {
+	case "desc":
+		if text == "-" {
+			text = ""
+		}
+		event.Description = text
+	case "name":
+		// Название может состоять из нескольких слов, команда получается из него
+		title := unquoteTitle(strings.TrimPrefix(text, "/"))
+		name, err := eventname.Normalize(title)
+		if err != nil {
+			sendMessage(ctx, b, chatID, eventNameErrorText(title, err))
+			return
+		}
+		if name == oldName && title == event.DisplayTitle() {
+			sendMessage(ctx, b, chatID, fmt.Sprintf("Событие уже называется '%s'", title))
+			return
+		}
+		event.Name = name
+		event.Title = title
+	default:
+		return
+	}
+
+	if err := store.UpdateEvent(ctx, event); err != nil {
+		if errors.Is(err, storage.ErrEventExists) {
+			sendMessage(ctx, b, chatID, fmt.Sprintf("❌ Событие /%s уже существует в этом чате", event.Name))
+			return
+		}
+		logger.Errorf("Ошибка изменения события %d: %v", event.ID, err)
+		sendMessage(ctx, b, chatID, "Ошибка при изменении события")
+		return
+	}
+
+	// Название и описание видны в меню команд чата, название — и в закреплённом отсчёте
+	syncChatCommands(ctx, b, chatID)
+	refreshPin(ctx, event.ID)
+
+	if in.Field == "name" {
+		logger.Infof("Событие переименовано: %s → %s (chat_id=%d)", oldName, event.Name, chatID)
+		sendMessage(ctx, b, chatID, fmt.Sprintf("✅ Событие '%s' переименовано в '%s'. Используйте /%s для информации.",
+			oldTitle, event.Title, event.Name))
+		return
+	}
+	logger.Infof("Описание события изменено: %s (chat_id=%d)", event.Name, chatID)
+	sendMessage(ctx, b, chatID, fmt.Sprintf("✅ Описание события '%s' изменено", event.DisplayTitle()))
+}
+
+// applyPendingDate сохраняет дату, выбранную в календаре для существующего события (/edit).
+func applyPendingDate(ctx context.Context, b *bot.Bot, chatID, userID int64, messageID int, pe *pendingEvent, formattedDate string) {
+	defer deletePending(ctx, chatID, userID)
+
+	edit := func(text string) {
+		b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+			Text:      text,
+			ParseMode: tgmodels.ParseModeHTML,
+		})
+	}
+
+	loc := chatLocation(ctx, chatID)
+	date, err := parseEventDate(formattedDate, loc)
+	if err != nil {
+		logger.Errorf("Ошибка парсинга даты из календаря %q: %v", formattedDate, err)
+		return
+	}
+
+	event, err := store.GetEventByID(ctx, pe.EditEventID)
+	if err != nil || event.ChatID != chatID {
+		edit("❌ Событие не найдено")
+		return
+	}
+
+	// Новая дата в будущем возвращает событие в активные
+	event.Date = date
+	oldStatus := event.Status
+	if _, upcoming, err := eventOccurrence(*event, loc, time.Now()); err == nil && upcoming {
+		event.Status = storage.StatusActive
+	}
+	if err := store.UpdateEvent(ctx, event); err != nil {
+		logger.Errorf("Ошибка изменения даты события %d: %v", event.ID, err)
+		edit(fmt.Sprintf("❌ Ошибка изменения события: %s", err))
+		return
+	}
+
+	recordStatusTransition(oldStatus, event.Status)
+
+	logger.Infof("Дата события изменена: %s → %s (chat_id=%d)", event.Name, formattedDate, chatID)
+	edit(fmt.Sprintf("✅ Дата события <b>%s</b> изменена на %s", html.EscapeString(event.DisplayTitle()), formattedDate))
+	syncChatCommands(ctx, b, chatID)
+	refreshPin(ctx, event.ID)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/storage"
+)
+
+const (
+	// pollStaleAfter — если успешного getUpdates не было дольше, бот не готов. Long polling
+	// держит запрос до минуты (см. bot.WithHTTPClient в main), поэтому запас — ещё минута.
+	pollStaleAfter = 2 * time.Minute
+	// readyCheckTimeout — таймаут проверки базы в /readyz.
+	readyCheckTimeout = 2 * time.Second
+)
+
+// ──────────────────────────── готовность ────────────────────────────
+
+// readiness — состояние бота для /readyz.
+type readiness struct {
+	migrator  storage.Migrator // nil — у хранилища нет схемы (memory://)
+	startedAt time.Time
+	lastPoll  atomic.Int64 // время последнего успешного getUpdates или запроса вебхука, UnixNano
+	migrated  atomic.Bool  // миграции применены; новые появляются только с новой версией бота
+	webhook   bool         // обновления приходят через вебхук, а не getUpdates
+	stopping  atomic.Bool
+}
+
+// ready — готовность бота к работе.
+var ready = &readiness{startedAt: time.Now()}
+
+// polled отмечает успешный getUpdates или принятый вебхук.
+func (r *readiness) polled(at time.Time) {
+	r.lastPoll.Store(at.UnixNano())
+}
+
+// checkResult — результат одной проверки /readyz.
+type checkResult struct {
+	Status     string   `json:"status"`
+	Error      string   `json:"error,omitempty"`
+	DurationMs *int64   `json:"duration_ms,omitempty"`
+	LastPoll   string   `json:"last_update,omitempty"`
+	AgeSeconds *float64 `json:"age_seconds,omitempty"`
+	Pending    *int     `json:"pending,omitempty"`
+}
+
+func checkOK(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "fail"
+}
+
+// checkDatabase проверяет доступность базы.
+func (r *readiness) checkDatabase(ctx context.Context) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	err := store.Ping(ctx)
+	took := time.Since(start).Milliseconds()
+	res := checkResult{Status: checkOK(err == nil), DurationMs: &took}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// checkPolling проверяет, что бот недавно успешно получал обновления.
+// До первого ответа getUpdates отсчёт идёт от запуска. В режиме вебхука обновления
+// приходят только при активности пользователей, поэтому время последнего лишь сообщается.
+func (r *readiness) checkPolling(now time.Time) checkResult {
+	last := r.startedAt
+	if ns := r.lastPoll.Load(); ns != 0 {
+		last = time.Unix(0, ns)
+	}
+	age := now.Sub(last)
+	ageSeconds := age.Round(time.Second).Seconds()
+	res := checkResult{Status: checkOK(r.webhook || age <= pollStaleAfter), AgeSeconds: &ageSeconds}
+	if r.lastPoll.Load() != 0 {
+		res.LastPoll = last.UTC().Format(time.RFC3339)
+	}
+	if res.Status != "ok" {
+		res.Error = "no successful getUpdates for " + age.Round(time.Second).String()
+	}
+	return res
+}
+
+// checkMigrations проверяет, что все миграции схемы применены.
+func (r *readiness) checkMigrations(ctx context.Context) checkResult {
+	if r.migrator == nil || r.migrated.Load() {
+		return checkResult{Status: "ok"}
+	}
+	ctx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+	defer cancel()
+	pending, err := r.migrator.PendingMigrations(ctx)
+	if err != nil {
+		return checkResult{Status: "fail", Error: err.Error()}
+	}
+	if pending == 0 {
+		r.migrated.Store(true)
+	}
+	return checkResult{Status: checkOK(pending == 0), Pending: &pending}
+}
+
+// ──────────────────────────── /healthz, /readyz ────────────────────────────
+
+// handleHealthz отвечает, что процесс жив. Внешние зависимости не проверяются.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":          "ok",
+		"uptime":          time.Since(ready.startedAt).Round(time.Second).String(),
+		"updates_handled": inflight.handled(),
+	})
+}
+
+// handleReadyz проверяет базу, получение обновлений и миграции. Отвечает 503,
+// если хотя бы одна проверка не прошла или бот останавливается.
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]checkResult{
+		"database":   ready.checkDatabase(r.Context()),
+		"updates":    ready.checkPolling(time.Now()),
+		"migrations": ready.checkMigrations(r.Context()),
+	}
+
+	status, code := "ok", http.StatusOK
+	for _, c := range checks {
+		if c.Status != "ok" {
+			status, code = "fail", http.StatusServiceUnavailable
+		}
+	}
+	if ready.stopping.Load() {
+		status, code = "stopping", http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]any{
+		"status": status,
+		"uptime": time.Since(ready.startedAt).Round(time.Second).String(),
+		"checks": checks,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		WithComponent("http").Debugf("Ошибка записи ответа: %v", err)
+	}
+}
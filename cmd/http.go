@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ──────────────────────────── HTTP-сервер ────────────────────────────
+
+// startHTTPServer запускает служебный HTTP-сервер: метрики Prometheus (/metrics),
+// проверки живости (/healthz) и готовности (/readyz). Возвращает nil, если сервер отключён (METRICS_ADDR=off).
+func startHTTPServer(addr string) *http.Server {
+	if addr == "" || addr == "off" {
+		return nil
+	}
+	log := WithComponent("http")
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/readyz", handleReadyz)
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("HTTP-сервер остановлен с ошибкой: %v", err)
+		}
+	}()
+	log.Infof("Служебный HTTP-сервер слушает %s", addr)
+	return srv
+}
+
+// stopHTTPServer останавливает HTTP-сервер, дожидаясь текущих запросов не дольше timeout.
+func stopHTTPServer(srv *http.Server, timeout time.Duration) {
+	if srv == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		WithComponent("http").Warnf("Ошибка остановки HTTP-сервера: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/eventname"
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+// ──────────────────────────── метрики обработчиков ────────────────────────────
+
+// callbackPrefixes — префиксы callback-данных inline-клавиатур (см. регистрацию в main).
+var callbackPrefixes = map[string]bool{"cal": true, "rem": true, "del": true, "edit": true, "adm": true}
+
+// handlerLabel возвращает метку обработчика обновления для метрик: "/set_date" для системных
+// команд (eventname.IsReserved), "dynamic" для команд событий, "callback:cal" для нажатий кнопок, "text" и "edited".
+// Метки не содержат имён событий и аргументов, чтобы число рядов метрик было ограничено.
+func handlerLabel(update *tgmodels.Update) string {
+	switch {
+	case update.CallbackQuery != nil:
+		prefix, _ := callbackAction(update.CallbackQuery.Data)
+		return "callback:" + prefix
+	case update.EditedMessage != nil:
+		return "edited"
+	case update.Message != nil:
+		parts := strings.Fields(normalizeCommand(update.Message.Text))
+		if len(parts) == 0 || !strings.HasPrefix(parts[0], "/") {
+			return "text"
+		}
+		if eventname.IsReserved(strings.TrimPrefix(parts[0], "/")) {
+			return parts[0]
+		}
+		return "dynamic"
+	default:
+		return "other"
+	}
+}
+
+// callbackAction разбирает callback-данные вида "cal:day:2025-01-31" на префикс и действие ("cal", "day").
+// Неизвестные префиксы и действия с цифрами или другими символами сводятся к "other".
+func callbackAction(data string) (prefix, action string) {
+	parts := strings.SplitN(data, ":", 3)
+	prefix, action = parts[0], "other"
+	if !callbackPrefixes[prefix] {
+		return "other", action
+	}
+	if len(parts) > 1 && parts[1] != "" && strings.Trim(parts[1], "abcdefghijklmnopqrstuvwxyz_") == "" {
+		action = parts[1]
+	}
+	return prefix, action
+}
+
+// instrumentHandlers — middleware бота: считает обновления и время их обработки по обработчикам,
+// использование команд и действия inline-кнопок.
+func instrumentHandlers(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		label := handlerLabel(update)
+		if strings.HasPrefix(label, "/") || label == "dynamic" {
+			CommandUsageCounter.WithLabelValues(label).Inc()
+		}
+		if update.CallbackQuery != nil {
+			CallbackActions.WithLabelValues(callbackAction(update.CallbackQuery.Data)).Inc()
+		}
+
+		start := time.Now()
+		defer func() {
+			HandlerRequests.WithLabelValues(label).Inc()
+			HandlerDuration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+		}()
+		next(ctx, b, update)
+	}
+}
+
+// ──────────────────────────── метрики Telegram API ────────────────────────────
+
+// telegramClient — HTTP-клиент бота, который считает ошибки и время запросов к Telegram Bot API.
+type telegramClient struct {
+	client *http.Client
+}
+
+// newTelegramClient создаёт клиент с тем же таймаутом, что и клиент библиотеки по умолчанию:
+// он должен быть больше таймаута long polling getUpdates.
+func newTelegramClient(timeout time.Duration) *telegramClient {
+	return &telegramClient{client: &http.Client{Timeout: timeout}}
+}
+
+// Do выполняет запрос. Метод API — последний сегмент пути (в пути также токен, в метки он не попадает).
+func (c *telegramClient) Do(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// Отмена запроса при остановке бота ошибкой API не считается
+		if req.Context().Err() == nil {
+			TelegramAPIErrors.WithLabelValues(method, "network").Inc()
+		}
+		return resp, err
+	}
+	if method == "getUpdates" {
+		if resp.StatusCode == http.StatusOK {
+			ready.polled(time.Now())
+		}
+	} else {
+		TelegramAPIDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	}
+	if resp.StatusCode != http.StatusOK {
+		TelegramAPIErrors.WithLabelValues(method, strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, nil
+}
+
+// ──────────────────────────── метрики хранилища ────────────────────────────
+
+// observeStore записывает время вызова хранилища и его ошибку. ErrNotFound — обычный ответ, а не сбой.
+func observeStore(op string, took time.Duration, err error) {
+	DBQueryDuration.WithLabelValues(op).Observe(took.Seconds())
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		DBErrors.WithLabelValues(op).Inc()
+	}
+}
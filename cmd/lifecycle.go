@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ──────────────────────────── остановка ────────────────────────────
+
+// handlerTracker считает выполняющиеся обработчики обновлений, чтобы при остановке дождаться их.
+// sync.WaitGroup не подходит: обработчик может начаться уже после начала ожидания.
+// Начав drain, трекер больше не принимает обработчики: иначе обновление, которое бот
+// успел получить до остановки, обрабатывалось бы уже с закрытым хранилищем.
+type handlerTracker struct {
+	mu       sync.Mutex
+	active   int
+	total    int64
+	stopping bool          // drain начат, новые обработчики не запускаются
+	idle     chan struct{} // закрывается, когда во время drain завершается последний обработчик
+}
+
+// inflight — обработчики обновлений бота.
+var inflight = &handlerTracker{}
+
+// begin учитывает начало обработчика. Возвращает false, если уже идёт остановка
+// и обработчик запускать нельзя.
+func (t *handlerTracker) begin() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopping {
+		return false
+	}
+	t.active++
+	t.total++
+	return true
+}
+
+func (t *handlerTracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active--
+	if t.active == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// handled возвращает число обработчиков, начатых с запуска бота.
+func (t *handlerTracker) handled() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// drain запрещает запуск новых обработчиков и ждёт завершения выполняющихся не дольше timeout.
+// Возвращает число обработчиков, которые не успели завершиться.
+func (t *handlerTracker) drain(timeout time.Duration) int {
+	t.mu.Lock()
+	t.stopping = true
+	if t.active == 0 {
+		t.mu.Unlock()
+		return 0
+	}
+	idle := make(chan struct{})
+	t.idle = idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return 0
+	case <-time.After(timeout):
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return t.active
+	}
+}
+
+// trackHandlers — middleware бота: учитывает обработчик в inflight и отвязывает его контекст
+// от отмены корневого. Получив сигнал остановки, бот перестаёт принимать обновления,
+// а начатые обработчики дописывают в базу и отвечают пользователю. Обработчик, который
+// библиотека запустила уже после начала drain, не выполняется.
+func trackHandlers(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		if !inflight.begin() {
+			logger.Warnf("Обновление %d пришло во время остановки и не обработано", update.ID)
+			return
+		}
+		defer inflight.end()
+		next(context.WithoutCancel(ctx), b, update)
+	}
+}
+
+// waitTimeout ждёт wg не дольше timeout. Возвращает false, если время вышло.
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// shutdown завершает работу после остановки приёма обновлений: ждёт начатые обработчики
+// и фоновые задачи (планировщик, очистку сессий, отсчёты) не дольше timeout в сумме,
+// закрывает хранилище, останавливает служебный HTTP-сервер и пишет итог остановки в лог.
+func shutdown(timeout time.Duration, jobs *sync.WaitGroup, httpServer *http.Server, startedAt time.Time) {
+	log := WithComponent("shutdown")
+	ready.stopping.Store(true)
+	log.Infof("Остановка: приём обновлений прекращён, ожидание обработчиков и фоновых задач (до %s)", timeout)
+
+	begin := time.Now()
+	deadline := begin.Add(timeout)
+
+	unfinished := inflight.drain(timeout)
+	jobsStopped := waitTimeout(jobs, time.Until(deadline))
+
+	store.Close()
+	stopHTTPServer(httpServer, time.Second)
+
+	entry := log.WithFields(logrus.Fields{
+		"uptime":              time.Since(startedAt).Round(time.Second).String(),
+		"updates_handled":     inflight.handled(),
+		"handlers_unfinished": unfinished,
+		"jobs_stopped":        jobsStopped,
+		"duration_ms":         time.Since(begin).Milliseconds(),
+	})
+	if unfinished > 0 || !jobsStopped {
+		entry.Warn("Бот остановлен по таймауту: часть работы прервана")
+		return
+	}
+	entry.Info("Бот остановлен")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+func TestDrainWaitsForHandlersStartedConcurrently(t *testing.T) {
+	old := inflight
+	inflight = &handlerTracker{}
+	t.Cleanup(func() { inflight = old })
+
+	var drained atomic.Bool
+	var started, late atomic.Int32
+	handler := trackHandlers(func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		started.Add(1)
+		time.Sleep(time.Millisecond)
+		if drained.Load() {
+			late.Add(1)
+		}
+	})
+
+	// Библиотека запускает обработчики в отдельных горутинах: часть из них начинается
+	// одновременно с остановкой
+	const handlers = 200
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < handlers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			<-start
+			handler(context.Background(), nil, &tgmodels.Update{ID: int64(id)})
+		}(i)
+	}
+	close(start)
+
+	if unfinished := inflight.drain(5 * time.Second); unfinished != 0 {
+		t.Fatalf("drain: %d handlers unfinished", unfinished)
+	}
+	drained.Store(true)
+	wg.Wait()
+
+	if n := late.Load(); n != 0 {
+		t.Errorf("%d handlers ran after drain returned", n)
+	}
+	if got := inflight.handled(); got != int64(started.Load()) {
+		t.Errorf("handled = %d, started = %d", got, started.Load())
+	}
+
+	// После остановки новые обработчики не запускаются
+	before := started.Load()
+	handler(context.Background(), nil, &tgmodels.Update{ID: handlers})
+	if started.Load() != before {
+		t.Error("handler ran after shutdown")
+	}
+}
@@ -2,19 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"regexp"
+	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode"
 
 	"github.com/TheReshkin/timer-bot/internal/config"
+	"github.com/TheReshkin/timer-bot/internal/dateparse"
+	"github.com/TheReshkin/timer-bot/internal/eventname"
 	"github.com/TheReshkin/timer-bot/internal/storage"
 	"github.com/go-telegram/bot"
 	tgmodels "github.com/go-telegram/bot/models"
 )
 
-// store — глобальная ссылка на PostgreSQL хранилище
-var store *storage.PostgresStorage
+// store — хранилище, выбранное по DATABASE_URL (PostgreSQL, SQLite или память)
+var store storage.Store
 
 // handleEditedMessage обрабатывает отредактированные сообщения,
 // перенаправляя их в соответствующие обработчики команд.
@@ -27,10 +35,10 @@ func handleEditedMessage(ctx context.Context, b *bot.Bot, update *tgmodels.Updat
 		if text == "" {
 			return
 		}
-		chatID := update.Message.Chat.ID
-		userID := update.Message.From.ID
-		if isAwaitingName(chatID, userID) {
+		if isNameReply(ctx, update.Message) {
 			handleEventNameReply(ctx, b, update)
+		} else if _, ok := editReply(ctx, update.Message); ok {
+			handleEditInput(ctx, b, update)
 		}
 		return
 	}
@@ -49,13 +57,15 @@ func handleEditedMessage(ctx context.Context, b *bot.Bot, update *tgmodels.Updat
 		return
 	}
 
-	// Проверяем, ожидает ли пользователь ввод названия события
-	chatID := update.Message.Chat.ID
-	userID := update.Message.From.ID
-	if isAwaitingName(chatID, userID) {
+	// Проверяем, не исправлен ли ответ на запрос названия события или нового значения
+	if isNameReply(ctx, update.Message) {
 		handleEventNameReply(ctx, b, update)
 		return
 	}
+	if _, ok := editReply(ctx, update.Message); ok {
+		handleEditInput(ctx, b, update)
+		return
+	}
 
 	cmd := normalizeCommand(text)
 	switch {
@@ -69,6 +79,30 @@ func handleEditedMessage(ctx context.Context, b *bot.Bot, update *tgmodels.Updat
 		handleOutdated(ctx, b, update)
 	case cmd == "/help":
 		handleHelp(ctx, b, update)
+	case strings.HasPrefix(cmd, "/remind"):
+		handleRemind(ctx, b, update)
+	case strings.HasPrefix(cmd, "/repeat"):
+		handleRepeat(ctx, b, update)
+	case strings.HasPrefix(cmd, "/timezone"):
+		handleTimezone(ctx, b, update)
+	case strings.HasPrefix(cmd, "/delete"):
+		handleDelete(ctx, b, update)
+	case strings.HasPrefix(cmd, "/edit"):
+		handleEdit(ctx, b, update)
+	case strings.HasPrefix(cmd, "/pin"):
+		handlePin(ctx, b, update)
+	case strings.HasPrefix(cmd, "/unpin"):
+		handleUnpin(ctx, b, update)
+	case strings.HasPrefix(cmd, "/digest"):
+		handleDigest(ctx, b, update)
+	case strings.HasPrefix(cmd, "/publish"):
+		handlePublish(ctx, b, update)
+	case strings.HasPrefix(cmd, "/subscribe"):
+		handleSubscribe(ctx, b, update)
+	case strings.HasPrefix(cmd, "/unsubscribe"):
+		handleUnsubscribe(ctx, b, update)
+	case strings.HasPrefix(cmd, "/admin_"):
+		handleAdmin(ctx, b, update)
 	case strings.HasPrefix(cmd, "/"):
 		handleDynamicOrUnknown(ctx, b, update)
 	}
@@ -78,26 +112,52 @@ func main() {
 	// Загрузка конфигурации (читает .env внутри)
 	cfg := config.LoadConfig()
 
+	// Подкоманда управления миграциями: murmansk-bot migrate [status|up]
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(cfg, os.Args[2:]))
+	}
+
 	if cfg.Token == "" {
 		logger.Fatal("TELEGRAM_TOKEN не задан")
 	}
+	startedAt := time.Now()
 
-	// Подключение к БД
-	store = storage.NewPostgresStorage(cfg.DatabaseURL)
-	defer store.Close()
-	logger.Info("PostgreSQL подключён")
+	// Корневой контекст отменяется по SIGINT/SIGTERM (docker stop): бот перестаёт принимать
+	// обновления, фоновые задачи завершают текущий проход, затем выполняется shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Инициализация бота с default handler для отредактированных сообщений
+	// Подключение к БД
+	storeCtx, cancelStore := context.WithTimeout(ctx, 5*time.Minute)
+	var err error
+	store, err = storage.Open(storeCtx, cfg.DatabaseURL, cfg.Location)
+	cancelStore()
+	if err != nil {
+		logger.Fatalf("Ошибка подключения к хранилищу: %v", err)
+	}
+	logger.Infof("Хранилище подключено: %T", store)
+	// Состояние пула соединений и время вызовов хранилища отдаются в метрики
+	registerPoolMetrics(store)
+	// /readyz проверяет миграции, если у хранилища есть схема
+	ready.migrator, _ = store.(storage.Migrator)
+	store = storage.Observed(store, observeStore)
+	httpServer := startHTTPServer(cfg.MetricsAddr)
+
+	// Инициализация бота с default handler для отредактированных сообщений.
+	// trackHandlers позволяет дождаться начатых обработчиков при остановке,
+	// instrumentHandlers и telegramClient собирают метрики обработчиков и запросов к API.
 	b, err := bot.New(cfg.Token,
-		bot.WithAllowedUpdates(bot.AllowedUpdates{"message", "edited_message", "callback_query"}),
+		bot.WithAllowedUpdates(allowedUpdates),
 		bot.WithDefaultHandler(handleEditedMessage),
+		bot.WithMiddlewares(trackHandlers, instrumentHandlers),
+		bot.WithHTTPClient(time.Minute, newTelegramClient(time.Minute)),
 	)
 	if err != nil {
 		logger.Fatalf("Ошибка создания бота: %v", err)
 	}
 
 	// Получение имени бота
-	me, err := b.GetMe(context.Background())
+	me, err := b.GetMe(ctx)
 	if err != nil {
 		logger.Fatalf("Не удалось получить информацию о боте: %v", err)
 	}
@@ -122,39 +182,132 @@ func main() {
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/help", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 		handleHelp(ctx, b, update)
 	})
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/remind", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handleRemind(ctx, b, update)
+	})
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/repeat", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handleRepeat(ctx, b, update)
+	})
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/timezone", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handleTimezone(ctx, b, update)
+	})
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/delete", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handleDelete(ctx, b, update)
+	})
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/edit", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handleEdit(ctx, b, update)
+	})
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/pin", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handlePin(ctx, b, update)
+	})
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/unpin", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handleUnpin(ctx, b, update)
+	})
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/digest", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handleDigest(ctx, b, update)
+	})
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/publish", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handlePublish(ctx, b, update)
+	})
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/subscribe", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handleSubscribe(ctx, b, update)
+	})
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/unsubscribe", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handleUnsubscribe(ctx, b, update)
+	})
+	// Команды администратора бота (ADMIN_ID), только в личном чате
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/admin_", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		handleAdmin(ctx, b, update)
+	})
 
 	// Обработчик callback query для inline-календаря
 	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "cal:", bot.MatchTypePrefix, handleCalendarCallback)
+	// Обработчик callback query для меню напоминаний
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "rem:", bot.MatchTypePrefix, handleRemindCallback)
+	// Обработчик callback query для подтверждения удаления
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "del:", bot.MatchTypePrefix, handleDeleteCallback)
+	// Обработчик callback query для меню редактирования
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "edit:", bot.MatchTypePrefix, handleEditCallback)
+	// Обработчик callback query для команд администратора
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "adm:", bot.MatchTypePrefix, handleAdminCallback)
 
 	// Обработчик для динамических команд — регистрируем последним
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/", bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 		handleDynamicOrUnknown(ctx, b, update)
 	})
 
-	// Запуск бота
-	logger.Info("Бот запущен")
-	b.Start(context.Background())
+	// Режим webhook: обновления приходят HTTP-запросами от Telegram в те же обработчики
+	var webhookServer *http.Server
+	if cfg.UpdatesMode == config.UpdatesWebhook {
+		ready.webhook = true
+		webhookServer, err = startWebhookServer(ctx, b, cfg)
+		if err != nil {
+			logger.Fatalf("Ошибка запуска вебхука: %v", err)
+		}
+	} else {
+		clearStaleWebhook(ctx, b)
+	}
+
+	// Фоновые задачи останавливаются вместе с ctx; shutdown ждёт их перед закрытием хранилища
+	var jobs sync.WaitGroup
+	runJob := func(job func(ctx context.Context)) {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			job(ctx)
+		}()
+	}
+	// Планировщик напоминаний, статусов и дайджестов
+	runJob(newScheduler(b, cfg.ReminderOffsets, cfg.SchedulerInterval).Run)
+	// Меню команд чатов с событиями
+	runJob(func(ctx context.Context) { syncAllChatCommands(ctx, b) })
+	runJob(func(ctx context.Context) { runSessionSweeper(ctx, b) })
+	// Закреплённые отсчёты до событий
+	runJob(func(ctx context.Context) { runPinUpdater(ctx, b) })
+
+	// Запуск бота: блокируется до сигнала остановки
+	logger.Infof("Бот запущен (%s)", cfg.UpdatesMode)
+	if webhookServer != nil {
+		<-ctx.Done()
+	} else {
+		b.Start(ctx)
+	}
+
+	// Повторный сигнал завершит процесс сразу, не дожидаясь shutdown
+	stop()
+	if webhookServer != nil {
+		stopHTTPServer(webhookServer, time.Second)
+		if cfg.WebhookURL != "" {
+			deleteWebhook(b)
+		}
+	}
+	shutdown(cfg.ShutdownTimeout, &jobs, httpServer, startedAt)
 }
 
 // ──────────────────────────── утилиты ────────────────────────────
 
-// normalizeCommand удаляет суффикс @bot_username из команды
+// normalizeCommand удаляет суффикс @bot_username из команды, сохраняя аргументы
 func normalizeCommand(text string) string {
-	if idx := strings.Index(text, "@"); idx != -1 {
-		return text[:idx]
+	cmd, args, hasArgs := strings.Cut(text, " ")
+	if idx := strings.Index(cmd, "@"); idx != -1 {
+		cmd = cmd[:idx]
 	}
-	return text
+	if hasArgs {
+		return cmd + " " + args
+	}
+	return cmd
 }
 
 // parseEventDate парсит дату в форматах "YYYY-MM-DD HH:MM", "YYYY-MM-DD", "DD.MM.YYYY"
-func parseEventDate(s string) (time.Time, error) {
+// как время в часовом поясе loc.
+func parseEventDate(s string, loc *time.Location) (time.Time, error) {
 	formats := []string{
 		"2006-01-02 15:04",
 		"2006-01-02",
 		"02.01.2006",
 	}
 	for _, f := range formats {
-		if t, err := time.Parse(f, s); err == nil {
+		if t, err := time.ParseInLocation(f, s, loc); err == nil {
 			return t, nil
 		}
 	}
@@ -169,6 +322,110 @@ func looksLikeDate(s string) bool {
 	return s[0] >= '0' && s[0] <= '9'
 }
 
+// titleQuotes — пары кавычек, которыми выделяется название события из нескольких слов.
+var titleQuotes = map[rune]rune{'"': '"', '«': '»', '“': '”'}
+
+// splitArgs разбивает текст команды на аргументы как strings.Fields, но текст в кавычках
+// ("…", «…», “…”) остаётся одним аргументом вместе с кавычками.
+func splitArgs(text string) []string {
+	var (
+		args    []string
+		current []rune
+		closing rune
+	)
+	flush := func() {
+		if len(current) > 0 {
+			args = append(args, string(current))
+			current = nil
+		}
+	}
+	for _, r := range text {
+		switch {
+		case closing != 0:
+			current = append(current, r)
+			if r == closing {
+				closing = 0
+			}
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			if c, ok := titleQuotes[r]; ok && len(current) == 0 {
+				closing = c
+			}
+			current = append(current, r)
+		}
+	}
+	flush()
+	return args
+}
+
+// unquoteTitle снимает с аргумента кавычки, добавленные для названия из нескольких слов.
+func unquoteTitle(arg string) string {
+	r := []rune(arg)
+	if len(r) >= 2 && titleQuotes[r[0]] == r[len(r)-1] {
+		return strings.TrimSpace(string(r[1 : len(r)-1]))
+	}
+	return arg
+}
+
+// eventInput — название, описание и дата события, разобранные из аргументов /set_date или ответа с названием.
+type eventInput struct {
+	Title       string
+	Description string
+	At          time.Time // нулевое значение — дата не указана, её выбирают в календаре
+	Exact       bool      // дата записана в одном из форматов parseEventDate и не требует подтверждения
+}
+
+// parseEventInput разбирает "[дата] название [дата] [описание]": дата может стоять
+// перед названием ("завтра в 18:00 Встреча") или сразу после него ("Встреча в пятницу").
+// Возвращает ошибку, если название не указано.
+func parseEventInput(args []string, loc *time.Location) (eventInput, error) {
+	now := time.Now().In(loc)
+	var in eventInput
+	dateAt := func(words []string) int {
+		res, ok := dateparse.Prefix(words, now)
+		if !ok {
+			return 0
+		}
+		in.At = res.Time
+		_, err := parseEventDate(strings.Join(words[:res.Words], " "), loc)
+		in.Exact = err == nil
+		return res.Words
+	}
+
+	args = args[dateAt(args):]
+	if len(args) == 0 {
+		return eventInput{}, errors.New("не указано название события")
+	}
+	in.Title = unquoteTitle(args[0])
+	rest := args[1:]
+	if in.At.IsZero() {
+		rest = rest[dateAt(rest):]
+	}
+	in.Description = strings.Join(rest, " ")
+	return in, nil
+}
+
+// eventNameErrorText возвращает сообщение пользователю о названии события, из которого не получается команда.
+func eventNameErrorText(title string, err error) string {
+	if errors.Is(err, eventname.ErrReserved) {
+		return fmt.Sprintf("❌ Команда /%s занята ботом, выберите другое название события.", eventname.Slug(title))
+	}
+	return fmt.Sprintf("❌ Из названия '%s' не получается команда: используйте буквы или цифры.", title)
+}
+
+// findChatEvent ищет событие чата по имени команды, а если его нет — по команде,
+// полученной из введённого названия (например, "/remind ДР" найдёт событие /dr).
+func findChatEvent(ctx context.Context, chatID int64, name string) (*storage.Event, error) {
+	event, err := store.GetEvent(ctx, chatID, name)
+	if errors.Is(err, storage.ErrNotFound) {
+		if slug := eventname.Slug(name); slug != "" && slug != name {
+			return store.GetEvent(ctx, chatID, slug)
+		}
+	}
+	return event, err
+}
+
 func sendMessage(ctx context.Context, b *bot.Bot, chatID int64, text string) {
 	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: chatID,
@@ -190,89 +447,106 @@ func handleSetDate(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 		return
 	}
 
-	parts := strings.Fields(command)
 	chatID := update.Message.Chat.ID
 	userID := update.Message.From.ID
+	loc := chatLocation(ctx, chatID)
 
-	// Режим 0: /set_date (без аргументов) → запрос названия события
-	if len(parts) == 1 {
-		setAwaitingName(chatID, userID)
-		sendMessage(ctx, b, chatID, "📝 Введите название события:")
+	// Параметр repeat=<правило> допустим в любом месте после команды
+	parts, recurrenceRule, recurrenceSet, err := extractRepeatOption(splitArgs(command))
+	if err != nil {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Ошибка: %s", err))
 		return
 	}
 
-	// Режим 1: /set_date <name> [description] → интерактивный календарь
-	if len(parts) >= 2 && !looksLikeDate(parts[1]) {
-		name := parts[1]
-		description := ""
-		if len(parts) > 2 {
-			description = strings.Join(parts[2:], " ")
+	// Режим 0: /set_date (без аргументов) → запрос названия события
+	if len(parts) == 1 {
+		prompt := "📝 Ответьте на это сообщение названием события (несколько слов — в кавычках) и, если нужно, описанием (/cancel — отмена):"
+		if promptID := sendPrompt(ctx, b, chatID, update.Message.ID, prompt); promptID != 0 {
+			setAwaitingName(ctx, chatID, userID, promptID)
 		}
-
-		setPending(chatID, userID, &pendingEvent{
-			Name:        name,
-			Description: description,
-			ChatID:      chatID,
-			UserID:      userID,
-			Hour:        -1,
-		})
-
-		now := time.Now()
-		sendCalendar(ctx, b, chatID, name, now.Year(), now.Month())
 		return
 	}
 
-	// Режим 2: /set_date <date> [time] <name> [description] → прямое создание
-	if len(parts) < 3 {
+	in, err := parseEventInput(parts[1:], loc)
+	if err != nil {
 		sendMessage(ctx, b, chatID,
 			"Используйте формат:\n"+
 				"/set_date event_name [description] — интерактивный календарь\n"+
 				"/set_date YYYY-MM-DD HH:MM event_name [description]\n"+
 				"/set_date YYYY-MM-DD event_name [description]\n"+
-				"/set_date DD.MM.YYYY event_name [description]")
+				"/set_date DD.MM.YYYY event_name [description]\n"+
+				"/set_date завтра в 18:00 event_name — дата словами (через 3 дня, в пятницу, 31 декабря, next monday 9am)\n"+
+				"Название из нескольких слов пишется в кавычках: /set_date 08.03.2026 \"День рождения мамы\"\n"+
+				"Добавьте repeat=yearly (monthly, weekly, daily, 3d, FREQ=...) для повторяющегося события")
+		return
+	}
+	if in.At.IsZero() && looksLikeDate(parts[1]) {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Ошибка парсинга даты: неизвестный формат даты: %s", parts[1]))
 		return
 	}
 
-	var dateStr, name, description string
+	title := in.Title
+	name, err := eventname.Normalize(title)
+	if err != nil {
+		sendMessage(ctx, b, chatID, eventNameErrorText(title, err))
+		return
+	}
+	pe := &pendingEvent{
+		Name:          name,
+		Title:         title,
+		Description:   in.Description,
+		ChatID:        chatID,
+		UserID:        userID,
+		Hour:          -1,
+		Recurrence:    recurrenceRule,
+		RecurrenceSet: recurrenceSet,
+	}
 
-	// Проверяем, является ли третий аргумент временем (HH:MM)
-	if len(parts) >= 4 && regexp.MustCompile(`^\d{1,2}:\d{2}$`).MatchString(parts[2]) {
-		dateStr = parts[1] + " " + parts[2]
-		name = parts[3]
-		if len(parts) > 4 {
-			description = strings.Join(parts[4:], " ")
-		}
-	} else {
-		dateStr = parts[1]
-		name = parts[2]
-		if len(parts) > 3 {
-			description = strings.Join(parts[3:], " ")
-		}
+	// Режим 1: /set_date <name|"title"> [description] → интерактивный календарь
+	if in.At.IsZero() {
+		now := time.Now().In(loc)
+		pe.MessageID = sendCalendar(ctx, b, chatID, title, now.Year(), now.Month())
+		setPending(ctx, chatID, userID, pe)
+		return
 	}
 
-	// Валидация даты
-	parsedDate, err := parseEventDate(dateStr)
-	if err != nil {
-		sendMessage(ctx, b, chatID, fmt.Sprintf("Ошибка парсинга даты: %s", err))
+	// Режим 2: /set_date <дата словами> <name> [description] → подтверждение распознанной даты
+	if !in.Exact {
+		pe.At = in.At
+		pe.MessageID = sendDateConfirmation(ctx, b, chatID, pe)
+		setPending(ctx, chatID, userID, pe)
 		return
 	}
-	formattedDate := parsedDate.Format("2006-01-02 15:04")
 
-	// Создание события в БД
-	if err := store.CreateEvent(ctx, chatID, name, formattedDate, description); err != nil {
+	// Режим 3: /set_date <date> [time] <name> [description] → прямое создание
+	event := &storage.Event{
+		ChatID:      chatID,
+		Name:        name,
+		Title:       title,
+		Date:        in.At,
+		Description: in.Description,
+		Recurrence:  recurrenceRule,
+	}
+	if err := store.CreateEvent(ctx, event); err != nil {
+		if errors.Is(err, storage.ErrEventExists) {
+			sendMessage(ctx, b, chatID, fmt.Sprintf("Событие '%s' уже существует в этом чате", name))
+			return
+		}
+		logger.Errorf("Ошибка создания события '%s': %v", name, err)
 		sendMessage(ctx, b, chatID, fmt.Sprintf("Ошибка: %s", err))
 		return
 	}
 
 	// Привязка события к пользователю
-	event, err := store.GetEvent(ctx, chatID, name)
-	if err == nil && event != nil {
-		_ = store.AddEventToUser(ctx, chatID, userID, event.ID)
-	}
+	_ = store.AddEventToUser(ctx, chatID, userID, event.ID)
 
 	logger.Infof("Событие создано: %s (chat_id=%d)", name, chatID)
-	sendMessage(ctx, b, chatID,
-		fmt.Sprintf("Событие '%s' добавлено! Используйте /%s для информации.", name, name))
+	msg := fmt.Sprintf("Событие '%s' добавлено! Используйте /%s для информации.", title, name)
+	if recurrenceRule != "" {
+		msg += fmt.Sprintf("\nПовторяется %s.", describeRecurrence(recurrenceRule))
+	}
+	sendMessage(ctx, b, chatID, msg)
+	syncChatCommands(ctx, b, chatID)
 }
 
 // handleEventNameReply обрабатывает текстовый ввод названия события
@@ -288,37 +562,63 @@ func handleEventNameReply(ctx context.Context, b *bot.Bot, update *tgmodels.Upda
 
 	// Если пользователь передумал
 	if text == "" || text == "/cancel" {
-		clearAwaitingName(chatID, userID)
+		clearAwaitingName(ctx, chatID, userID)
 		sendMessage(ctx, b, chatID, "❌ Создание события отменено.")
 		return
 	}
 
 	// Если ввели другую команду — сбрасываем ожидание
 	if strings.HasPrefix(text, "/") {
-		clearAwaitingName(chatID, userID)
+		clearAwaitingName(ctx, chatID, userID)
 		return
 	}
 
-	clearAwaitingName(chatID, userID)
+	clearAwaitingName(ctx, chatID, userID)
 
-	// Первое слово — название, остальное — описание
-	parts := strings.Fields(text)
-	name := parts[0]
-	description := ""
-	if len(parts) > 1 {
-		description = strings.Join(parts[1:], " ")
+	parts, recurrenceRule, recurrenceSet, err := extractRepeatOption(splitArgs(text))
+	if err != nil {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Ошибка: %s", err))
+		return
+	}
+	loc := chatLocation(ctx, chatID)
+	in, err := parseEventInput(parts, loc)
+	if err != nil {
+		// Введена только дата — ждём ответ ещё раз
+		prompt := "❌ Не указано название события. Ответьте на это сообщение, например: завтра в 18:00 Встреча"
+		if promptID := sendPrompt(ctx, b, chatID, update.Message.ID, prompt); promptID != 0 {
+			setAwaitingName(ctx, chatID, userID, promptID)
+		}
+		return
 	}
 
-	setPending(chatID, userID, &pendingEvent{
-		Name:        name,
-		Description: description,
-		ChatID:      chatID,
-		UserID:      userID,
-		Hour:        -1,
-	})
+	title := in.Title
+	name, err := eventname.Normalize(title)
+	if err != nil {
+		sendMessage(ctx, b, chatID, eventNameErrorText(title, err))
+		return
+	}
+	pe := &pendingEvent{
+		Name:          name,
+		Title:         title,
+		Description:   in.Description,
+		ChatID:        chatID,
+		UserID:        userID,
+		Hour:          -1,
+		Recurrence:    recurrenceRule,
+		RecurrenceSet: recurrenceSet,
+	}
 
-	now := time.Now()
-	sendCalendar(ctx, b, chatID, name, now.Year(), now.Month())
+	// Дата указана в ответе — показываем, как она понята, вместо календаря
+	if !in.At.IsZero() {
+		pe.At = in.At
+		pe.MessageID = sendDateConfirmation(ctx, b, chatID, pe)
+		setPending(ctx, chatID, userID, pe)
+		return
+	}
+
+	now := time.Now().In(loc)
+	pe.MessageID = sendCalendar(ctx, b, chatID, title, now.Year(), now.Month())
+	setPending(ctx, chatID, userID, pe)
 }
 
 func handleList(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
@@ -331,30 +631,21 @@ func handleList(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 	}
 
 	chatID := update.Message.Chat.ID
-	events, err := store.ListEvents(ctx, chatID)
+	events, err := listChatEvents(ctx, chatID)
 	if err != nil {
 		sendMessage(ctx, b, chatID, "Ошибка при получении событий")
 		return
 	}
 
-	// Добавляем события из тестового чата, если мы не в нём
-	cfg := config.GetConfig()
-	testChatID := int64(cfg.TestChatID)
-	if chatID != testChatID {
-		testEvents, err := store.ListEvents(ctx, testChatID)
-		if err == nil {
-			events = append(events, testEvents...)
-		}
-	}
-
 	if len(events) == 0 {
 		sendMessage(ctx, b, chatID, "Нет событий")
 		return
 	}
 
+	loc := chatLocation(ctx, chatID)
 	msg := "События:\n"
 	for _, e := range events {
-		msg += fmt.Sprintf("- %s: %s (/%s)\n", e.Name, e.Date, e.Name)
+		msg += e.line(loc)
 	}
 	sendMessage(ctx, b, chatID, msg)
 }
@@ -368,24 +659,21 @@ func handleActive(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 	}
 
 	chatID := update.Message.Chat.ID
-	events, err := store.ListEvents(ctx, chatID)
+	events, err := listChatEvents(ctx, chatID)
 	if err != nil {
 		sendMessage(ctx, b, chatID, "Ошибка при получении событий")
 		return
 	}
 
-	cfg := config.GetConfig()
-	testChatID := int64(cfg.TestChatID)
-	if chatID != testChatID {
-		testEvents, err := store.ListEvents(ctx, testChatID)
-		if err == nil {
-			events = append(events, testEvents...)
-		}
-	}
-
-	var active []storage.Event
+	var active []listedEvent
+	// Статус вычисляется по дате: сохранённый обновляется планировщиком с задержкой до SCHEDULER_INTERVAL
+	now := time.Now()
 	for _, e := range events {
-		if e.Status == "active" {
+		status, err := eventStatus(e.Event, e.loc, now)
+		if err != nil {
+			status = e.Status
+		}
+		if status == storage.StatusActive {
 			active = append(active, e)
 		}
 	}
@@ -396,8 +684,9 @@ func handleActive(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 	}
 
 	msg := "Активные события:\n"
+	loc := chatLocation(ctx, chatID)
 	for _, e := range active {
-		msg += fmt.Sprintf("- %s: %s\n", e.Name, e.Date)
+		msg += e.line(loc)
 	}
 	sendMessage(ctx, b, chatID, msg)
 }
@@ -411,24 +700,21 @@ func handleOutdated(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 	}
 
 	chatID := update.Message.Chat.ID
-	events, err := store.ListEvents(ctx, chatID)
+	events, err := listChatEvents(ctx, chatID)
 	if err != nil {
 		sendMessage(ctx, b, chatID, "Ошибка при получении событий")
 		return
 	}
 
-	cfg := config.GetConfig()
-	testChatID := int64(cfg.TestChatID)
-	if chatID != testChatID {
-		testEvents, err := store.ListEvents(ctx, testChatID)
-		if err == nil {
-			events = append(events, testEvents...)
-		}
-	}
-
-	var outdated []storage.Event
+	var outdated []listedEvent
+	// Статус вычисляется по дате: сохранённый обновляется планировщиком с задержкой до SCHEDULER_INTERVAL
+	now := time.Now()
 	for _, e := range events {
-		if e.Status == "outdated" {
+		status, err := eventStatus(e.Event, e.loc, now)
+		if err != nil {
+			status = e.Status
+		}
+		if status == storage.StatusOutdated {
 			outdated = append(outdated, e)
 		}
 	}
@@ -439,8 +725,9 @@ func handleOutdated(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 	}
 
 	msg := "Устаревшие события:\n"
+	loc := chatLocation(ctx, chatID)
 	for _, e := range outdated {
-		msg += fmt.Sprintf("- %s: %s\n", e.Name, e.Date)
+		msg += e.line(loc)
 	}
 	sendMessage(ctx, b, chatID, msg)
 }
@@ -455,11 +742,25 @@ func handleHelp(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 
 	helpText := `Команды:
 /set_date event_name [description] — добавить событие (📅 календарь)
+/set_date "Название события" [description] — название из нескольких слов, команда получится из него
 /set_date YYYY-MM-DD event_name [description] — добавить событие напрямую
 /set_date YYYY-MM-DD HH:MM event_name [description] — с указанием времени
+/set_date завтра в 18:00 event_name — дата словами, бот покажет её перед сохранением
+/set_date ... repeat=yearly — повторяющееся событие (monthly, weekly, daily, 3d, FREQ=...)
 /list — список всех событий
 /active — активные события
 /outdated — устаревшие события
+/remind event_name [3d 2h 15m] — напоминания о событии
+/repeat event_name yearly|off — повторение события
+/timezone [Europe/Moscow] — часовой пояс чата
+/digest daily 09:00 | weekly mon 09:00 | off — дайджест предстоящих событий
+/edit event_name — изменить дату, описание или название события
+/delete event_name — удалить событие
+/pin event_name — закрепить обновляемый отсчёт до события
+/unpin event_name — остановить и открепить отсчёт
+/publish [off] — опубликовать события чата как подборку (код для других чатов)
+/subscribe [код] — подписаться на подборку, её события появятся в списках с пометкой 🔗
+/unsubscribe код — отписаться от подборки
 /help — справка
 /<event_name> — информация о событии`
 	sendMessage(ctx, b, update.Message.Chat.ID, helpText)
@@ -478,12 +779,21 @@ func handleDynamicOrUnknown(ctx context.Context, b *bot.Bot, update *tgmodels.Up
 		command = command[:idx]
 	}
 
-	// Пропускаем системные команды
-	systemCommands := []string{"set_date", "list", "all", "active", "outdated", "help", "start"}
-	for _, sc := range systemCommands {
-		if command == sc {
-			return
+	// /cancel прерывает ожидание ввода (название события, новое описание или название)
+	if command == "cancel" && update.Message.From != nil {
+		chatID, userID := update.Message.Chat.ID, update.Message.From.ID
+		_, editing := getAwaitingEdit(ctx, chatID, userID)
+		if isAwaitingName(ctx, chatID, userID) || editing {
+			clearAwaitingName(ctx, chatID, userID)
+			clearAwaitingEdit(ctx, chatID, userID)
+			sendMessage(ctx, b, chatID, "❌ Ввод отменён.")
 		}
+		return
+	}
+
+	// Пропускаем системные команды
+	if eventname.IsReserved(command) {
+		return
 	}
 
 	logger.Debugf("Динамическая команда: %s", command)
@@ -496,11 +806,10 @@ func handleDynamicCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Upda
 	}
 	chatID := update.Message.Chat.ID
 
-	// Сначала ищем в текущем чате
-	event, err := store.GetEvent(ctx, chatID, name)
-	if err != nil {
-		// Ищем во всех остальных чатах
-		event, _, err = store.FindEventAcrossChats(ctx, name, chatID)
+	// Сначала ищем в текущем чате, затем в подборках, на которые он подписан
+	event, err := findChatEvent(ctx, chatID, name)
+	if errors.Is(err, storage.ErrNotFound) {
+		event, err = findSubscribedEvent(ctx, chatID, name)
 	}
 
 	if err != nil {
@@ -509,28 +818,35 @@ func handleDynamicCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Upda
 		return
 	}
 
-	// Автообновление статуса: если дата прошла — помечаем outdated
-	parsedDate, err := parseEventDate(event.Date)
+	// Дата события хранится во времени его чата, а показывается во времени текущего чата.
+	// Для повторяющегося события отсчёт идёт до ближайшего вхождения.
+	now := time.Now()
+	// Статус наступившего события обновляет планировщик (sweepStatuses)
+	parsedDate, _, err := eventOccurrence(*event, chatLocation(ctx, event.ChatID), now)
 	if err != nil {
 		logger.Errorf("Ошибка парсинга даты события '%s': %v", name, err)
 		sendMessage(ctx, b, chatID, "Ошибка при расчете времени")
 		return
 	}
 
-	if time.Now().After(parsedDate) && event.Status != "outdated" {
-		_ = store.UpdateEventStatus(ctx, event.ChatID, name, "outdated")
-		event.Status = "outdated"
-	}
-
-	duration := time.Until(parsedDate)
+	duration := parsedDate.Sub(now)
 	days := int(duration.Hours() / 24)
 	hours := int(duration.Hours()) % 24
 	minutes := int(duration.Minutes()) % 60
 
-	msg := fmt.Sprintf("Событие: %s\nДата: %s\n", event.Name, event.Date)
+	loc := chatLocation(ctx, chatID)
+	msg := fmt.Sprintf("Событие: %s\nДата: %s\n", event.DisplayTitle(), formatEventTime(parsedDate, loc))
+	if event.Recurrence != "" {
+		msg += fmt.Sprintf("Повторяется: %s\n", describeRecurrence(event.Recurrence))
+	}
 	if event.Description != "" {
 		msg += fmt.Sprintf("Описание: %s\n", event.Description)
 	}
+	if event.ChatID != chatID {
+		if c, err := store.GetCollection(ctx, event.ChatID); err == nil {
+			msg += fmt.Sprintf("🔗 Из подборки %s\n", collectionTitle(*c))
+		}
+	}
 	if duration > 0 {
 		msg += fmt.Sprintf("Осталось: %d дней, %d часов, %d минут", days, hours, minutes)
 	} else {
@@ -546,20 +862,12 @@ func loadExistingCommands(b *bot.Bot) {
 	// Удаляем старые команды, чтобы Telegram точно обновил меню
 	b.DeleteMyCommands(context.Background(), &bot.DeleteMyCommandsParams{})
 
-	commands := []tgmodels.BotCommand{
-		{Command: "set_date", Description: "📅 Добавить событие (календарь или дата)"},
-		{Command: "list", Description: "📋 Список всех событий"},
-		{Command: "active", Description: "✅ Активные события"},
-		{Command: "outdated", Description: "⏰ Устаревшие события"},
-		{Command: "help", Description: "❓ Справка по командам"},
-	}
-
 	_, err := b.SetMyCommands(context.Background(), &bot.SetMyCommandsParams{
-		Commands: commands,
+		Commands: staticCommands,
 	})
 	if err != nil {
 		logger.Errorf("Ошибка при установке команд: %v", err)
 	} else {
-		logger.Infof("Команды установлены (%d)", len(commands))
+		logger.Infof("Команды установлены (%d)", len(staticCommands))
 	}
 }
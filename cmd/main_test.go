@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetDateCreatesEvent(t *testing.T) {
+	const (
+		chatID = -400
+		userID = 1
+	)
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+
+	handleSetDate(ctx, b, textMessage(chatID, userID, `/set_date 2099-03-08 10:00 "День рождения" торт`))
+	expectContains(t, "create", ft.lastSent(chatID), "добавлено", "/den_rozhdeniya")
+
+	e, err := store.GetEvent(ctx, chatID, "den_rozhdeniya")
+	if err != nil {
+		t.Fatalf("event not stored: %v", err)
+	}
+	if e.Title != "День рождения" || e.Description != "торт" {
+		t.Errorf("stored event = %+v", e)
+	}
+	if want := time.Date(2099, 3, 8, 10, 0, 0, 0, time.UTC); !e.Date.Equal(want) {
+		t.Errorf("date = %v, want %v", e.Date, want)
+	}
+	if owner, _ := store.IsEventOwner(ctx, e.ID, userID); !owner {
+		t.Error("creator is not recorded as the event owner")
+	}
+}
+
+func TestSetDateRejectsDuplicate(t *testing.T) {
+	const chatID = -400
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+
+	handleSetDate(ctx, b, textMessage(chatID, 1, "/set_date 2099-03-08 party"))
+	handleSetDate(ctx, b, textMessage(chatID, 2, "/set_date 2099-04-01 party другое"))
+	expectContains(t, "duplicate", ft.lastSent(chatID), "уже существует")
+
+	e, err := store.GetEvent(ctx, chatID, "party")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Date.Month() != time.March || e.Description != "" {
+		t.Errorf("duplicate overwrote the event: %+v", e)
+	}
+
+	// То же имя в другом чате — другое событие
+	handleSetDate(ctx, b, textMessage(chatID-1, 1, "/set_date 2099-04-01 party"))
+	if _, err := store.GetEvent(ctx, chatID-1, "party"); err != nil {
+		t.Errorf("same name in another chat: %v", err)
+	}
+}
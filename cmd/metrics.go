@@ -1,11 +1,11 @@
 package main
 
 import (
+	"github.com/TheReshkin/timer-bot/internal/storage"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-// ДОработать, пока тут нет полезных метрик
 const (
 	ComponentName = "timer_bot"
 )
@@ -19,4 +19,158 @@ var (
 		},
 		[]string{"command"},
 	)
+
+	// SessionsActive — число незавершённых диалогов (календарь, ожидание ввода) по видам
+	SessionsActive = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sessions_active",
+			Help: "Number of unfinished conversation sessions by kind",
+		},
+		[]string{"kind"},
+	)
+
+	// SessionsExpiredCounter counts sessions abandoned until their TTL ran out
+	SessionsExpiredCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sessions_expired_total",
+			Help: "Total number of conversation sessions that expired unfinished",
+		},
+		[]string{"kind"},
+	)
+
+	// SessionLifetime — время от начала до истечения брошенного диалога
+	SessionLifetime = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "session_lifetime_seconds",
+			Help:    "Lifetime of expired conversation sessions from creation to expiry",
+			Buckets: []float64{60, 300, 900, 1800, 3600, 3 * 3600, 6 * 3600, 24 * 3600},
+		},
+		[]string{"kind"},
+	)
+
+	// EventStatusTransitions counts event status changes (active → outdated and back)
+	EventStatusTransitions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "event_status_transitions_total",
+			Help: "Total number of event status transitions",
+		},
+		[]string{"from", "to"},
+	)
+
+	// HandlerRequests counts handled updates by handler (command, callback prefix, text)
+	HandlerRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "handler_requests_total",
+			Help: "Total number of updates handled, by handler",
+		},
+		[]string{"handler"},
+	)
+
+	// HandlerDuration — время обработки обновления
+	HandlerDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "handler_duration_seconds",
+			Help:    "Time spent handling an update, by handler",
+			Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		},
+		[]string{"handler"},
+	)
+
+	// CallbackActions counts inline keyboard presses by callback prefix and action
+	CallbackActions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "callback_actions_total",
+			Help: "Total number of callback query actions, by prefix and action",
+		},
+		[]string{"prefix", "action"},
+	)
+
+	// TelegramAPIDuration — время запросов к Telegram Bot API (кроме long polling getUpdates)
+	TelegramAPIDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "telegram_api_duration_seconds",
+			Help:    "Telegram Bot API request latency, by method (getUpdates excluded)",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+		},
+		[]string{"method"},
+	)
+
+	// TelegramAPIErrors counts failed Telegram Bot API requests by HTTP status ("network" if there was no response)
+	TelegramAPIErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "telegram_api_errors_total",
+			Help: "Total number of failed Telegram Bot API requests, by method and status code",
+		},
+		[]string{"method", "code"},
+	)
+
+	// DBQueryDuration — время вызовов хранилища по методам
+	DBQueryDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Storage call latency, by store method",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		},
+		[]string{"op"},
+	)
+
+	// DBErrors counts failed storage calls (storage.ErrNotFound is not an error here)
+	DBErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_errors_total",
+			Help: "Total number of failed storage calls, by store method",
+		},
+		[]string{"op"},
+	)
+
+	// EventsActive — число активных событий по итогам последнего прохода планировщика
+	EventsActive = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "events_active",
+			Help: "Number of active events as of the last scheduler pass",
+		},
+	)
 )
+
+// poolCollector отдаёт состояние пула соединений хранилища в момент запроса метрик.
+type poolCollector struct {
+	pool storage.PoolStatter
+
+	maxConns     *prometheus.Desc
+	conns        *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+}
+
+// registerPoolMetrics регистрирует метрики пула соединений, если он есть у хранилища.
+func registerPoolMetrics(s storage.Store) {
+	if pool, ok := s.(storage.PoolStatter); ok {
+		prometheus.MustRegister(newPoolCollector(pool))
+	}
+}
+
+func newPoolCollector(pool storage.PoolStatter) *poolCollector {
+	return &poolCollector{
+		pool:         pool,
+		maxConns:     prometheus.NewDesc("db_pool_max_connections", "Maximum number of open connections in the pool", nil, nil),
+		conns:        prometheus.NewDesc("db_pool_connections", "Open pool connections, by state", []string{"state"}, nil),
+		waitCount:    prometheus.NewDesc("db_pool_wait_total", "Total number of acquires that waited for a free connection", nil, nil),
+		waitDuration: prometheus.NewDesc("db_pool_wait_seconds_total", "Total time spent waiting for a free connection", nil, nil),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxConns
+	ch <- c.conns
+	ch <- c.waitCount
+	ch <- c.waitDuration
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	st := c.pool.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(st.MaxConns))
+	ch <- prometheus.MustNewConstMetric(c.conns, prometheus.GaugeValue, float64(st.InUseConns), "in_use")
+	ch <- prometheus.MustNewConstMetric(c.conns, prometheus.GaugeValue, float64(st.IdleConns), "idle")
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(st.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, st.WaitDuration.Seconds())
+}
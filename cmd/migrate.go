@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/config"
+	"github.com/TheReshkin/timer-bot/internal/storage"
+)
+
+// runMigrateCommand выполняет подкоманду "migrate" и возвращает код выхода:
+//
+//	murmansk-bot migrate status — список миграций и их состояние
+//	murmansk-bot migrate up     — применить неприменённые миграции
+func runMigrateCommand(cfg *config.Config, args []string) int {
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	if action != "status" && action != "up" {
+		fmt.Fprintln(os.Stderr, "usage: murmansk-bot migrate [status|up]")
+		return 2
+	}
+
+	if cfg.DatabaseURL == "" {
+		fmt.Fprintln(os.Stderr, "DATABASE_URL не задан")
+		return 1
+	}
+	if storage.Backend(cfg.DatabaseURL) == storage.BackendMemory {
+		fmt.Println("хранилище в памяти не использует миграции")
+		return 0
+	}
+	s, err := storage.OpenMigrator(cfg.DatabaseURL, cfg.Location)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if action == "up" {
+		applied, err := s.Migrate(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migration failed: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return 0
+	}
+
+	statuses, err := s.MigrationStatus(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	pending := 0
+	for _, st := range statuses {
+		applied := "pending"
+		if st.AppliedAt != nil {
+			applied = st.AppliedAt.In(cfg.Location).Format("2006-01-02 15:04:05")
+		} else {
+			pending++
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", st.Version, st.Name, applied)
+	}
+	w.Flush()
+	fmt.Printf("%d pending\n", pending)
+	return 0
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+const (
+	// pinUpdateInterval — как часто фоновая задача ищет закреплённые сообщения, которые пора обновить.
+	pinUpdateInterval = 10 * time.Second
+	// pinLease — на сколько откладывается обновление, полученное репликой: если она не успеет
+	// обновить сообщение (ошибка Telegram, остановка), его подхватит следующий проход.
+	pinLease = time.Minute
+)
+
+// countdownStep возвращает оставшееся время, округлённое вниз до точности отсчёта, и точность.
+// Чем ближе событие, тем чаще меняется текст: по дням, пока до события больше двух суток,
+// затем по часам, а в последний час — каждую минуту.
+func countdownStep(remaining time.Duration) (shown, step time.Duration) {
+	switch {
+	case remaining >= 48*time.Hour:
+		step = 24 * time.Hour
+	case remaining >= time.Hour:
+		step = time.Hour
+	default:
+		step = time.Minute
+	}
+	return remaining.Truncate(step), step
+}
+
+// pinCountdown формирует текст закреплённого отсчёта и момент, когда текст изменится:
+// в этот момент сообщение нужно обновить.
+func pinCountdown(e storage.Event, occurrence time.Time, loc *time.Location, now time.Time) (string, time.Time) {
+	shown, _ := countdownStep(occurrence.Sub(now))
+	left := "меньше минуты"
+	next := occurrence
+	if shown > 0 {
+		left = formatOffset(shown)
+		// Секунда запаса: в момент occurrence-shown округлённое значение ещё не изменилось
+		next = occurrence.Add(-shown + time.Second)
+	}
+
+	text := fmt.Sprintf("⏳ <b>%s</b>\nДо события осталось: %s\nДата: %s",
+		html.EscapeString(e.DisplayTitle()), left, formatEventTime(occurrence, loc))
+	if e.Recurrence != "" {
+		text += fmt.Sprintf(" 🔁 %s", describeRecurrence(e.Recurrence))
+	}
+	return text + fmt.Sprintf("\n/%s", e.Name), next
+}
+
+// pinFinalText — последнее обновление закреплённого сообщения в момент наступления события.
+func pinFinalText(e storage.Event, occurrence time.Time, loc *time.Location) string {
+	text := fmt.Sprintf("🎉🎉🎉 Событие <b>%s</b> наступило!\nДата: %s",
+		html.EscapeString(e.DisplayTitle()), formatEventTime(occurrence, loc))
+	if e.Description != "" {
+		text += fmt.Sprintf("\n%s", html.EscapeString(e.Description))
+	}
+	return text
+}
+
+// isMessageGone сообщает, что закреплённое сообщение больше нельзя обновлять:
+// его удалили, бота исключили из чата или чат перестал существовать.
+func isMessageGone(err error) bool {
+	if errors.Is(err, bot.ErrorForbidden) {
+		return true
+	}
+	if !errors.Is(err, bot.ErrorBadRequest) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "message to edit not found") ||
+		strings.Contains(msg, "message_id_invalid") ||
+		strings.Contains(msg, "chat not found")
+}
+
+// isNotModified сообщает, что текст сообщения не изменился — обновление не требуется.
+func isNotModified(err error) bool {
+	return errors.Is(err, bot.ErrorBadRequest) && strings.Contains(err.Error(), "message is not modified")
+}
+
+// ──────────────────────────── /pin, /unpin ────────────────────────────
+
+// handlePin отправляет и закрепляет сообщение с обратным отсчётом до события: /pin <event_name>.
+// Повторный /pin заменяет прежнее сообщение события.
+func handlePin(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil {
+		return
+	}
+	parts := splitArgs(normalizeCommand(update.Message.Text))
+	if len(parts) == 0 || parts[0] != "/pin" {
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	if len(parts) != 2 {
+		sendMessage(ctx, b, chatID, "Используйте формат: /pin event_name")
+		return
+	}
+
+	name := unquoteTitle(parts[1])
+	event, err := findChatEvent(ctx, chatID, name)
+	if err != nil {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Событие '%s' не найдено", name))
+		return
+	}
+	if !canManageFromMessage(ctx, b, update.Message, event) {
+		sendMessage(ctx, b, chatID, manageDeniedText)
+		return
+	}
+
+	loc := chatLocation(ctx, chatID)
+	now := time.Now()
+	occurrence, upcoming, err := eventOccurrence(*event, loc, now)
+	if err != nil {
+		logger.Errorf("Ошибка расчёта даты события '%s': %v", event.Name, err)
+		sendMessage(ctx, b, chatID, "Ошибка при расчете времени")
+		return
+	}
+	if !upcoming {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Событие '%s' уже прошло — отсчитывать нечего", event.DisplayTitle()))
+		return
+	}
+
+	// Прежнее сообщение события больше не обновляется — удаляем его, чтобы не висели два отсчёта
+	if old, err := store.GetPin(ctx, event.ID); err == nil {
+		b.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: old.ChatID, MessageID: old.MessageID})
+	}
+
+	text, next := pinCountdown(*event, occurrence, loc, now)
+	msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+	if err != nil {
+		logger.Errorf("Ошибка отправки отсчёта chat_id=%d: %v", chatID, err)
+		return
+	}
+
+	if err := store.SetPin(ctx, &storage.Pin{EventID: event.ID, ChatID: chatID, MessageID: msg.ID, NextUpdateAt: next}); err != nil {
+		logger.Errorf("Ошибка сохранения отсчёта события %d: %v", event.ID, err)
+		sendMessage(ctx, b, chatID, "Ошибка при закреплении отсчёта")
+		return
+	}
+
+	_, err = b.PinChatMessage(ctx, &bot.PinChatMessageParams{
+		ChatID:              chatID,
+		MessageID:           msg.ID,
+		DisableNotification: true,
+	})
+	if err != nil {
+		// Без права закреплять сообщение всё равно обновляется — его можно закрепить вручную
+		logger.Warnf("Не удалось закрепить отсчёт chat_id=%d: %v", chatID, err)
+		sendMessage(ctx, b, chatID, "⚠️ Не удалось закрепить сообщение: дайте боту право закреплять сообщения. Отсчёт всё равно будет обновляться.")
+	}
+	logger.Infof("Отсчёт закреплён: %s (chat_id=%d, message_id=%d)", event.Name, chatID, msg.ID)
+}
+
+// handleUnpin останавливает обновление отсчёта события и открепляет его: /unpin <event_name>.
+func handleUnpin(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil {
+		return
+	}
+	parts := splitArgs(normalizeCommand(update.Message.Text))
+	if len(parts) == 0 || parts[0] != "/unpin" {
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	if len(parts) != 2 {
+		sendMessage(ctx, b, chatID, "Используйте формат: /unpin event_name")
+		return
+	}
+
+	name := unquoteTitle(parts[1])
+	event, err := findChatEvent(ctx, chatID, name)
+	if err != nil {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Событие '%s' не найдено", name))
+		return
+	}
+	if !canManageFromMessage(ctx, b, update.Message, event) {
+		sendMessage(ctx, b, chatID, manageDeniedText)
+		return
+	}
+
+	pin, err := store.GetPin(ctx, event.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("У события '%s' нет закреплённого отсчёта", event.DisplayTitle()))
+		return
+	}
+	if err != nil {
+		logger.Errorf("Ошибка получения отсчёта события %d: %v", event.ID, err)
+		sendMessage(ctx, b, chatID, "Ошибка при откреплении отсчёта")
+		return
+	}
+
+	stopPin(ctx, b, pin, fmt.Sprintf("⏹ Отсчёт до события <b>%s</b> остановлен", html.EscapeString(event.DisplayTitle())))
+	sendMessage(ctx, b, chatID, fmt.Sprintf("✅ Отсчёт до события '%s' откреплён", event.DisplayTitle()))
+}
+
+// stopPin заменяет текст закреплённого сообщения, открепляет его и прекращает обновление.
+// Ошибки Telegram (сообщение уже удалено или откреплено вручную) не мешают остановке.
+func stopPin(ctx context.Context, b *bot.Bot, pin *storage.Pin, text string) {
+	b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    pin.ChatID,
+		MessageID: pin.MessageID,
+		Text:      text,
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+	b.UnpinChatMessage(ctx, &bot.UnpinChatMessageParams{ChatID: pin.ChatID, MessageID: pin.MessageID})
+	if err := store.DeletePin(ctx, pin.EventID); err != nil {
+		logger.Errorf("Ошибка удаления отсчёта события %d: %v", pin.EventID, err)
+	}
+}
+
+// refreshPin обновляет закреплённый отсчёт события при ближайшем проходе фоновой задачи —
+// после изменения даты, названия или повторения. Если отсчёта нет, ничего не делает.
+func refreshPin(ctx context.Context, eventID int64) {
+	if err := store.ReschedulePin(ctx, eventID, time.Now()); err != nil {
+		logger.Errorf("Ошибка обновления отсчёта события %d: %v", eventID, err)
+	}
+}
+
+// ──────────────────────────── фоновое обновление ────────────────────────────
+
+// runPinUpdater обновляет закреплённые отсчёты, когда меняется их текст, до отмены ctx.
+func runPinUpdater(ctx context.Context, b *bot.Bot) {
+	log := WithComponent("pins")
+	ticker := time.NewTicker(pinUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		pins, err := store.ClaimDuePins(ctx, time.Now(), pinLease)
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("Ошибка выборки закреплённых отсчётов: %v", err)
+		}
+		for _, p := range pins {
+			if ctx.Err() != nil {
+				return
+			}
+			updatePin(ctx, b, p)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// updatePin обновляет одно закреплённое сообщение: новый остаток времени или, если событие
+// наступило, финальный текст. Удалённое сообщение больше не обновляется.
+func updatePin(ctx context.Context, b *bot.Bot, p storage.Pin) {
+	log := WithComponent("pins").WithField("event_id", p.EventID).WithField("chat_id", p.ChatID)
+
+	event, err := store.GetEventByID(ctx, p.EventID)
+	if errors.Is(err, storage.ErrNotFound) {
+		store.DeletePin(ctx, p.EventID)
+		return
+	}
+	if err != nil {
+		log.Errorf("Ошибка получения события: %v", err)
+		return
+	}
+
+	// Только что наступившее вхождение ещё учитывается, чтобы повторяющееся событие
+	// тоже получило финальное сообщение, а не сразу отсчёт до следующего раза
+	loc := chatLocation(ctx, p.ChatID)
+	now := time.Now()
+	occurrence, _, err := eventOccurrence(*event, loc, now.Add(-missedReminderWindow))
+	if err != nil {
+		log.Warnf("Не удалось вычислить дату события '%s': %v", event.Name, err)
+		return
+	}
+
+	final := !occurrence.After(now)
+	var (
+		text string
+		next time.Time
+	)
+	if final {
+		text = pinFinalText(*event, occurrence, loc)
+	} else {
+		text, next = pinCountdown(*event, occurrence, loc, now)
+	}
+
+	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    p.ChatID,
+		MessageID: p.MessageID,
+		Text:      text,
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+	switch {
+	case err == nil, isNotModified(err):
+	case isMessageGone(err):
+		log.Infof("Закреплённый отсчёт события '%s' удалён из чата, обновление остановлено: %v", event.Name, err)
+		store.DeletePin(ctx, p.EventID)
+		return
+	default:
+		// Повторим после pinLease
+		log.Warnf("Ошибка обновления отсчёта события '%s': %v", event.Name, err)
+		return
+	}
+
+	if final {
+		log.Infof("Отсчёт события '%s' завершён", event.Name)
+		if err := store.DeletePin(ctx, p.EventID); err != nil {
+			log.Errorf("Ошибка удаления отсчёта: %v", err)
+		}
+		return
+	}
+	if err := store.ReschedulePin(ctx, p.EventID, next); err != nil {
+		log.Errorf("Ошибка планирования отсчёта: %v", err)
+	}
+}
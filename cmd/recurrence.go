@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/recurrence"
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+// eventOccurrence возвращает дату, до которой идёт отсчёт: для однократного события — его дату,
+// для повторяющегося — ближайшее вхождение не раньше after. loc — часовой пояс чата события.
+// upcoming == false, если событие (или все вхождения правила) уже в прошлом.
+func eventOccurrence(e storage.Event, loc *time.Location, after time.Time) (time.Time, bool, error) {
+	// Вхождения считаются по «настенному» времени чата, чтобы переход на летнее время не сдвигал событие
+	date := e.Date.In(loc)
+	if e.Recurrence == "" {
+		return date, !date.Before(after), nil
+	}
+
+	rule, err := recurrence.Parse(e.Recurrence)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("правило повторения события '%s': %w", e.Name, err)
+	}
+	next, ok := rule.Next(date, after)
+	if next.IsZero() {
+		return date, false, nil
+	}
+	return next, ok, nil
+}
+
+// displayDate возвращает дату события для списков: для повторяющихся событий —
+// ближайшее вхождение с пометкой о правиле повторения.
+func displayDate(e storage.Event, loc *time.Location) string {
+	return displayDateIn(e, loc, loc)
+}
+
+// displayDateIn — displayDate для события другого чата: вхождения считаются в часовом поясе
+// чата события eventLoc, а дата показывается в часовом поясе текущего чата loc.
+func displayDateIn(e storage.Event, eventLoc, loc *time.Location) string {
+	next, _, err := eventOccurrence(e, eventLoc, time.Now())
+	if err != nil {
+		return formatEventTime(e.Date, loc)
+	}
+	if e.Recurrence == "" {
+		return formatEventTime(next, loc)
+	}
+	return fmt.Sprintf("%s 🔁 %s", formatEventTime(next, loc), describeRecurrence(e.Recurrence))
+}
+
+// normalizeRecurrence разбирает правило, введённое пользователем, и возвращает каноническую
+// запись RRULE. "once", "none", "off" и "нет" означают однократное событие (пустая строка).
+func normalizeRecurrence(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "once", "none", "off", "нет", "однократно":
+		return "", nil
+	}
+	rule, err := recurrence.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("не удалось разобрать правило повторения %q: %w", s, err)
+	}
+	return rule.String(), nil
+}
+
+// extractRepeatOption ищет среди аргументов команды параметр repeat=<правило>
+// и возвращает остальные аргументы. set == false, если параметр не указан.
+func extractRepeatOption(parts []string) (rest []string, rule string, set bool, err error) {
+	for _, p := range parts {
+		value, found := strings.CutPrefix(p, "repeat=")
+		if !found {
+			rest = append(rest, p)
+			continue
+		}
+		rule, err = normalizeRecurrence(value)
+		if err != nil {
+			return nil, "", false, err
+		}
+		set = true
+	}
+	return rest, rule, set, nil
+}
+
+var russianWeekdays = map[time.Weekday]string{
+	time.Monday: "пн", time.Tuesday: "вт", time.Wednesday: "ср", time.Thursday: "чт",
+	time.Friday: "пт", time.Saturday: "сб", time.Sunday: "вс",
+}
+
+// describeRecurrence выводит правило повторения по-русски: "каждый год", "каждые 2 недели (пн, ср)".
+func describeRecurrence(rrule string) string {
+	if rrule == "" {
+		return ""
+	}
+	rule, err := recurrence.Parse(rrule)
+	if err != nil {
+		return rrule
+	}
+
+	var text string
+	n := rule.Interval
+	switch rule.Freq {
+	case recurrence.Daily:
+		text = everyRu(n, "каждый день", "день", "дня", "дней")
+	case recurrence.Weekly:
+		text = everyRu(n, "каждую неделю", "неделю", "недели", "недель")
+	case recurrence.Monthly:
+		text = everyRu(n, "каждый месяц", "месяц", "месяца", "месяцев")
+	case recurrence.Yearly:
+		text = everyRu(n, "каждый год", "год", "года", "лет")
+	}
+
+	if len(rule.ByDay) > 0 {
+		days := make([]string, 0, len(rule.ByDay))
+		for _, wd := range rule.ByDay {
+			days = append(days, russianWeekdays[wd])
+		}
+		text += fmt.Sprintf(" (%s)", strings.Join(days, ", "))
+	}
+	if rule.Count > 0 {
+		text += fmt.Sprintf(", %d %s", rule.Count, pluralRu(rule.Count, "раз", "раза", "раз"))
+	}
+	if !rule.Until.IsZero() {
+		text += fmt.Sprintf(", до %s", rule.Until.Format("2006-01-02"))
+	}
+	return text
+}
+
+func everyRu(n int, single, one, few, many string) string {
+	if n <= 1 {
+		return single
+	}
+	every := "каждые"
+	if n%10 == 1 && n%100 != 11 {
+		every = "каждый"
+		if one == "неделю" {
+			every = "каждую"
+		}
+	}
+	return fmt.Sprintf("%s %d %s", every, n, pluralRu(n, one, few, many))
+}
+
+// ──────────────────────────── выбор повторения в календаре ────────────────────────────
+
+// recurrenceChoices — варианты повторения, предлагаемые после выбора даты и времени.
+var recurrenceChoices = []struct {
+	Key   string
+	Label string
+}{
+	{"once", "1️⃣ Однократно"},
+	{"yearly", "🎂 Каждый год"},
+	{"monthly", "📆 Каждый месяц"},
+	{"weekly", "🔁 Каждую неделю"},
+	{"daily", "☀️ Каждый день"},
+}
+
+func buildRecurrencePicker(dateStr string, hour, minute int) *tgmodels.InlineKeyboardMarkup {
+	rows := [][]tgmodels.InlineKeyboardButton{
+		{{Text: fmt.Sprintf("🔁 Повторение (%s %02d:%02d)", dateStr, hour, minute), CallbackData: "cal:ignore"}},
+	}
+	for _, c := range recurrenceChoices {
+		rows = append(rows, []tgmodels.InlineKeyboardButton{{
+			Text:         c.Label,
+			CallbackData: fmt.Sprintf("cal:rep:%s:%d:%d:%s", dateStr, hour, minute, c.Key),
+		}})
+	}
+	rows = append(rows, []tgmodels.InlineKeyboardButton{
+		{Text: "⬅ Назад к минутам", CallbackData: fmt.Sprintf("cal:back_to_min:%s:%d", dateStr, hour)},
+		{Text: "❌ Отмена", CallbackData: "cal:cancel"},
+	})
+	return &tgmodels.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+func editToRecurrencePicker(ctx context.Context, b *bot.Bot, chatID int64, messageID int, eventName, dateStr string, hour, minute int) {
+	kb := buildRecurrencePicker(dateStr, hour, minute)
+	b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        fmt.Sprintf("🔁 Как часто повторяется событие <b>%s</b> (%s %02d:%02d)?", html.EscapeString(eventName), dateStr, hour, minute),
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: kb,
+	})
+}
+
+// ──────────────────────────── /repeat ────────────────────────────
+
+// handleRepeat задаёт правило повторения существующему событию:
+//
+//	/repeat <event_name> yearly|monthly|weekly|daily|3d|2w|FREQ=...
+//	/repeat <event_name> off
+func handleRepeat(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil {
+		return
+	}
+	parts := splitArgs(normalizeCommand(update.Message.Text))
+	if len(parts) == 0 || parts[0] != "/repeat" {
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	if len(parts) != 3 {
+		sendMessage(ctx, b, chatID,
+			"Используйте формат:\n"+
+				"/repeat event_name yearly|monthly|weekly|daily — повторять событие\n"+
+				"/repeat event_name 3d — каждые 3 дня (2w — каждые 2 недели)\n"+
+				"/repeat event_name FREQ=WEEKLY;BYDAY=MO,WE — правило RRULE\n"+
+				"/repeat event_name off — однократное событие")
+		return
+	}
+
+	title := unquoteTitle(parts[1])
+	event, err := findChatEvent(ctx, chatID, title)
+	if err != nil {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Событие '%s' не найдено", title))
+		return
+	}
+	if !canManageFromMessage(ctx, b, update.Message, event) {
+		sendMessage(ctx, b, chatID, manageDeniedText)
+		return
+	}
+	name := event.Name
+
+	rule, err := normalizeRecurrence(parts[2])
+	if err != nil {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Ошибка: %s", err))
+		return
+	}
+	if err := store.UpdateEventRecurrence(ctx, chatID, name, rule); err != nil {
+		logger.Errorf("Ошибка обновления повторения события '%s': %v", name, err)
+		sendMessage(ctx, b, chatID, "Ошибка при обновлении события")
+		return
+	}
+	refreshPin(ctx, event.ID)
+
+	// Повторение может вернуть прошедшее событие в активные (и наоборот, если правило закончилось)
+	event.Recurrence = rule
+	if status, err := eventStatus(*event, chatLocation(ctx, chatID), time.Now()); err == nil {
+		if err := setEventStatus(ctx, event, status); err != nil {
+			logger.Errorf("Ошибка обновления статуса события '%s': %v", name, err)
+		}
+	}
+
+	if rule == "" {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Событие '%s' больше не повторяется", event.DisplayTitle()))
+		return
+	}
+	sendMessage(ctx, b, chatID, fmt.Sprintf("Событие '%s' повторяется %s", event.DisplayTitle(), describeRecurrence(rule)))
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRepeatRequiresManageRights(t *testing.T) {
+	const (
+		chatID   = -300
+		authorID = 1
+		otherID  = 2
+	)
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+	ownedEvent(t, chatID, authorID, "standup", time.Now().Add(24*time.Hour))
+
+	handleRepeat(ctx, b, textMessage(chatID, otherID, "/repeat standup daily"))
+	if got := ft.lastSent(chatID); got != manageDeniedText {
+		t.Errorf("stranger /repeat: got %q, want denial", got)
+	}
+	e, err := store.GetEvent(ctx, chatID, "standup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Recurrence != "" {
+		t.Fatalf("stranger set recurrence %q", e.Recurrence)
+	}
+
+	handleRepeat(ctx, b, textMessage(chatID, authorID, "/repeat standup daily"))
+	if e, _ = store.GetEvent(ctx, chatID, "standup"); e.Recurrence == "" {
+		t.Errorf("author /repeat: recurrence not set, reply %q", ft.lastSent(chatID))
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/config"
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+const (
+	// maxEventReminders — максимум собственных смещений у одного события
+	maxEventReminders = 10
+	// maxReminderOffset — напоминание можно поставить не раньше, чем за год до события
+	maxReminderOffset = 366 * 24 * time.Hour
+)
+
+// reminderPresets — кнопки быстрого добавления смещений в inline-меню /remind
+var reminderPresets = []time.Duration{
+	7 * 24 * time.Hour,
+	24 * time.Hour,
+	time.Hour,
+	15 * time.Minute,
+	0,
+}
+
+// parseOffset разбирает смещение напоминания: "3d", "2h", "15m", "1w", "1d12h".
+// "0", "now" и "start" означают напоминание в момент наступления события.
+func parseOffset(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "0", "now", "start":
+		return 0, nil
+	}
+
+	var total time.Duration
+	num := ""
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			num += string(r)
+		case num == "":
+			return 0, fmt.Errorf("некорректное смещение: %s", s)
+		default:
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return 0, fmt.Errorf("некорректное смещение: %s", s)
+			}
+			var unit time.Duration
+			switch r {
+			case 'w':
+				unit = 7 * 24 * time.Hour
+			case 'd':
+				unit = 24 * time.Hour
+			case 'h':
+				unit = time.Hour
+			case 'm':
+				unit = time.Minute
+			default:
+				return 0, fmt.Errorf("неизвестная единица '%c' в смещении %s (используйте w, d, h, m)", r, s)
+			}
+			// Проверяем до умножения и сложения, чтобы большое число не переполнило Duration
+			if int64(n) > int64(maxReminderOffset/unit) {
+				return 0, fmt.Errorf("смещение %s больше года", s)
+			}
+			total += time.Duration(n) * unit
+			if err := checkOffset(total); err != nil {
+				return 0, fmt.Errorf("смещение %s больше года", s)
+			}
+			num = ""
+		}
+	}
+	if num != "" || total == 0 {
+		return 0, fmt.Errorf("некорректное смещение: %s", s)
+	}
+	return total, nil
+}
+
+// checkOffset проверяет, что смещение напоминания не отрицательно и не больше maxReminderOffset.
+func checkOffset(d time.Duration) error {
+	if d < 0 || d > maxReminderOffset {
+		return fmt.Errorf("смещение %s вне допустимого диапазона", d)
+	}
+	return nil
+}
+
+// effectiveReminders возвращает смещения, по которым будут отправлены напоминания события,
+// и признак того, что это смещения по умолчанию.
+func effectiveReminders(ctx context.Context, eventID int64) ([]time.Duration, bool, error) {
+	offsets, err := store.ListEventReminders(ctx, eventID)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(offsets) > 0 {
+		return offsets, false, nil
+	}
+	return config.GetConfig().ReminderOffsets, true, nil
+}
+
+// formatReminder выводит смещение для пользователя: "за 2 дня" или "в момент наступления".
+func formatReminder(d time.Duration) string {
+	if d == 0 {
+		return "в момент наступления"
+	}
+	return "за " + formatOffset(d)
+}
+
+// ──────────────────────────── /remind ────────────────────────────
+
+// handleRemind управляет смещениями напоминаний события:
+//
+//	/remind <event_name>               — список напоминаний и inline-меню
+//	/remind <event_name> 3d 2h 15m     — добавить напоминания
+//	/remind <event_name> rm 3d [2h]    — удалить напоминания
+//	/remind <event_name> reset         — вернуть напоминания по умолчанию
+func handleRemind(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil {
+		return
+	}
+	command := normalizeCommand(update.Message.Text)
+	parts := splitArgs(command)
+	if len(parts) == 0 || parts[0] != "/remind" {
+		// Например, /reminder_party — это событие, а не команда /remind
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	if len(parts) < 2 {
+		sendMessage(ctx, b, chatID,
+			"Используйте формат:\n"+
+				"/remind event_name — список напоминаний\n"+
+				"/remind event_name 3d 2h 15m — добавить напоминания\n"+
+				"/remind event_name rm 3d — удалить напоминание\n"+
+				"/remind event_name reset — напоминания по умолчанию")
+		return
+	}
+
+	name := unquoteTitle(parts[1])
+	event, err := findChatEvent(ctx, chatID, name)
+	if err != nil {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Событие '%s' не найдено", name))
+		return
+	}
+
+	if !canManageFromMessage(ctx, b, update.Message, event) {
+		sendMessage(ctx, b, chatID, manageDeniedText)
+		return
+	}
+
+	args := parts[2:]
+	switch {
+	case len(args) == 0:
+		// только показываем меню
+
+	case args[0] == "reset":
+		if err := store.ClearEventReminders(ctx, event.ID); err != nil {
+			logger.Errorf("Ошибка сброса напоминаний события %d: %v", event.ID, err)
+			sendMessage(ctx, b, chatID, "Ошибка при сбросе напоминаний")
+			return
+		}
+
+	case args[0] == "rm" || args[0] == "del":
+		if len(args) < 2 {
+			sendMessage(ctx, b, chatID, "Укажите, какие напоминания удалить: /remind event_name rm 3d")
+			return
+		}
+		for _, a := range args[1:] {
+			off, err := parseOffset(a)
+			if err != nil {
+				sendMessage(ctx, b, chatID, fmt.Sprintf("Ошибка: %s", err))
+				return
+			}
+			removed, err := store.DeleteEventReminder(ctx, event.ID, off)
+			if err != nil {
+				logger.Errorf("Ошибка удаления напоминания события %d: %v", event.ID, err)
+				sendMessage(ctx, b, chatID, "Ошибка при удалении напоминания")
+				return
+			}
+			if !removed {
+				sendMessage(ctx, b, chatID, fmt.Sprintf("Напоминания %s нет", formatReminder(off)))
+			}
+		}
+
+	default:
+		offsets := make([]time.Duration, 0, len(args))
+		for _, a := range args {
+			off, err := parseOffset(a)
+			if err != nil {
+				sendMessage(ctx, b, chatID, fmt.Sprintf("Ошибка: %s", err))
+				return
+			}
+			offsets = append(offsets, off)
+		}
+		if msg := addEventReminders(ctx, event.ID, offsets); msg != "" {
+			sendMessage(ctx, b, chatID, msg)
+			return
+		}
+	}
+
+	text, kb, err := buildRemindMenu(ctx, event)
+	if err != nil {
+		logger.Errorf("Ошибка получения напоминаний события %d: %v", event.ID, err)
+		sendMessage(ctx, b, chatID, "Ошибка при получении напоминаний")
+		return
+	}
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        text,
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: kb,
+	})
+	if err != nil {
+		logger.Errorf("Ошибка отправки меню напоминаний chat_id=%d: %v", chatID, err)
+	}
+}
+
+// addEventReminders добавляет смещения с проверкой лимита.
+// Возвращает текст ошибки для пользователя или пустую строку при успехе.
+func addEventReminders(ctx context.Context, eventID int64, offsets []time.Duration) string {
+	existing, err := store.ListEventReminders(ctx, eventID)
+	if err != nil {
+		logger.Errorf("Ошибка получения напоминаний события %d: %v", eventID, err)
+		return "Ошибка при добавлении напоминания"
+	}
+	known := make(map[time.Duration]bool, len(existing))
+	for _, off := range existing {
+		known[off] = true
+	}
+	for _, off := range offsets {
+		known[off] = true
+	}
+	if len(known) > maxEventReminders {
+		return fmt.Sprintf("У события может быть не больше %d напоминаний", maxEventReminders)
+	}
+
+	for _, off := range offsets {
+		if err := store.AddEventReminder(ctx, eventID, off); err != nil {
+			logger.Errorf("Ошибка добавления напоминания события %d: %v", eventID, err)
+			return "Ошибка при добавлении напоминания"
+		}
+	}
+	return ""
+}
+
+// buildRemindMenu формирует текст и inline-клавиатуру со списком напоминаний события.
+func buildRemindMenu(ctx context.Context, event *storage.Event) (string, *tgmodels.InlineKeyboardMarkup, error) {
+	offsets, isDefault, err := effectiveReminders(ctx, event.ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	text := fmt.Sprintf("⏰ Напоминания события <b>%s</b>", html.EscapeString(event.DisplayTitle()))
+	if isDefault {
+		text += " (по умолчанию)"
+	}
+	text += ":\n"
+	if len(offsets) == 0 {
+		text += "— нет\n"
+	}
+	for _, off := range offsets {
+		text += fmt.Sprintf("• %s\n", formatReminder(off))
+	}
+
+	rows := [][]tgmodels.InlineKeyboardButton{}
+
+	// Кнопки удаления — только для собственных смещений события
+	if !isDefault {
+		for _, off := range offsets {
+			rows = append(rows, []tgmodels.InlineKeyboardButton{{
+				Text:         "❌ " + formatReminder(off),
+				CallbackData: fmt.Sprintf("rem:del:%d:%d", event.ID, int64(off/time.Second)),
+			}})
+		}
+	}
+
+	present := make(map[time.Duration]bool, len(offsets))
+	if !isDefault {
+		for _, off := range offsets {
+			present[off] = true
+		}
+	}
+	addRow := []tgmodels.InlineKeyboardButton{}
+	for _, off := range reminderPresets {
+		if present[off] {
+			continue
+		}
+		label := "+" + formatOffset(off)
+		if off == 0 {
+			label = "+ в момент"
+		}
+		addRow = append(addRow, tgmodels.InlineKeyboardButton{
+			Text:         label,
+			CallbackData: fmt.Sprintf("rem:add:%d:%d", event.ID, int64(off/time.Second)),
+		})
+	}
+	if len(addRow) > 0 {
+		rows = append(rows, addRow)
+	}
+
+	if !isDefault {
+		rows = append(rows, []tgmodels.InlineKeyboardButton{
+			{Text: "🔄 По умолчанию", CallbackData: fmt.Sprintf("rem:reset:%d", event.ID)},
+		})
+	}
+
+	return text, &tgmodels.InlineKeyboardMarkup{InlineKeyboard: rows}, nil
+}
+
+// handleRemindCallback обрабатывает кнопки inline-меню /remind:
+// rem:add:<event_id>:<seconds>, rem:del:<event_id>:<seconds>, rem:reset:<event_id>.
+func handleRemindCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	cb := update.CallbackQuery
+	if cb == nil || cb.Message.Message == nil {
+		return
+	}
+
+	chatID := cb.Message.Message.Chat.ID
+	messageID := cb.Message.Message.ID
+	answer := ""
+
+	defer func() {
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID, Text: answer})
+	}()
+
+	parts := strings.Split(cb.Data, ":")
+	if len(parts) < 3 {
+		return
+	}
+	eventID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return
+	}
+
+	// Событие должно принадлежать чату, из которого пришёл callback
+	event, err := store.GetEventByID(ctx, eventID)
+	if err != nil || event.ChatID != chatID {
+		answer = "Событие не найдено"
+		return
+	}
+
+	if !canManageEvent(ctx, b, cb.Message.Message.Chat, cb.From.ID, event) {
+		answer = manageDeniedText
+		return
+	}
+
+	var offset time.Duration
+	if len(parts) >= 4 {
+		sec, err := strconv.ParseInt(parts[3], 10, 64)
+		if err != nil {
+			return
+		}
+		// Данные кнопки можно подделать: смещение проверяется так же, как введённое в /remind
+		if sec > int64(maxReminderOffset/time.Second) {
+			answer = "Некорректное смещение"
+			return
+		}
+		offset = time.Duration(sec) * time.Second
+		if err := checkOffset(offset); err != nil {
+			answer = "Некорректное смещение"
+			return
+		}
+	}
+
+	switch parts[1] {
+	case "add":
+		if msg := addEventReminders(ctx, event.ID, []time.Duration{offset}); msg != "" {
+			answer = msg
+			return
+		}
+	case "del":
+		if _, err := store.DeleteEventReminder(ctx, event.ID, offset); err != nil {
+			logger.Errorf("Ошибка удаления напоминания события %d: %v", event.ID, err)
+			answer = "Ошибка при удалении напоминания"
+			return
+		}
+	case "reset":
+		if err := store.ClearEventReminders(ctx, event.ID); err != nil {
+			logger.Errorf("Ошибка сброса напоминаний события %d: %v", event.ID, err)
+			answer = "Ошибка при сбросе напоминаний"
+			return
+		}
+	default:
+		return
+	}
+
+	text, kb, err := buildRemindMenu(ctx, event)
+	if err != nil {
+		logger.Errorf("Ошибка получения напоминаний события %d: %v", event.ID, err)
+		return
+	}
+	b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        text,
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: kb,
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+func TestRemindRequiresManageRights(t *testing.T) {
+	const (
+		chatID   = -200
+		authorID = 1
+		otherID  = 2
+		adminID  = 3
+	)
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+	ft.members[adminID] = string(tgmodels.ChatMemberTypeAdministrator)
+	e := ownedEvent(t, chatID, authorID, "party", time.Now().Add(48*time.Hour))
+
+	handleRemind(ctx, b, textMessage(chatID, otherID, "/remind party 3d"))
+	if got := ft.lastSent(chatID); got != manageDeniedText {
+		t.Errorf("stranger /remind: got %q, want denial", got)
+	}
+	handleRemindCallback(ctx, b, callbackQuery(chatID, otherID, fmt.Sprintf("rem:add:%d:%d", e.ID, 3600)))
+	if got := ft.answered(); got != manageDeniedText {
+		t.Errorf("stranger rem:add: answer %q, want denial", got)
+	}
+	if offsets, _ := store.ListEventReminders(ctx, e.ID); len(offsets) != 0 {
+		t.Fatalf("stranger changed reminders: %v", offsets)
+	}
+
+	handleRemind(ctx, b, textMessage(chatID, authorID, "/remind party 3d"))
+	handleRemindCallback(ctx, b, callbackQuery(chatID, adminID, fmt.Sprintf("rem:add:%d:%d", e.ID, 3600)))
+	offsets, err := store.ListEventReminders(ctx, e.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offsets) != 2 {
+		t.Errorf("reminders after author and admin changes = %v, want 3d and 1h", offsets)
+	}
+}
+
+func TestParseOffsetBounds(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "3d", want: 72 * time.Hour},
+		{in: "1d12h", want: 36 * time.Hour},
+		{in: "366d", want: maxReminderOffset},
+		{in: "367d", wantErr: true},
+		{in: "365d25h", wantErr: true},
+		{in: "99999999999999999999d", wantErr: true},
+		{in: "9223372036854775807m", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "5", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseOffset(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOffset(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("parseOffset(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRemindCallbackRejectsForgedOffset(t *testing.T) {
+	const chatID = 7
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+	e := ownedEvent(t, chatID, chatID, "party", time.Now().Add(48*time.Hour))
+
+	for _, sec := range []string{"-3600", "31708801", "9223372036854775807"} {
+		handleRemindCallback(ctx, b, callbackQuery(chatID, chatID, fmt.Sprintf("rem:add:%d:%s", e.ID, sec)))
+		if got := ft.answered(); got != "Некорректное смещение" {
+			t.Errorf("rem:add offset %s: answer %q, want rejection", sec, got)
+		}
+	}
+	if offsets, _ := store.ListEventReminders(ctx, e.ID); len(offsets) != 0 {
+		t.Errorf("forged offsets stored: %v", offsets)
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	"github.com/sirupsen/logrus"
+)
+
+// missedReminderWindow — если событие наступило раньше, чем столько времени назад,
+// пропущенные (например, из-за простоя бота) напоминания не отправляются по отдельности:
+// чат получает одно сообщение о том, что событие уже прошло и какие напоминания пропущены.
+const missedReminderWindow = time.Hour
+
+// scheduler периодически просматривает активные события и отправляет напоминания
+// в чат события за заданные интервалы до его наступления, переводит наступившие события
+// в outdated, а также отправляет дайджесты чатов (/digest).
+type scheduler struct {
+	b        *bot.Bot
+	offsets  []time.Duration
+	interval time.Duration
+}
+
+func newScheduler(b *bot.Bot, offsets []time.Duration, interval time.Duration) *scheduler {
+	sorted := append([]time.Duration(nil), offsets...)
+	// От больших смещений к меньшим: неделя, день, час, момент наступления
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+	return &scheduler{b: b, offsets: sorted, interval: interval}
+}
+
+// Run блокируется до отмены ctx. Первый проход выполняется сразу, чтобы после
+// перезапуска догнать напоминания, которые должны были сработать во время простоя.
+func (s *scheduler) Run(ctx context.Context) {
+	log := WithComponent("scheduler")
+	log.Infof("Планировщик напоминаний запущен (интервал %s, смещения %v)", s.interval, s.offsets)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			log.Info("Планировщик напоминаний остановлен")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *scheduler) tick(ctx context.Context) {
+	log := WithComponent("scheduler")
+	start := time.Now()
+
+	events, err := store.ListActiveEvents(ctx)
+	if err != nil {
+		LogSyncResult(log, "reminders", time.Since(start).Milliseconds(), 0, err)
+		return
+	}
+
+	// Часовые пояса чатов запрашиваем один раз за проход
+	locations := make(map[int64]*time.Location)
+	sent := 0
+	for _, e := range events {
+		if ctx.Err() != nil {
+			return
+		}
+		loc, ok := locations[e.ChatID]
+		if !ok {
+			loc = chatLocation(ctx, e.ChatID)
+			locations[e.ChatID] = loc
+		}
+		if s.processEvent(ctx, e, loc, start) {
+			sent++
+		}
+	}
+	LogSyncResult(log, "reminders", time.Since(start).Milliseconds(), sent, nil)
+
+	statusStart := time.Now()
+	swept := s.sweepStatuses(ctx, events, locations, start)
+	LogSyncResult(log, "statuses", time.Since(statusStart).Milliseconds(), swept, nil)
+
+	// sweepStatuses обновляет статусы в events, поэтому наступившие события уже не считаются
+	active := 0
+	for _, e := range events {
+		if e.Status == storage.StatusActive {
+			active++
+		}
+	}
+	EventsActive.Set(float64(active))
+
+	digestStart := time.Now()
+	digests, err := s.sendDigests(ctx, start)
+	LogSyncResult(log, "digests", time.Since(digestStart).Milliseconds(), digests, err)
+}
+
+// processEvent отправляет самое актуальное из наступивших напоминаний события. Более ранние
+// наступившие, но не отправленные напоминания не отправляются отдельно, чтобы не засыпать чат
+// устаревшими сообщениями: они перечисляются в том же сообщении. Если событие уже давно прошло,
+// вместо напоминания отправляется одно сообщение о пропущенных напоминаниях.
+// Возвращает true, если сообщение отправлено.
+func (s *scheduler) processEvent(ctx context.Context, e storage.Event, loc *time.Location, now time.Time) bool {
+	log := WithComponent("scheduler").WithField("event_id", e.ID).WithField("chat_id", e.ChatID)
+
+	// Для повторяющегося события берём ближайшее вхождение, включая только что наступившее.
+	// Вхождение входит в ключ отметок, чтобы каждое повторение напоминало заново.
+	occurrence, _, err := eventOccurrence(e, loc, now.Add(-missedReminderWindow))
+	if err != nil {
+		log.Warnf("Не удалось вычислить дату события '%s': %v", e.Name, err)
+		return false
+	}
+
+	// Собственные смещения события заменяют смещения по умолчанию
+	offsets, err := store.ListEventReminders(ctx, e.ID)
+	if err != nil {
+		log.Errorf("Ошибка получения смещений напоминаний: %v", err)
+		return false
+	}
+	if len(offsets) == 0 {
+		offsets = s.offsets
+	}
+
+	sentOffsets, err := store.ListSentReminders(ctx, e.ID, occurrence)
+	if err != nil {
+		log.Errorf("Ошибка получения отправленных напоминаний: %v", err)
+		return false
+	}
+	alreadySent := make(map[time.Duration]bool, len(sentOffsets))
+	for _, off := range sentOffsets {
+		alreadySent[off] = true
+	}
+
+	var due []time.Duration
+	for _, off := range offsets {
+		if !alreadySent[off] && !now.Before(occurrence.Add(-off)) {
+			due = append(due, off)
+		}
+	}
+	if len(due) == 0 {
+		return false
+	}
+
+	// Событие давно прошло — бот, видимо, был недоступен. Сообщаем об этом одним сообщением.
+	if now.Sub(occurrence) > missedReminderWindow {
+		claimed, ok := claimReminders(ctx, log, e, occurrence, due)
+		missed := missedOffsets(e, occurrence, claimed)
+		if !ok || len(missed) == 0 {
+			return false
+		}
+		log.Warnf("Пропущены напоминания события '%s' (%v): событие уже прошло", e.Name, missed)
+		return s.send(ctx, log, e, occurrence, claimed, missedEventText(e, occurrence.In(loc), missed))
+	}
+
+	// due отсортирован по убыванию смещения: последнее — самое актуальное
+	latest := due[len(due)-1]
+	earlier, ok := claimReminders(ctx, log, e, occurrence, due[:len(due)-1])
+	if !ok {
+		return false
+	}
+	claimed, err := store.ClaimReminder(ctx, e.ID, occurrence, latest)
+	if err != nil {
+		log.Errorf("Ошибка записи напоминания: %v", err)
+		releaseReminders(ctx, log, e, occurrence, earlier)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	text := reminderText(e, occurrence.In(loc), latest)
+	if missed := missedOffsets(e, occurrence, earlier); len(missed) > 0 {
+		log.Infof("Напоминания события '%s' %v пропущены, отправлено более позднее", e.Name, missed)
+		text += "\n⚠️ Не отправлены вовремя напоминания " + describeOffsets(missed)
+	}
+	if !s.send(ctx, log, e, occurrence, append(earlier, latest), text) {
+		return false
+	}
+	log.Infof("Напоминание отправлено: '%s' за %s", e.Name, formatOffset(latest))
+	return true
+}
+
+// send отправляет сообщение о событии. Если отправить не удалось, снимает отметки claimed,
+// чтобы повторить попытку на следующем проходе.
+func (s *scheduler) send(ctx context.Context, log *logrus.Entry, e storage.Event, occurrence time.Time, claimed []time.Duration, text string) bool {
+	if _, err := s.b.SendMessage(ctx, &bot.SendMessageParams{ChatID: e.ChatID, Text: text}); err != nil {
+		log.Errorf("Ошибка отправки напоминания: %v", err)
+		releaseReminders(ctx, log, e, occurrence, claimed)
+		return false
+	}
+	return true
+}
+
+// claimReminders отмечает напоминания offsets как отправленные и возвращает те, что отметил
+// именно этот вызов (остальные уже отметила другая реплика). При ошибке снимает свои отметки.
+func claimReminders(ctx context.Context, log *logrus.Entry, e storage.Event, occurrence time.Time, offsets []time.Duration) ([]time.Duration, bool) {
+	var claimed []time.Duration
+	for _, off := range offsets {
+		ok, err := store.ClaimReminder(ctx, e.ID, occurrence, off)
+		if err != nil {
+			log.Errorf("Ошибка записи напоминания: %v", err)
+			releaseReminders(ctx, log, e, occurrence, claimed)
+			return nil, false
+		}
+		if ok {
+			claimed = append(claimed, off)
+		}
+	}
+	return claimed, true
+}
+
+// releaseReminders снимает отметки об отправке напоминаний offsets.
+func releaseReminders(ctx context.Context, log *logrus.Entry, e storage.Event, occurrence time.Time, offsets []time.Duration) {
+	for _, off := range offsets {
+		if err := store.ReleaseReminder(ctx, e.ID, occurrence, off); err != nil {
+			log.Errorf("Ошибка снятия отметки напоминания: %v", err)
+		}
+	}
+}
+
+// missedOffsets возвращает из offsets напоминания, которые бот должен был отправить, но пропустил:
+// их время наступило уже после создания события. Более ранние к событию не относились — например,
+// напоминание за неделю для события, добавленного за день, — и о них чат не оповещается.
+func missedOffsets(e storage.Event, occurrence time.Time, offsets []time.Duration) []time.Duration {
+	var missed []time.Duration
+	for _, off := range offsets {
+		if !occurrence.Add(-off).Before(e.CreatedAt) {
+			missed = append(missed, off)
+		}
+	}
+	return missed
+}
+
+// missedEventText — сообщение о событии, которое наступило, пока бот был недоступен.
+func missedEventText(e storage.Event, date time.Time, missed []time.Duration) string {
+	msg := fmt.Sprintf("⚠️ Событие %s уже наступило (%s), пока бот был недоступен.\nНе отправлены напоминания %s",
+		e.DisplayTitle(), date.Format("2006-01-02 15:04"), describeOffsets(missed))
+	return msg + fmt.Sprintf("\n/%s", e.Name)
+}
+
+// describeOffsets перечисляет смещения напоминаний: "за 1 неделю, за 1 день, в момент наступления".
+func describeOffsets(offsets []time.Duration) string {
+	accusative := strings.NewReplacer("неделя", "неделю", "минута", "минуту")
+	parts := make([]string, 0, len(offsets))
+	for _, off := range offsets {
+		if off == 0 {
+			parts = append(parts, "в момент наступления")
+			continue
+		}
+		parts = append(parts, "за "+accusative.Replace(formatOffset(off)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reminderText формирует текст напоминания для смещения offset до даты события.
+func reminderText(e storage.Event, date time.Time, offset time.Duration) string {
+	var msg string
+	if offset == 0 {
+		msg = fmt.Sprintf("🎉 Событие %s наступило!\nДата: %s", e.DisplayTitle(), date.Format("2006-01-02 15:04"))
+	} else {
+		msg = fmt.Sprintf("⏰ Напоминание: до события %s осталось %s\nДата: %s",
+			e.DisplayTitle(), formatOffset(offset), date.Format("2006-01-02 15:04"))
+	}
+	if e.Description != "" {
+		msg += fmt.Sprintf("\nОписание: %s", e.Description)
+	}
+	return msg + fmt.Sprintf("\n/%s", e.Name)
+}
+
+// formatOffset выводит смещение по-русски: "1 неделя", "2 дня", "1 час", "15 минут".
+func formatOffset(d time.Duration) string {
+	if d <= 0 {
+		return "0 минут"
+	}
+	const (
+		day  = 24 * time.Hour
+		week = 7 * day
+	)
+	type unit struct {
+		size           time.Duration
+		one, few, many string
+	}
+	units := []unit{
+		{week, "неделя", "недели", "недель"},
+		{day, "день", "дня", "дней"},
+		{time.Hour, "час", "часа", "часов"},
+		{time.Minute, "минута", "минуты", "минут"},
+	}
+
+	var parts []string
+	for _, u := range units {
+		if n := int(d / u.size); n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, pluralRu(n, u.one, u.few, u.many)))
+			d -= time.Duration(n) * u.size
+		}
+	}
+	if len(parts) == 0 {
+		return "меньше минуты"
+	}
+	return strings.Join(parts, " ")
+}
+
+// pluralRu выбирает форму слова для числа n: 1 день, 2 дня, 5 дней.
+func pluralRu(n int, one, few, many string) string {
+	n %= 100
+	if n >= 11 && n <= 14 {
+		return many
+	}
+	switch n % 10 {
+	case 1:
+		return one
+	case 2, 3, 4:
+		return few
+	default:
+		return many
+	}
+}
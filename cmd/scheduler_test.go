@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/storage"
+)
+
+const testChatID = -100
+
+var testOffsets = []time.Duration{0, time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+
+// schedulerEvent создаёт событие с датой date, созданное в момент created.
+func schedulerEvent(t *testing.T, name string, date, created time.Time) storage.Event {
+	t.Helper()
+	e := &storage.Event{ChatID: testChatID, Name: name, Date: date}
+	if err := store.CreateEvent(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	e.CreatedAt = created
+	return *e
+}
+
+func TestProcessEventReportsMissedEvent(t *testing.T) {
+	b, ft := newTestBot(t)
+	s := newScheduler(b, testOffsets, time.Minute)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	e := schedulerEvent(t, "exam", now.Add(-2*time.Hour), now.Add(-14*24*time.Hour))
+	if !s.processEvent(ctx, e, time.UTC, now) {
+		t.Fatal("missed event: nothing sent")
+	}
+	expectContains(t, "missed event", ft.lastSent(testChatID),
+		"уже наступило", "за 1 неделю, за 1 день, за 1 час, в момент наступления", "/exam")
+
+	// Сообщение о пропуске отправляется один раз
+	if s.processEvent(ctx, e, time.UTC, now.Add(time.Minute)) {
+		t.Error("missed event reported twice")
+	}
+}
+
+func TestProcessEventListsMissedReminders(t *testing.T) {
+	b, ft := newTestBot(t)
+	s := newScheduler(b, testOffsets, time.Minute)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	e := schedulerEvent(t, "trip", now.Add(30*time.Minute), now.Add(-14*24*time.Hour))
+	if !s.processEvent(ctx, e, time.UTC, now) {
+		t.Fatal("reminder not sent")
+	}
+	text := ft.lastSent(testChatID)
+	expectContains(t, "reminder", text, "осталось 1 час", "Не отправлены вовремя напоминания за 1 неделю, за 1 день")
+
+	sent, err := store.ListSentReminders(ctx, e.ID, e.Date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 3 {
+		t.Errorf("claimed reminders = %v, want week, day and hour", sent)
+	}
+}
+
+func TestProcessEventSkipsRemindersBeforeCreation(t *testing.T) {
+	b, ft := newTestBot(t)
+	s := newScheduler(b, testOffsets, time.Minute)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// Событие добавлено за 40 минут до наступления: напоминания за неделю, день и час к нему не относились
+	e := schedulerEvent(t, "call", now.Add(30*time.Minute), now.Add(-10*time.Minute))
+	if !s.processEvent(ctx, e, time.UTC, now) {
+		t.Fatal("reminder not sent")
+	}
+	if text := ft.lastSent(testChatID); strings.Contains(text, "Не отправлены") {
+		t.Errorf("reminder mentions reminders that predate the event: %q", text)
+	}
+
+	// Событие прошло, пока бот был недоступен: о напоминании за час не сообщается
+	ft.reset()
+	e = schedulerEvent(t, "demo", now.Add(-2*time.Hour), now.Add(-150*time.Minute))
+	if !s.processEvent(ctx, e, time.UTC, now) {
+		t.Fatal("missed event: nothing sent")
+	}
+	text := ft.lastSent(testChatID)
+	expectContains(t, "missed event", text, "Не отправлены напоминания в момент наступления")
+	if strings.Contains(text, "за 1 час") {
+		t.Errorf("missed event mentions a reminder that predates the event: %q", text)
+	}
+
+	// Событие создано уже после наступления — сообщать не о чем
+	ft.reset()
+	e = schedulerEvent(t, "past", now.Add(-2*time.Hour), now.Add(-time.Hour))
+	if s.processEvent(ctx, e, time.UTC, now) || len(ft.sent(testChatID)) > 0 {
+		t.Errorf("event created after it happened: sent %v", ft.sent(testChatID))
+	}
+}
+
+func TestProcessEventReleasesOnSendFailure(t *testing.T) {
+	b, ft := newTestBot(t)
+	s := newScheduler(b, testOffsets, time.Minute)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	e := schedulerEvent(t, "launch", now.Add(30*time.Minute), now.Add(-14*24*time.Hour))
+	ft.fail["sendMessage"] = true
+	if s.processEvent(ctx, e, time.UTC, now) {
+		t.Fatal("send failed but processEvent reported success")
+	}
+	sent, err := store.ListSentReminders(ctx, e.ID, e.Date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 0 {
+		t.Errorf("claims kept after failed send: %v", sent)
+	}
+
+	// Следующий проход повторяет отправку
+	ft.fail["sendMessage"] = false
+	if !s.processEvent(ctx, e, time.UTC, now.Add(time.Minute)) {
+		t.Error("reminder not retried after failed send")
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/config"
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ──────────────────────────── сессии диалогов ────────────────────────────
+
+// Виды сессий: у пользователя в чате может быть по одной сессии каждого вида.
+const (
+	sessionPending      = "pending"       // событие в календаре или подтверждении даты
+	sessionAwaitingName = "awaiting_name" // ответ с названием после /set_date
+	sessionAwaitingEdit = "awaiting_edit" // ответ с новым описанием или названием после /edit
+	sessionBroadcast    = "broadcast"     // текст рассылки между предпросмотром и отправкой (/admin_broadcast)
+)
+
+// sessionSweepInterval — период очистки истёкших сессий. Сессия живёт config.SessionTTL
+// с последнего шага, так что сообщение о её истечении опаздывает не больше чем на этот период.
+const sessionSweepInterval = time.Minute
+
+// sessionTTL возвращает время жизни диалога из конфигурации.
+func sessionTTL() time.Duration {
+	if cfg := config.GetConfig(); cfg != nil && cfg.SessionTTL > 0 {
+		return cfg.SessionTTL
+	}
+	return 30 * time.Minute
+}
+
+// loadSession читает состояние диалога в v. Возвращает false, если сессии нет или она истекла.
+func loadSession(ctx context.Context, chatID, userID int64, kind string, v any) bool {
+	sess, err := store.GetSession(ctx, chatID, userID, kind)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			logger.Errorf("Ошибка чтения сессии %s chat_id=%d user_id=%d: %v", kind, chatID, userID, err)
+		}
+		return false
+	}
+	if err := json.Unmarshal(sess.Data, v); err != nil {
+		logger.Errorf("Повреждённая сессия %s chat_id=%d user_id=%d: %v", kind, chatID, userID, err)
+		return false
+	}
+	return true
+}
+
+// saveSession сохраняет состояние диалога и продлевает сессию на config.SessionTTL.
+func saveSession(ctx context.Context, chatID, userID int64, kind string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		logger.Errorf("Ошибка сериализации сессии %s: %v", kind, err)
+		return
+	}
+	sess := &storage.Session{
+		ChatID:    chatID,
+		UserID:    userID,
+		Kind:      kind,
+		Data:      data,
+		ExpiresAt: time.Now().Add(sessionTTL()),
+	}
+	if err := store.SetSession(ctx, sess); err != nil {
+		logger.Errorf("Ошибка сохранения сессии %s chat_id=%d user_id=%d: %v", kind, chatID, userID, err)
+	}
+}
+
+// dropSession завершает диалог.
+func dropSession(ctx context.Context, chatID, userID int64, kind string) {
+	if err := store.DeleteSession(ctx, chatID, userID, kind); err != nil {
+		logger.Errorf("Ошибка удаления сессии %s chat_id=%d user_id=%d: %v", kind, chatID, userID, err)
+	}
+}
+
+// sendPrompt отправляет запрос ввода с ForceReply (в ответ на сообщение replyTo, если оно задано)
+// и возвращает id отправленного сообщения или 0, если отправить не удалось. Ответом на запрос
+// считается только сообщение, отвечающее на него (repliesTo), — прочие сообщения пользователя
+// в группе не перехватываются.
+func sendPrompt(ctx context.Context, b *bot.Bot, chatID int64, replyTo int, text string) int {
+	params := &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        text,
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: &tgmodels.ForceReply{ForceReply: true, Selective: true},
+	}
+	if replyTo != 0 {
+		params.ReplyParameters = &tgmodels.ReplyParameters{MessageID: replyTo, AllowSendingWithoutReply: true}
+	}
+	msg, err := b.SendMessage(ctx, params)
+	if err != nil {
+		logger.Errorf("Ошибка отправки запроса ввода chat_id=%d: %v", chatID, err)
+		return 0
+	}
+	return msg.ID
+}
+
+// repliesTo сообщает, что msg — ответ на сообщение бота promptID.
+func repliesTo(msg *tgmodels.Message, promptID int) bool {
+	return promptID != 0 && msg.ReplyToMessage != nil && msg.ReplyToMessage.ID == promptID
+}
+
+// runSessionSweeper периодически удаляет истёкшие сессии, помечает их календари
+// устаревшими и обновляет метрики сессий. Каждую истёкшую сессию обрабатывает одна реплика.
+func runSessionSweeper(ctx context.Context, b *bot.Bot) {
+	log := WithComponent("sessions")
+	ticker := time.NewTicker(sessionSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		sweepSessions(ctx, b, log)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func sweepSessions(ctx context.Context, b *bot.Bot, log *logrus.Entry) {
+	now := time.Now()
+	expired, err := store.DeleteExpiredSessions(ctx, now)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Errorf("Ошибка удаления истёкших сессий: %v", err)
+		}
+		return
+	}
+	for _, sess := range expired {
+		SessionsExpiredCounter.WithLabelValues(sess.Kind).Inc()
+		SessionLifetime.WithLabelValues(sess.Kind).Observe(sess.ExpiresAt.Sub(sess.CreatedAt).Seconds())
+		if sess.Kind == sessionPending {
+			expirePendingMessage(ctx, b, sess)
+		}
+	}
+	if len(expired) > 0 {
+		log.Infof("Истекло сессий: %d", len(expired))
+	}
+
+	counts, err := store.CountSessions(ctx)
+	if err != nil {
+		log.Errorf("Ошибка подсчёта сессий: %v", err)
+		return
+	}
+	for _, kind := range []string{sessionPending, sessionAwaitingName, sessionAwaitingEdit, sessionBroadcast} {
+		SessionsActive.WithLabelValues(kind).Set(float64(counts[kind]))
+	}
+}
+
+// expirePendingMessage заменяет календарь (или подтверждение даты) истёкшей сессии
+// сообщением об истечении, чтобы его кнопки не выглядели рабочими.
+func expirePendingMessage(ctx context.Context, b *bot.Bot, sess storage.Session) {
+	var pe pendingEvent
+	if err := json.Unmarshal(sess.Data, &pe); err != nil || pe.MessageID == 0 {
+		return
+	}
+	text := "⌛ Время выбора даты истекло. Используйте /set_date заново."
+	if pe.EditEventID != 0 {
+		text = "⌛ Время изменения даты истекло. Используйте /edit заново."
+	}
+	_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    sess.ChatID,
+		MessageID: pe.MessageID,
+		Text:      text,
+	})
+	if err != nil {
+		// Сообщение могли удалить или уже заменить результатом
+		logger.Debugf("Не удалось пометить календарь истёкшим chat_id=%d message_id=%d: %v", sess.ChatID, pe.MessageID, err)
+	}
+}
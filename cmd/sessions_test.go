@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAwaitingNameOnlyTakesReplyToPrompt(t *testing.T) {
+	const (
+		chatID = -600
+		userID = 1
+	)
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+
+	handleSetDate(ctx, b, textMessage(chatID, userID, "/set_date"))
+	promptID := ft.lastMessageID(chatID)
+
+	// Обычное сообщение в группе и ответ на другое сообщение — не название события
+	handleEditedMessage(ctx, b, textMessage(chatID, userID, "всем привет"))
+	handleEditedMessage(ctx, b, replyMessage(chatID, userID, promptID+100, "Встреча"))
+	var pe pendingEvent
+	if loadSession(ctx, chatID, userID, sessionPending, &pe) {
+		t.Fatalf("message that is not a reply to the prompt started event %q", pe.Title)
+	}
+	if !isAwaitingName(ctx, chatID, userID) {
+		t.Fatal("unrelated message cleared the awaiting-name session")
+	}
+
+	// Ответ другого участника на запрос не засчитывается
+	handleEditedMessage(ctx, b, replyMessage(chatID, userID+1, promptID, "Чужое"))
+	if loadSession(ctx, chatID, userID+1, sessionPending, &pe) {
+		t.Fatal("another member's reply started an event")
+	}
+
+	handleEditedMessage(ctx, b, replyMessage(chatID, userID, promptID, "Встреча"))
+	if !loadSession(ctx, chatID, userID, sessionPending, &pe) || pe.Title != "Встреча" {
+		t.Fatalf("reply to the prompt: pending event = %+v", pe)
+	}
+	if isAwaitingName(ctx, chatID, userID) {
+		t.Error("awaiting-name session kept after the reply")
+	}
+}
+
+func TestAwaitingEditOnlyTakesReplyToPrompt(t *testing.T) {
+	const (
+		chatID = -600
+		userID = 1
+	)
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+	e := ownedEvent(t, chatID, userID, "party", time.Now().Add(24*time.Hour))
+
+	handleEditCallback(ctx, b, callbackQuery(chatID, userID, fmt.Sprintf("edit:desc:%d", e.ID)))
+	promptID := ft.lastMessageID(chatID)
+
+	handleEditedMessage(ctx, b, textMessage(chatID, userID, "просто сообщение"))
+	if got, _ := store.GetEventByID(ctx, e.ID); got.Description != "" {
+		t.Fatalf("message that is not a reply to the prompt became the description %q", got.Description)
+	}
+
+	handleEditedMessage(ctx, b, replyMessage(chatID, userID, promptID, "торт и свечи"))
+	if got, _ := store.GetEventByID(ctx, e.ID); got.Description != "торт и свечи" {
+		t.Errorf("reply to the prompt: description = %q", got.Description)
+	}
+	if _, ok := getAwaitingEdit(ctx, chatID, userID); ok {
+		t.Error("awaiting-edit session kept after the reply")
+	}
+}
+
+func TestCalendarCallbackOnlyFromCurrentCalendar(t *testing.T) {
+	const (
+		chatID = -700
+		userID = 1
+	)
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+
+	// calendarClick — нажатие кнопки под сообщением бота messageID
+	calendarClick := func(messageID int, data string) {
+		update := callbackQuery(chatID, userID, data)
+		update.CallbackQuery.Message.Message.ID = messageID
+		handleCalendarCallback(ctx, b, update)
+	}
+
+	handleSetDate(ctx, b, textMessage(chatID, userID, "/set_date завтра party"))
+	oldID := ft.lastMessageID(chatID)
+	handleSetDate(ctx, b, textMessage(chatID, userID, "/set_date trip"))
+	newID := ft.lastMessageID(chatID)
+	if oldID == 0 || newID == oldID {
+		t.Fatalf("set_date messages: old %d, new %d", oldID, newID)
+	}
+
+	// Кнопки подтверждения от первой команды не действуют на новую сессию
+	for _, data := range []string{"cal:confirm", "cal:pick", "cal:cancel", "cal:day:2030-01-01"} {
+		calendarClick(oldID, data)
+		if got := ft.answered(); got != "Этот календарь устарел" {
+			t.Errorf("%s on the old message: answer %q", data, got)
+		}
+	}
+	var pe pendingEvent
+	if !loadSession(ctx, chatID, userID, sessionPending, &pe) || pe.Title != "trip" || pe.Date != "" || pe.MessageID != newID {
+		t.Fatalf("old calendar changed the session: %+v", pe)
+	}
+	if events, _ := store.ListEvents(ctx, chatID); len(events) != 0 {
+		t.Fatalf("old calendar created events: %+v", events)
+	}
+
+	calendarClick(newID, "cal:cancel")
+	if loadSession(ctx, chatID, userID, sessionPending, &pe) {
+		t.Error("cal:cancel on the current calendar kept the session")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/storage"
+)
+
+// eventStatus возвращает статус, который должно иметь событие в момент now: active, пока
+// событие (для повторяющегося — очередное вхождение) не наступило, и outdated после этого.
+func eventStatus(e storage.Event, loc *time.Location, now time.Time) (string, error) {
+	_, upcoming, err := eventOccurrence(e, loc, now)
+	if err != nil {
+		return "", err
+	}
+	if upcoming {
+		return storage.StatusActive, nil
+	}
+	return storage.StatusOutdated, nil
+}
+
+// setEventStatus сохраняет новый статус события и учитывает переход в метрике.
+// Если статус не изменился, ничего не делает.
+func setEventStatus(ctx context.Context, e *storage.Event, status string) error {
+	if e.Status == status {
+		return nil
+	}
+	if err := store.UpdateEventStatus(ctx, e.ChatID, e.Name, status); err != nil {
+		return err
+	}
+	recordStatusTransition(e.Status, status)
+	e.Status = status
+	return nil
+}
+
+// recordStatusTransition учитывает смену статуса события в метрике event_status_transitions_total.
+func recordStatusTransition(from, to string) {
+	if from != to {
+		EventStatusTransitions.WithLabelValues(from, to).Inc()
+	}
+}
+
+// sweepStatuses переводит в outdated активные события, которые наступили к моменту now.
+// Вызывается планировщиком после напоминаний с тем же now: напоминание «в момент наступления»
+// успевает уйти раньше, чем событие пропадёт из ListActiveEvents. Возвращает число переведённых событий.
+func (s *scheduler) sweepStatuses(ctx context.Context, events []storage.Event, locations map[int64]*time.Location, now time.Time) int {
+	log := WithComponent("scheduler")
+
+	swept := 0
+	for i := range events {
+		e := &events[i]
+		if ctx.Err() != nil {
+			break
+		}
+		loc, ok := locations[e.ChatID]
+		if !ok {
+			loc = chatLocation(ctx, e.ChatID)
+			locations[e.ChatID] = loc
+		}
+		status, err := eventStatus(*e, loc, now)
+		if err != nil || status == e.Status {
+			continue
+		}
+		if err := setEventStatus(ctx, e, status); err != nil {
+			log.WithField("event_id", e.ID).Errorf("Ошибка обновления статуса события '%s': %v", e.Name, err)
+			continue
+		}
+		log.WithField("event_id", e.ID).Infof("Событие '%s' наступило: статус %s", e.Name, status)
+		swept++
+	}
+	return swept
+}
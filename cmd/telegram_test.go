@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/config"
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+// TestMain загружает конфигурацию из временного .env: LoadConfig требует файл,
+// а часовой пояс по умолчанию в тестах — UTC.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "timer-bot-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(dir+"/.env", []byte("TIMEZONE=UTC\nADMIN_ID=1000\n"), 0o600); err != nil {
+		panic(err)
+	}
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	config.LoadConfig()
+	os.Chdir(wd)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// apiCall — запрос бота к Bot API: метод, параметры формы и id сообщения в ответе.
+type apiCall struct {
+	Method    string
+	Params    map[string]string
+	MessageID int
+}
+
+// fakeTelegram — Bot API для тестов: запоминает запросы бота и отвечает успехом.
+// Ответ на отдельный метод можно заменить через fail, limited или members.
+type fakeTelegram struct {
+	mu      sync.Mutex
+	calls   []apiCall
+	fail    map[string]bool  // методы, на которые отвечать ошибкой 400
+	limited map[string]int   // методы, на которые отвечать 429 с retry_after в секундах
+	members map[int64]string // user_id → статус участника для getChatMember (по умолчанию member)
+	nextID  int
+}
+
+// newTestBot подменяет хранилище пустым хранилищем в памяти и возвращает бота,
+// который обращается к fakeTelegram.
+func newTestBot(t *testing.T) (*bot.Bot, *fakeTelegram) {
+	t.Helper()
+	store = storage.NewMemoryStorage()
+
+	ft := &fakeTelegram{fail: make(map[string]bool), limited: make(map[string]int), members: make(map[int64]string)}
+	srv := httptest.NewServer(ft)
+	t.Cleanup(srv.Close)
+
+	b, err := bot.New("123:test", bot.WithServerURL(srv.URL), bot.WithSkipGetMe(), bot.WithNotAsyncHandlers())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, ft
+}
+
+func (ft *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := path.Base(r.URL.Path)
+	params := make(map[string]string)
+	if err := r.ParseMultipartForm(1 << 20); err == nil {
+		for k, v := range r.MultipartForm.Value {
+			params[k] = v[0]
+		}
+	}
+
+	ft.mu.Lock()
+	ft.nextID++
+	id := ft.nextID
+	ft.calls = append(ft.calls, apiCall{Method: method, Params: params, MessageID: id})
+	fail := ft.fail[method]
+	retryAfter := ft.limited[method]
+	userID, _ := strconv.ParseInt(params["user_id"], 10, 64)
+	status := ft.members[userID]
+	ft.mu.Unlock()
+
+	if retryAfter > 0 {
+		json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": 429, "description": "Too Many Requests",
+			"parameters": map[string]any{"retry_after": retryAfter}})
+		return
+	}
+	if fail {
+		json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": 400, "description": "Bad Request: test failure"})
+		return
+	}
+
+	var result any = true
+	switch method {
+	case "sendMessage", "editMessageText":
+		chatID, _ := strconv.ParseInt(params["chat_id"], 10, 64)
+		result = tgmodels.Message{ID: id, Chat: tgmodels.Chat{ID: chatID}, Text: params["text"]}
+	case "getChatMember":
+		if status == "" {
+			status = string(tgmodels.ChatMemberTypeMember)
+		}
+		result = map[string]any{"status": status, "user": map[string]any{"id": userID}}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+// sent возвращает тексты сообщений, отправленных в чат (sendMessage и editMessageText).
+func (ft *fakeTelegram) sent(chatID int64) []string {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	var texts []string
+	for _, c := range ft.calls {
+		if (c.Method == "sendMessage" || c.Method == "editMessageText") && c.Params["chat_id"] == fmt.Sprint(chatID) {
+			texts = append(texts, c.Params["text"])
+		}
+	}
+	return texts
+}
+
+// lastSent возвращает текст последнего сообщения в чат или пустую строку.
+func (ft *fakeTelegram) lastSent(chatID int64) string {
+	texts := ft.sent(chatID)
+	if len(texts) == 0 {
+		return ""
+	}
+	return texts[len(texts)-1]
+}
+
+// lastMessageID возвращает id последнего сообщения, отправленного ботом в чат (0 — сообщений не было).
+func (ft *fakeTelegram) lastMessageID(chatID int64) int {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	for i := len(ft.calls) - 1; i >= 0; i-- {
+		if c := ft.calls[i]; c.Method == "sendMessage" && c.Params["chat_id"] == fmt.Sprint(chatID) {
+			return c.MessageID
+		}
+	}
+	return 0
+}
+
+// reset забывает запросы, сделанные до этого момента.
+func (ft *fakeTelegram) reset() {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	ft.calls = nil
+}
+
+// textMessage — обновление с текстовым сообщением пользователя userID в чате chatID.
+func textMessage(chatID, userID int64, text string) *tgmodels.Update {
+	chatType := tgmodels.ChatTypeGroup
+	if chatID == userID {
+		chatType = tgmodels.ChatTypePrivate
+	}
+	return &tgmodels.Update{Message: &tgmodels.Message{
+		ID:   1,
+		Chat: tgmodels.Chat{ID: chatID, Type: chatType},
+		From: &tgmodels.User{ID: userID, FirstName: "Test"},
+		Text: text,
+	}}
+}
+
+// replyMessage — textMessage в ответ на сообщение бота replyTo.
+func replyMessage(chatID, userID int64, replyTo int, text string) *tgmodels.Update {
+	update := textMessage(chatID, userID, text)
+	update.Message.ReplyToMessage = &tgmodels.Message{ID: replyTo, Chat: update.Message.Chat}
+	return update
+}
+
+// expectContains проверяет, что текст содержит все подстроки want.
+func expectContains(t *testing.T, what, text string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(text, w) {
+			t.Errorf("%s: %q does not contain %q", what, text, w)
+		}
+	}
+}
+
+// callbackQuery — нажатие пользователем userID кнопки с данными data под сообщением бота в чате chatID.
+func callbackQuery(chatID, userID int64, data string) *tgmodels.Update {
+	chatType := tgmodels.ChatTypeGroup
+	if chatID == userID {
+		chatType = tgmodels.ChatTypePrivate
+	}
+	return &tgmodels.Update{CallbackQuery: &tgmodels.CallbackQuery{
+		ID:   "cb",
+		From: tgmodels.User{ID: userID, FirstName: "Test"},
+		Message: tgmodels.MaybeInaccessibleMessage{
+			Type:    tgmodels.MaybeInaccessibleMessageTypeMessage,
+			Message: &tgmodels.Message{ID: 1, Chat: tgmodels.Chat{ID: chatID, Type: chatType}},
+		},
+		Data: data,
+	}}
+}
+
+// answered возвращает текст последнего ответа на нажатие кнопки.
+func (ft *fakeTelegram) answered() string {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	for i := len(ft.calls) - 1; i >= 0; i-- {
+		if ft.calls[i].Method == "answerCallbackQuery" {
+			return ft.calls[i].Params["text"]
+		}
+	}
+	return ""
+}
+
+// ownedEvent создаёт событие name в чате chatID от имени пользователя ownerID.
+func ownedEvent(t *testing.T, chatID, ownerID int64, name string, date time.Time) *storage.Event {
+	t.Helper()
+	ctx := context.Background()
+	e := &storage.Event{ChatID: chatID, Name: name, Date: date}
+	if err := store.CreateEvent(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddEventToUser(ctx, chatID, ownerID, e.ID); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
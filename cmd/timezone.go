@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // база часовых поясов на случай, если в образе нет tzdata
+
+	"github.com/TheReshkin/timer-bot/internal/config"
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+const timezoneDeniedText = "⛔ Менять часовой пояс чата может только администратор чата"
+
+// chatLocation возвращает часовой пояс чата: выбранный через /timezone или TIMEZONE из конфигурации.
+func chatLocation(ctx context.Context, chatID int64) *time.Location {
+	settings, err := store.GetChatSettings(ctx, chatID)
+	if err != nil {
+		logger.Errorf("Ошибка получения настроек чата %d: %v", chatID, err)
+		return config.GetConfig().Location
+	}
+	return settingsLocation(settings)
+}
+
+// settingsLocation — chatLocation для уже полученных настроек чата.
+func settingsLocation(settings *storage.ChatSettings) *time.Location {
+	def := config.GetConfig().Location
+	if settings.Timezone == "" {
+		return def
+	}
+
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		logger.Warnf("Некорректный часовой пояс чата %d (%q): %v", settings.ChatID, settings.Timezone, err)
+		return def
+	}
+	return loc
+}
+
+// formatEventTime выводит момент времени в часовом поясе loc.
+func formatEventTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02 15:04")
+}
+
+// loadChatTimezone проверяет IANA-имя часового пояса, введённое пользователем.
+func loadChatTimezone(name string) (*time.Location, error) {
+	// "Local" зависит от сервера, а пустое имя — это UTC; оба варианта пользователю не нужны
+	if name == "" || strings.EqualFold(name, "local") {
+		return nil, fmt.Errorf("неизвестный часовой пояс: %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("неизвестный часовой пояс: %q (пример: Europe/Moscow)", name)
+	}
+	return loc, nil
+}
+
+// handleTimezone показывает или меняет часовой пояс чата:
+//
+//	/timezone                 — текущий часовой пояс
+//	/timezone Europe/Berlin   — выбрать часовой пояс (IANA)
+//	/timezone reset           — вернуть часовой пояс по умолчанию
+//
+// В группе менять часовой пояс может только администратор, посмотреть — любой участник.
+func handleTimezone(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if update.Message == nil {
+		return
+	}
+	parts := strings.Fields(normalizeCommand(update.Message.Text))
+	if len(parts) == 0 || parts[0] != "/timezone" {
+		handleDynamicOrUnknown(ctx, b, update)
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	if len(parts) == 1 {
+		loc := chatLocation(ctx, chatID)
+		sendMessage(ctx, b, chatID, fmt.Sprintf(
+			"🕐 Часовой пояс чата: %s (сейчас %s)\nИзменить: /timezone Europe/Moscow",
+			loc, time.Now().In(loc).Format("2006-01-02 15:04")))
+		return
+	}
+
+	if !canManageChat(ctx, b, update.Message) {
+		sendMessage(ctx, b, chatID, timezoneDeniedText)
+		return
+	}
+
+	name := parts[1]
+	if name == "reset" {
+		if err := store.SetChatTimezone(ctx, chatID, ""); err != nil {
+			logger.Errorf("Ошибка сохранения часового пояса чата %d: %v", chatID, err)
+			sendMessage(ctx, b, chatID, "Ошибка при сохранении часового пояса")
+			return
+		}
+		sendMessage(ctx, b, chatID, fmt.Sprintf("🕐 Часовой пояс чата сброшен на %s", config.GetConfig().Timezone))
+		return
+	}
+
+	loc, err := loadChatTimezone(name)
+	if err != nil {
+		sendMessage(ctx, b, chatID, fmt.Sprintf("Ошибка: %s", err))
+		return
+	}
+	if err := store.SetChatTimezone(ctx, chatID, loc.String()); err != nil {
+		logger.Errorf("Ошибка сохранения часового пояса чата %d: %v", chatID, err)
+		sendMessage(ctx, b, chatID, "Ошибка при сохранении часового пояса")
+		return
+	}
+
+	logger.Infof("Часовой пояс чата %d: %s", chatID, loc)
+	sendMessage(ctx, b, chatID, fmt.Sprintf("🕐 Часовой пояс чата: %s (сейчас %s)",
+		loc, time.Now().In(loc).Format("2006-01-02 15:04")))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+func TestTimezoneRequiresChatAdmin(t *testing.T) {
+	const (
+		chatID   = -300
+		memberID = 1
+		adminID  = 2
+	)
+	b, ft := newTestBot(t)
+	ctx := context.Background()
+	ft.members[adminID] = string(tgmodels.ChatMemberTypeAdministrator)
+
+	handleTimezone(ctx, b, textMessage(chatID, memberID, "/timezone Europe/Berlin"))
+	if got := ft.lastSent(chatID); got != timezoneDeniedText {
+		t.Errorf("member /timezone: got %q, want denial", got)
+	}
+	handleTimezone(ctx, b, textMessage(chatID, memberID, "/timezone reset"))
+	if got := ft.lastSent(chatID); got != timezoneDeniedText {
+		t.Errorf("member /timezone reset: got %q, want denial", got)
+	}
+	if loc := chatLocation(ctx, chatID); loc.String() != "UTC" {
+		t.Fatalf("member changed timezone to %s", loc)
+	}
+
+	// Посмотреть часовой пояс может любой участник
+	handleTimezone(ctx, b, textMessage(chatID, memberID, "/timezone"))
+	expectContains(t, "member /timezone", ft.lastSent(chatID), "Часовой пояс чата: UTC")
+
+	handleTimezone(ctx, b, textMessage(chatID, adminID, "/timezone Europe/Berlin"))
+	if loc := chatLocation(ctx, chatID); loc.String() != "Europe/Berlin" {
+		t.Errorf("admin /timezone: timezone = %s, want Europe/Berlin", loc)
+	}
+
+	// В личном чате права не проверяются
+	handleTimezone(ctx, b, textMessage(memberID, memberID, "/timezone Asia/Tokyo"))
+	if loc := chatLocation(ctx, memberID); loc.String() != "Asia/Tokyo" {
+		t.Errorf("private /timezone: timezone = %s, want Asia/Tokyo", loc)
+	}
+}
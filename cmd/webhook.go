@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/config"
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+const (
+	// webhookSecretHeader — заголовок, в котором Telegram передаёт секрет вебхука.
+	webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+	// maxWebhookBody — предел размера обновления; обычные обновления занимают единицы килобайт.
+	maxWebhookBody = 1 << 20
+)
+
+// allowedUpdates — типы обновлений, которые бот получает (и через getUpdates, и через вебхук).
+var allowedUpdates = bot.AllowedUpdates{"message", "edited_message", "callback_query"}
+
+// webhookSecretPattern — допустимый секрет вебхука по документации Bot API.
+var webhookSecretPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// ──────────────────────────── вебхук ────────────────────────────
+
+// webhookHandler принимает обновления от Telegram и передаёт их тем же обработчикам,
+// что и long polling. Запрос без верного секрета отклоняется с 401.
+// ctx — корневой контекст бота: обработчики не должны зависеть от HTTP-запроса,
+// на который Telegram ждёт ответа.
+func webhookHandler(ctx context.Context, b *bot.Bot, secret string) http.HandlerFunc {
+	log := WithComponent("webhook")
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookSecretHeader)), []byte(secret)) != 1 {
+			log.Warnf("Запрос вебхука с неверным секретом от %s", r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		// После сигнала остановки новые обновления не принимаем: Telegram повторит их позже
+		if ctx.Err() != nil || ready.stopping.Load() {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var update tgmodels.Update
+		if err := json.Unmarshal(body, &update); err != nil {
+			log.Warnf("Некорректное обновление вебхука: %v", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		ready.polled(time.Now())
+		// ProcessUpdate запускает обработчик в отдельной горутине, ответ Telegram не задерживается
+		b.ProcessUpdate(ctx, &update)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// startWebhookServer запускает HTTP-сервер вебхука и, если задан WEBHOOK_URL, регистрирует вебхук в Telegram.
+func startWebhookServer(ctx context.Context, b *bot.Bot, cfg *config.Config) (*http.Server, error) {
+	if !webhookSecretPattern.MatchString(cfg.WebhookSecret) {
+		return nil, errors.New("WEBHOOK_SECRET должен содержать от 1 до 256 символов A-Z, a-z, 0-9, _ и -")
+	}
+	log := WithComponent("webhook")
+
+	// Порт занимаем сразу, чтобы ошибка остановила запуск, а не только попала в лог
+	ln, err := net.Listen("tcp", cfg.WebhookAddr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(cfg.WebhookPath, webhookHandler(ctx, b, cfg.WebhookSecret))
+	srv := &http.Server{
+		Addr:              cfg.WebhookAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Сервер вебхука остановлен с ошибкой: %v", err)
+		}
+	}()
+	log.Infof("Вебхук принимается на %s%s", cfg.WebhookAddr, cfg.WebhookPath)
+
+	if cfg.WebhookURL == "" {
+		log.Warn("WEBHOOK_URL не задан: вебхук не зарегистрирован в Telegram, обновления принимаются только локально")
+		return srv, nil
+	}
+	if _, err := b.SetWebhook(ctx, &bot.SetWebhookParams{
+		URL:            cfg.WebhookURL,
+		AllowedUpdates: allowedUpdates,
+		SecretToken:    cfg.WebhookSecret,
+	}); err != nil {
+		stopHTTPServer(srv, time.Second)
+		return nil, fmt.Errorf("не удалось зарегистрировать вебхук: %w", err)
+	}
+	log.Infof("Вебхук зарегистрирован: %s", cfg.WebhookURL)
+	return srv, nil
+}
+
+// deleteWebhook снимает вебхук в Telegram. Обновления, пришедшие до следующего запуска,
+// Telegram хранит у себя и отдаст через getUpdates или новый вебхук.
+func deleteWebhook(b *bot.Bot) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := b.DeleteWebhook(ctx, &bot.DeleteWebhookParams{}); err != nil {
+		WithComponent("webhook").Errorf("Не удалось снять вебхук: %v", err)
+		return
+	}
+	WithComponent("webhook").Info("Вебхук снят")
+}
+
+// clearStaleWebhook снимает вебхук, оставшийся от запуска в режиме webhook:
+// пока он установлен, getUpdates возвращает ошибку 409.
+func clearStaleWebhook(ctx context.Context, b *bot.Bot) {
+	info, err := b.GetWebhookInfo(ctx)
+	if err != nil {
+		WithComponent("webhook").Warnf("Не удалось получить информацию о вебхуке: %v", err)
+		return
+	}
+	if info.URL != "" {
+		WithComponent("webhook").Infof("Установлен вебхук %s, снимаем его для long polling", info.URL)
+		deleteWebhook(b)
+	}
+}
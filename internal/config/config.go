@@ -4,21 +4,54 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	Token       string
-	AdminID     int
-	TestChatID  int
 	ServiceName string
 	DatabaseURL string
+
+	// AdminIDs — id пользователей Telegram с доступом к командам /admin_* (ADMIN_ID, через запятую).
+	AdminIDs []int64
+
+	// Timezone — часовой пояс по умолчанию для чатов, не выбравших свой (/timezone).
+	Timezone string
+	Location *time.Location
+
+	// ReminderOffsets — за сколько до события отправлять напоминания (0 — в момент наступления).
+	ReminderOffsets []time.Duration
+	// SchedulerInterval — период опроса событий фоновым планировщиком.
+	SchedulerInterval time.Duration
+	// SessionTTL — сколько живёт незавершённый диалог (календарь, ожидание ввода) после последнего шага.
+	SessionTTL time.Duration
+	// ShutdownTimeout — сколько при остановке ждать начатые обработчики и фоновые задачи.
+	// Должен быть меньше времени, которое Docker даёт контейнеру до SIGKILL (10s по умолчанию).
+	ShutdownTimeout time.Duration
+	// MetricsAddr — адрес HTTP-сервера метрик Prometheus (/metrics); "off" отключает сервер.
+	MetricsAddr string
+
+	// UpdatesMode — способ получения обновлений: UpdatesPolling (getUpdates) или UpdatesWebhook.
+	UpdatesMode string
+	// WebhookURL — публичный HTTPS-адрес вебхука, который регистрируется в Telegram.
+	// Пустой в режиме webhook — вебхук не регистрируется (локальная проверка POST-запросами).
+	WebhookURL string
+	// WebhookAddr и WebhookPath — где бот принимает вебхук (за обратным прокси).
+	WebhookAddr string
+	WebhookPath string
+	// WebhookSecret — секрет, который Telegram передаёт в заголовке X-Telegram-Bot-Api-Secret-Token.
+	WebhookSecret string
 }
 
-// // TestChatID is the hardcoded chat ID used for testing and fallback searches
-// var TestChatID int64 = 332288278
+// Способы получения обновлений (UPDATES_MODE).
+const (
+	UpdatesPolling = "polling"
+	UpdatesWebhook = "webhook"
+)
 
 var (
 	configInstance *Config
@@ -34,35 +67,110 @@ func LoadConfig() *Config {
 
 		configInstance = &Config{
 			Token:       os.Getenv("TELEGRAM_TOKEN"),
-			AdminID:     getEnvAsInt("ADMIN_ID", 0),
-			TestChatID:  getEnvAsInt("TEST_CHAT_ID", 0),
+			AdminIDs:    getEnvAsInt64s("ADMIN_ID"),
 			ServiceName: os.Getenv("c"),
 			DatabaseURL: os.Getenv("DATABASE_URL"),
+
+			Timezone: getEnv("TIMEZONE", "Europe/Moscow"),
+
+			ReminderOffsets:   getEnvAsDurations("REMINDER_OFFSETS", []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, 0}),
+			SchedulerInterval: getEnvAsDuration("SCHEDULER_INTERVAL", 30*time.Second),
+			SessionTTL:        getEnvAsDuration("SESSION_TTL", 30*time.Minute),
+			ShutdownTimeout:   getEnvAsDuration("SHUTDOWN_TIMEOUT", 8*time.Second),
+
+			MetricsAddr: getEnv("METRICS_ADDR", ":9090"),
+
+			UpdatesMode:   strings.ToLower(getEnv("UPDATES_MODE", UpdatesPolling)),
+			WebhookURL:    os.Getenv("WEBHOOK_URL"),
+			WebhookAddr:   getEnv("WEBHOOK_ADDR", ":8080"),
+			WebhookPath:   getEnv("WEBHOOK_PATH", "/webhook"),
+			WebhookSecret: os.Getenv("WEBHOOK_SECRET"),
+		}
+
+		loc, err := time.LoadLocation(configInstance.Timezone)
+		if err != nil {
+			log.Printf("Неизвестный часовой пояс TIMEZONE=%q, используется UTC: %v", configInstance.Timezone, err)
+			configInstance.Timezone = "UTC"
+			loc = time.UTC
+		}
+		configInstance.Location = loc
+
+		if m := configInstance.UpdatesMode; m != UpdatesPolling && m != UpdatesWebhook {
+			log.Printf("Неизвестный UPDATES_MODE=%q, используется %s", m, UpdatesPolling)
+			configInstance.UpdatesMode = UpdatesPolling
 		}
 	})
 	return configInstance
 }
 
+// IsAdmin сообщает, является ли пользователь администратором бота.
+func (c *Config) IsAdmin(userID int64) bool {
+	for _, id := range c.AdminIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
 // GetConfig returns the current config singleton (nil if not yet loaded).
 func GetConfig() *Config {
 	return configInstance
 }
 
-// // LoadTestChatID loads the test chat ID from environment variable or uses default
-// func LoadTestChatID() int64 {
-// 	if envValue := os.Getenv("TEST_CHAT_ID"); envValue != "" {
-// 		if parsed, err := strconv.ParseInt(envValue, 10, 64); err == nil {
-// 			return parsed
-// 		}
-// 	}
-// 	return TestChatID
-// }
-
-func getEnvAsInt(key string, defaultValue int) int {
+func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
 		}
+		log.Printf("Некорректное значение %s=%q, используется %s", key, value, defaultValue)
 	}
 	return defaultValue
 }
+
+// getEnvAsDurations читает список длительностей через запятую (например, "168h,24h,1h,0").
+func getEnvAsDurations(key string, defaultValue []time.Duration) []time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var result []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil || d < 0 {
+			log.Printf("Некорректное значение %s=%q, используется значение по умолчанию", key, value)
+			return defaultValue
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
+// getEnvAsInt64s читает список целых чисел через запятую (например, "123,456"). Некорректные значения пропускаются.
+func getEnvAsInt64s(key string) []int64 {
+	var result []int64
+	for _, part := range strings.Split(os.Getenv(key), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			log.Printf("Некорректное значение в %s: %q пропущено", key, part)
+			continue
+		}
+		result = append(result, id)
+	}
+	return result
+}
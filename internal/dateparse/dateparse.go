@@ -0,0 +1,497 @@
+// Package dateparse распознаёт даты, записанные словами по-русски и по-английски:
+// "завтра в 18:00", "через 3 дня", "в пятницу", "31 декабря", "next monday 9am", "in 2 weeks",
+// а также числовые форматы 2025-12-31, 31.12.2025 и 31.12.
+//
+// Выражение состоит из даты и времени в любом порядке (каждое необязательно) либо из
+// относительного смещения ("через 2 часа"). Все вычисления идут в часовом поясе now.
+package dateparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result — распознанное выражение даты.
+type Result struct {
+	Time    time.Time
+	Words   int  // сколько слов в начале текста занимает выражение
+	HasTime bool // время указано явно (иначе — 00:00)
+}
+
+// Parse разбирает строку целиком. Возвращает ошибку, если строка не является выражением даты
+// или после даты остались лишние слова.
+func Parse(s string, now time.Time) (time.Time, error) {
+	words := strings.Fields(s)
+	res, ok := Prefix(words, now)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+	}
+	if res.Words != len(words) {
+		return time.Time{}, fmt.Errorf("unexpected %q after date", strings.Join(words[res.Words:], " "))
+	}
+	return res.Time, nil
+}
+
+// Prefix ищет выражение даты в начале списка слов и возвращает его значение и длину.
+// Остальные слова (название и описание события) не разбираются.
+func Prefix(words []string, now time.Time) (Result, bool) {
+	p := &parser{words: make([]string, len(words)), now: now}
+	for i, w := range words {
+		p.words[i] = strings.TrimRight(strings.ToLower(w), ",")
+	}
+
+	var (
+		date    time.Time // полночь выбранного дня
+		hasDate bool
+		hour    int
+		minute  int
+		hasTime bool
+		i       int
+	)
+
+	// Относительное смещение в минутах и часах задаёт момент целиком
+	if n, t, ok := p.relativeClock(0); ok {
+		return Result{Time: t.Truncate(time.Minute), Words: n, HasTime: true}, true
+	}
+
+	for i < len(p.words) {
+		if !hasDate {
+			if n, d, ok := p.date(i); ok {
+				date, hasDate = d, true
+				i += n
+				continue
+			}
+		}
+		if !hasTime {
+			if n, h, m, ok := p.clock(i); ok {
+				hour, minute, hasTime = h, m, true
+				i += n
+				continue
+			}
+		}
+		break
+	}
+
+	if !hasDate && !hasTime {
+		return Result{}, false
+	}
+
+	loc := now.Location()
+	if !hasDate {
+		// Только время: сегодня, а если оно уже прошло — завтра
+		date = midnight(now)
+		if !time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, loc).After(now) {
+			date = date.AddDate(0, 0, 1)
+		}
+	}
+	t := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, loc)
+	return Result{Time: t, Words: i, HasTime: hasTime}, true
+}
+
+type parser struct {
+	words []string
+	now   time.Time
+}
+
+func (p *parser) word(i int) string {
+	if i < len(p.words) {
+		return p.words[i]
+	}
+	return ""
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// ---------- дата ----------
+
+// datePrepositions — слова перед датой, не меняющие её значения.
+var datePrepositions = map[string]bool{"в": true, "во": true, "on": true, "this": true}
+
+// nextWords — "следующий/next": ближайший такой день недели, как и без этого слова.
+var nextWords = map[string]bool{
+	"next": true, "следующий": true, "следующую": true, "следующее": true, "следующая": true, "след": true,
+}
+
+// date разбирает дату, начинающуюся со слова i, и возвращает число слов и полночь этого дня.
+func (p *parser) date(i int) (int, time.Time, bool) {
+	skip := 0
+	for skip < 2 && (datePrepositions[p.word(i+skip)] || nextWords[p.word(i+skip)]) {
+		skip++
+	}
+	if skip > 0 {
+		if n, d, ok := p.dateWord(i+skip, true); ok {
+			return skip + n, d, true
+		}
+	}
+	return p.dateWord(i, false)
+}
+
+// dateWord разбирает дату без предлогов. Сокращения дней недели и месяцев ("sun", "mar", "пт")
+// в начале даты считаются датой, только если перед ними стоит предлог (afterPrep): иначе
+// "Sun party" или "Mar 5 tour" — это название события.
+func (p *parser) dateWord(i int, afterPrep bool) (int, time.Time, bool) {
+	today := midnight(p.now)
+	w := p.word(i)
+
+	switch w {
+	case "сегодня", "today":
+		return 1, today, true
+	case "завтра", "tomorrow":
+		return 1, today.AddDate(0, 0, 1), true
+	case "послезавтра":
+		return 1, today.AddDate(0, 0, 2), true
+	case "day":
+		if p.word(i+1) == "after" && p.word(i+2) == "tomorrow" {
+			return 3, today.AddDate(0, 0, 2), true
+		}
+	}
+
+	if wd, ok := parseWeekday(w); ok && (afterPrep || !abbreviations[w]) {
+		// Ближайший такой день недели после сегодняшнего
+		days := (int(wd) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return 1, today.AddDate(0, 0, days), true
+	}
+
+	if n, d, ok := p.relativeDate(i); ok {
+		return n, d, true
+	}
+	if n, d, ok := p.numericDate(i); ok {
+		return n, d, true
+	}
+	return p.monthNameDate(i, afterPrep)
+}
+
+// numericDate — 2025-12-31, 31.12.2025, 31.12 (ближайшее 31 декабря).
+func (p *parser) numericDate(i int) (int, time.Time, bool) {
+	w := p.word(i)
+	loc := p.now.Location()
+	for _, layout := range []string{"2006-1-2", "2.1.2006"} {
+		if t, err := time.ParseInLocation(layout, w, loc); err == nil {
+			return 1, t, true
+		}
+	}
+	if t, err := time.ParseInLocation("2.1", w, loc); err == nil {
+		d, ok := p.nearestDate(t.Month(), t.Day())
+		return 1, d, ok
+	}
+	return 0, time.Time{}, false
+}
+
+// monthNameDate — "31 декабря [2026]", "december 31[, 2026]", "31 dec", "on dec 31".
+func (p *parser) monthNameDate(i int, afterPrep bool) (int, time.Time, bool) {
+	var (
+		day   int
+		month time.Month
+		n     int
+	)
+	if d, ok := parseDay(p.word(i)); ok {
+		m, ok := parseMonth(p.word(i + 1))
+		if !ok {
+			return 0, time.Time{}, false
+		}
+		day, month, n = d, m, 2
+	} else if m, ok := parseMonth(p.word(i)); ok && (afterPrep || !abbreviations[strings.TrimSuffix(p.word(i), ".")]) {
+		d, ok := parseDay(p.word(i + 1))
+		if !ok {
+			return 0, time.Time{}, false
+		}
+		day, month, n = d, m, 2
+	} else {
+		return 0, time.Time{}, false
+	}
+
+	if year, ok := parseYear(p.word(i + n)); ok {
+		n++
+		if w := p.word(i + n); w == "г" || w == "г." || w == "года" || w == "год" {
+			n++
+		}
+		t := time.Date(year, month, day, 0, 0, 0, 0, p.now.Location())
+		if t.Day() != day {
+			return 0, time.Time{}, false
+		}
+		return n, t, true
+	}
+	d, ok := p.nearestDate(month, day)
+	return n, d, ok
+}
+
+// nearestDate возвращает ближайшую (сегодня или позже) дату с указанными месяцем и днём.
+func (p *parser) nearestDate(month time.Month, day int) (time.Time, bool) {
+	today := midnight(p.now)
+	for year := today.Year(); year <= today.Year()+8; year++ {
+		t := time.Date(year, month, day, 0, 0, 0, 0, p.now.Location())
+		if t.Day() != day {
+			continue // 29 февраля в невисокосный год
+		}
+		if !t.Before(today) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// relativeDate — "через 3 дня", "через неделю", "in 2 weeks", "in a month".
+func (p *parser) relativeDate(i int) (int, time.Time, bool) {
+	n, amount, unit, ok := p.relative(i)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	today := midnight(p.now)
+	switch unit {
+	case unitDay:
+		return n, today.AddDate(0, 0, amount), true
+	case unitWeek:
+		return n, today.AddDate(0, 0, 7*amount), true
+	case unitMonth:
+		return n, today.AddDate(0, amount, 0), true
+	case unitYear:
+		return n, today.AddDate(amount, 0, 0), true
+	}
+	return 0, time.Time{}, false
+}
+
+// relativeClock — "через 15 минут", "через 2 часа", "in an hour".
+func (p *parser) relativeClock(i int) (int, time.Time, bool) {
+	n, amount, unit, ok := p.relative(i)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	switch unit {
+	case unitMinute:
+		return n, p.now.Add(time.Duration(amount) * time.Minute), true
+	case unitHour:
+		return n, p.now.Add(time.Duration(amount) * time.Hour), true
+	}
+	return 0, time.Time{}, false
+}
+
+type unit int
+
+const (
+	unitMinute unit = iota + 1
+	unitHour
+	unitDay
+	unitWeek
+	unitMonth
+	unitYear
+)
+
+// relative разбирает "через|in [N|a|an] <единица>"; без числа смещение равно 1.
+func (p *parser) relative(i int) (n, amount int, u unit, ok bool) {
+	if w := p.word(i); w != "через" && w != "in" {
+		return 0, 0, 0, false
+	}
+	n, amount = 1, 1
+	if v, err := strconv.Atoi(p.word(i + 1)); err == nil && v > 0 {
+		n, amount = 2, v
+	} else if w := p.word(i + 1); w == "a" || w == "an" {
+		n = 2
+	}
+	u, ok = parseUnit(p.word(i + n))
+	if !ok || amount > maxRelative[u] {
+		return 0, 0, 0, false
+	}
+	return n + 1, amount, u, true
+}
+
+// maxRelative — наибольшее смещение в каждой единице (около 100 лет); "через 100000000 лет" — не дата.
+var maxRelative = map[unit]int{
+	unitMinute: 100 * 366 * 24 * 60,
+	unitHour:   100 * 366 * 24,
+	unitDay:    100 * 366,
+	unitWeek:   100 * 53,
+	unitMonth:  100 * 12,
+	unitYear:   100,
+}
+
+// ---------- время ----------
+
+// clock разбирает время: "18:00", "в 18:00", "at 9am", "9 pm", "в 6 вечера", "в полдень".
+func (p *parser) clock(i int) (n, hour, minute int, ok bool) {
+	skip := 0
+	if w := p.word(i); w == "в" || w == "at" {
+		skip = 1
+	}
+	w := p.word(i + skip)
+
+	switch w {
+	case "полдень", "noon":
+		return skip + 1, 12, 0, true
+	case "полночь", "midnight":
+		return skip + 1, 0, 0, true
+	}
+
+	// 9am, 9:30pm, 9 am
+	for _, suffix := range []string{"am", "pm"} {
+		if num, found := strings.CutSuffix(w, suffix); found && num != "" {
+			if h, m, ok := parseHourMinute(num, true); ok {
+				return skip + 1, to24(h, suffix), m, true
+			}
+		}
+	}
+	h, m, isClock := parseHourMinute(w, false)
+	if !isClock {
+		return 0, 0, 0, false
+	}
+	next := p.word(i + skip + 1)
+	if (next == "am" || next == "pm") && h >= 1 && h <= 12 {
+		return skip + 2, to24(h, next), m, true
+	}
+	// "2 дня" без предлога — скорее количество ("2 дня рождения"), чем 14:00
+	if period, found := dayPeriods[next]; found && h >= 1 && h <= 12 && (next != "дня" || skip > 0) {
+		return skip + 2, period(h), m, true
+	}
+	// Одно число без двоеточия — время, только если перед ним стоит "в"/"at"
+	if strings.Contains(w, ":") || skip > 0 {
+		return skip + 1, h, m, true
+	}
+	return 0, 0, 0, false
+}
+
+// dayPeriods — "в 6 утра", "в 3 дня", "в 6 вечера", "в 2 ночи". "дня" — только после "в".
+var dayPeriods = map[string]func(h int) int{
+	"утра":   func(h int) int { return h % 12 },
+	"дня":    func(h int) int { return h%12 + 12 },
+	"вечера": func(h int) int { return h%12 + 12 },
+	"ночи": func(h int) int {
+		if h == 12 {
+			return 0
+		}
+		if h >= 6 {
+			return h + 12 // 11 ночи
+		}
+		return h
+	},
+}
+
+func to24(h int, suffix string) int {
+	h %= 12
+	if suffix == "pm" {
+		h += 12
+	}
+	return h
+}
+
+// parseHourMinute разбирает "18", "18:30": час от 0 до 23, а для am/pm — от 1 до 12.
+func parseHourMinute(s string, ampm bool) (int, int, bool) {
+	hs, ms, hasMinutes := strings.Cut(s, ":")
+	h, err := strconv.Atoi(hs)
+	if err != nil || len(hs) > 2 || h < 0 || h > 23 || ampm && (h < 1 || h > 12) {
+		return 0, 0, false
+	}
+	m := 0
+	if hasMinutes {
+		m, err = strconv.Atoi(ms)
+		if err != nil || len(ms) != 2 || m > 59 {
+			return 0, 0, false
+		}
+	}
+	return h, m, true
+}
+
+// ---------- словари ----------
+
+func parseDay(s string) (int, bool) {
+	s = strings.TrimSuffix(s, "-го")
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		s = strings.TrimSuffix(s, suffix)
+	}
+	d, err := strconv.Atoi(s)
+	if err != nil || d < 1 || d > 31 {
+		return 0, false
+	}
+	return d, true
+}
+
+func parseYear(s string) (int, bool) {
+	s = strings.TrimSuffix(s, "г.")
+	s = strings.TrimSuffix(s, "г")
+	if len(s) != 4 {
+		return 0, false
+	}
+	y, err := strconv.Atoi(s)
+	return y, err == nil && y >= 1900
+}
+
+// months — названия месяцев во всех употребимых формах и их сокращения.
+var months = map[string]time.Month{
+	"январь": time.January, "января": time.January, "январе": time.January, "янв": time.January,
+	"февраль": time.February, "февраля": time.February, "феврале": time.February, "фев": time.February,
+	"март": time.March, "марта": time.March, "марте": time.March, "мар": time.March,
+	"апрель": time.April, "апреля": time.April, "апреле": time.April, "апр": time.April,
+	"май": time.May, "мая": time.May, "мае": time.May,
+	"июнь": time.June, "июня": time.June, "июне": time.June, "июн": time.June,
+	"июль": time.July, "июля": time.July, "июле": time.July, "июл": time.July,
+	"август": time.August, "августа": time.August, "августе": time.August, "авг": time.August,
+	"сентябрь": time.September, "сентября": time.September, "сентябре": time.September, "сен": time.September, "сент": time.September,
+	"октябрь": time.October, "октября": time.October, "октябре": time.October, "окт": time.October,
+	"ноябрь": time.November, "ноября": time.November, "ноябре": time.November, "ноя": time.November, "нояб": time.November,
+	"декабрь": time.December, "декабря": time.December, "декабре": time.December, "дек": time.December,
+	"january": time.January, "jan": time.January, "february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March, "april": time.April, "apr": time.April, "may": time.May,
+	"june": time.June, "jun": time.June, "july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August, "september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October, "november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// abbreviations — сокращённые названия дней недели и месяцев. В начале даты они требуют предлога.
+var abbreviations = map[string]bool{
+	"пн": true, "вт": true, "ср": true, "чт": true, "пт": true, "сб": true, "вс": true,
+	"mon": true, "tue": true, "wed": true, "thu": true, "fri": true, "sat": true, "sun": true,
+	"янв": true, "фев": true, "мар": true, "апр": true, "июн": true, "июл": true, "авг": true,
+	"сен": true, "сент": true, "окт": true, "ноя": true, "нояб": true, "дек": true,
+	"jan": true, "feb": true, "mar": true, "apr": true, "jun": true, "jul": true, "aug": true,
+	"sep": true, "sept": true, "oct": true, "nov": true, "dec": true,
+}
+
+// parseMonth распознаёт месяц только по слову целиком: "марафон" — не март.
+func parseMonth(s string) (time.Month, bool) {
+	m, ok := months[strings.TrimSuffix(s, ".")]
+	return m, ok
+}
+
+var weekdays = map[string]time.Weekday{
+	"понедельник": time.Monday, "пн": time.Monday, "monday": time.Monday, "mon": time.Monday,
+	"вторник": time.Tuesday, "вт": time.Tuesday, "tuesday": time.Tuesday, "tue": time.Tuesday,
+	"среда": time.Wednesday, "среду": time.Wednesday, "ср": time.Wednesday, "wednesday": time.Wednesday, "wed": time.Wednesday,
+	"четверг": time.Thursday, "чт": time.Thursday, "thursday": time.Thursday, "thu": time.Thursday,
+	"пятница": time.Friday, "пятницу": time.Friday, "пт": time.Friday, "friday": time.Friday, "fri": time.Friday,
+	"суббота": time.Saturday, "субботу": time.Saturday, "сб": time.Saturday, "saturday": time.Saturday, "sat": time.Saturday,
+	"воскресенье": time.Sunday, "вс": time.Sunday, "sunday": time.Sunday, "sun": time.Sunday,
+}
+
+// Weekday распознаёт название дня недели по-русски или по-английски: "пн", "Monday", "среду".
+func Weekday(s string) (time.Weekday, bool) {
+	return parseWeekday(strings.ToLower(s))
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	wd, ok := weekdays[s]
+	return wd, ok
+}
+
+func parseUnit(s string) (unit, bool) {
+	switch {
+	case s == "мин" || strings.HasPrefix(s, "минут") || s == "min" || s == "mins" || strings.HasPrefix(s, "minute"):
+		return unitMinute, true
+	case strings.HasPrefix(s, "час") || s == "h" || s == "hour" || s == "hours":
+		return unitHour, true
+	case s == "день" || s == "дня" || s == "дней" || s == "day" || s == "days":
+		return unitDay, true
+	case strings.HasPrefix(s, "недел") || s == "week" || s == "weeks":
+		return unitWeek, true
+	case strings.HasPrefix(s, "месяц") || s == "month" || s == "months":
+		return unitMonth, true
+	case s == "год" || s == "года" || s == "лет" || s == "year" || s == "years":
+		return unitYear, true
+	}
+	return 0, false
+}
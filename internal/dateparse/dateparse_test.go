@@ -0,0 +1,100 @@
+package dateparse
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// now — среда, 14 октября 2026, 12:00.
+var now = time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC)
+
+func at(month time.Month, day, hour, minute int) time.Time {
+	year := 2026
+	if month < time.October {
+		year = 2027
+	}
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestPrefix(t *testing.T) {
+	tests := []struct {
+		text  string
+		want  time.Time
+		words int
+	}{
+		{"завтра в 18:00", at(time.October, 15, 18, 0), 3},
+		{"через 3 дня", at(time.October, 17, 0, 0), 3},
+		{"в пятницу", at(time.October, 16, 0, 0), 2},
+		{"31 декабря", at(time.December, 31, 0, 0), 2},
+		{"next monday 9am", at(time.October, 19, 9, 0), 3},
+		{"in 2 weeks", at(time.October, 28, 0, 0), 3},
+		{"через 2 часа", at(time.October, 14, 14, 0), 3},
+		{"in an hour", at(time.October, 14, 13, 0), 3},
+		{"через 100 лет", time.Date(2126, time.October, 14, 0, 0, 0, 0, time.UTC), 3},
+		{"в 3 дня", at(time.October, 14, 15, 0), 3},
+		{"завтра в 6 вечера", at(time.October, 15, 18, 0), 4},
+		{"10:00", at(time.October, 15, 10, 0), 1},
+		{"2026-12-31 23:59", at(time.December, 31, 23, 59), 2},
+		{"31.12", at(time.December, 31, 0, 0), 1},
+		{"5 марта 2027 г.", at(time.March, 5, 0, 0), 4},
+		{"March 5", at(time.March, 5, 0, 0), 2},
+		{"5 mar", at(time.March, 5, 0, 0), 2},
+		{"on Mar 5", at(time.March, 5, 0, 0), 3},
+		{"on Sun", at(time.October, 18, 0, 0), 2},
+		{"в пт в 19:00", at(time.October, 16, 19, 0), 4},
+		{"Sunday", at(time.October, 18, 0, 0), 1},
+		{"завтра Встреча команды", at(time.October, 15, 0, 0), 1},
+		{"в пятницу, 18:00 Ужин", at(time.October, 16, 18, 0), 3},
+	}
+	for _, tt := range tests {
+		res, ok := Prefix(strings.Fields(tt.text), now)
+		if !ok {
+			t.Errorf("Prefix(%q): not recognized", tt.text)
+			continue
+		}
+		if !res.Time.Equal(tt.want) || res.Words != tt.words {
+			t.Errorf("Prefix(%q) = %v, %d words; want %v, %d words", tt.text, res.Time, res.Words, tt.want, tt.words)
+		}
+	}
+}
+
+func TestPrefixNotDate(t *testing.T) {
+	tests := []string{
+		"Sun party",
+		"Sunrise hike",
+		"Mar 5 tour",
+		"Марафон 5 км",
+		"Market day",
+		"вс встреча",
+		"2 дня рождения",
+		"3 дня рыбалки",
+		"через 100000000 лет",
+		"через 101 год",
+		"in 99999999999 minutes",
+		"Встреча",
+		"",
+	}
+	for _, text := range tests {
+		if res, ok := Prefix(strings.Fields(text), now); ok {
+			t.Errorf("Prefix(%q) = %v (%d words), want no date", text, res.Time, res.Words)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse("завтра в 18:00", now); err != nil {
+		t.Errorf("Parse: %v", err)
+	}
+	if _, err := Parse("завтра в 18:00 Встреча", now); err == nil {
+		t.Error("Parse accepted trailing words")
+	}
+}
+
+func TestWeekday(t *testing.T) {
+	for text, want := range map[string]time.Weekday{"пн": time.Monday, "Friday": time.Friday, "среду": time.Wednesday, "sun": time.Sunday} {
+		if got, ok := Weekday(text); !ok || got != want {
+			t.Errorf("Weekday(%q) = %v, %v; want %v", text, got, ok, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package eventname приводит названия событий к виду, пригодному для команд Telegram.
+//
+// Имя команды Telegram — от 1 до 32 символов из строчных латинских букв, цифр и "_".
+// Название события, введённое пользователем («Новый-Год», «Day_Off», «др_мамы»),
+// сохраняется как заголовок, а команда (slug) получается из него транслитерацией
+// кириллицы, приведением к нижнему регистру и заменой остальных символов на "_".
+package eventname
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// MaxLen — максимальная длина имени команды Telegram.
+const MaxLen = 32
+
+var (
+	// ErrEmpty — из названия не получилось ни одного допустимого символа (например, одни эмодзи).
+	ErrEmpty = errors.New("event name has no latin letters, digits or cyrillic")
+	// ErrReserved — название совпадает с системной командой бота.
+	ErrReserved = errors.New("event name is reserved for a bot command")
+)
+
+// reserved — системные команды бота. Событие с таким именем было бы недоступно как /<name>,
+// поэтому список нужно пополнять вместе с регистрацией новых команд в main.
+var reserved = map[string]bool{
+	"start": true, "help": true, "cancel": true,
+	"set_date": true, "list": true, "all": true, "active": true, "outdated": true,
+	"remind": true, "repeat": true, "timezone": true, "edit": true, "delete": true,
+	"pin": true, "unpin": true, "digest": true, "publish": true, "subscribe": true, "unsubscribe": true,
+	"admin_stats": true, "admin_chats": true, "admin_broadcast": true, "admin_purge_chat": true,
+}
+
+// IsReserved сообщает, является ли имя системной командой бота.
+func IsReserved(name string) bool {
+	return reserved[name]
+}
+
+// translit — транслитерация кириллицы (русский и украинский алфавиты) в латиницу.
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+// Slug возвращает имя команды для названия события: транслитерация, нижний регистр,
+// любые другие символы заменяются одним "_", крайние "_" отбрасываются, длина — не больше MaxLen.
+// Для названия без допустимых символов возвращает пустую строку.
+func Slug(title string) string {
+	var sb strings.Builder
+	pendingSep := false
+	write := func(s string) {
+		if s == "" {
+			return
+		}
+		if pendingSep && sb.Len() > 0 {
+			sb.WriteByte('_')
+		}
+		pendingSep = false
+		sb.WriteString(s)
+	}
+
+	for _, r := range strings.TrimPrefix(strings.TrimSpace(title), "/") {
+		r = unicode.ToLower(r)
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			write(string(r))
+		default:
+			if t, ok := translit[r]; ok {
+				write(t)
+			} else {
+				// Пробелы, дефисы, "_", знаки и эмодзи разделяют слова
+				pendingSep = true
+			}
+		}
+	}
+
+	slug := sb.String()
+	if len(slug) > MaxLen {
+		slug = strings.TrimRight(slug[:MaxLen], "_")
+	}
+	return slug
+}
+
+// Normalize возвращает имя команды для названия события или ошибку,
+// если имя получилось пустым или совпадает с системной командой.
+func Normalize(title string) (string, error) {
+	slug := Slug(title)
+	if slug == "" {
+		return "", ErrEmpty
+	}
+	if IsReserved(slug) {
+		return "", ErrReserved
+	}
+	return slug, nil
+}
+
+// Valid сообщает, подходит ли имя для команды Telegram.
+func Valid(name string) bool {
+	if name == "" || len(name) > MaxLen {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
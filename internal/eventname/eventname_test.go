@@ -0,0 +1,100 @@
+package eventname
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		title, want string
+	}{
+		{"birthday", "birthday"},
+		{"Новый-Год", "novyy_god"},
+		{"др_мамы", "dr_mamy"},
+		{"Щука и Ёж", "shchuka_i_ezh"},
+		{"Подъезд", "podezd"},
+		{"Їжак Ґанок", "yizhak_ganok"},
+		{"Day_Off", "day_off"},
+		{"NEW-YEAR-2027", "new_year_2027"},
+		{"/Party", "party"},
+		{"a___b", "a_b"},
+		{"a - _ - b", "a_b"},
+		{"__trip__", "trip"},
+		{"🎉 Party 🎉", "party"},
+		{"🎉🎂", ""},
+		{"!!! ???", ""},
+		{"", ""},
+		{strings.Repeat("a", 40), strings.Repeat("a", MaxLen)},
+		// Обрезка по MaxLen не оставляет "_" в конце
+		{strings.Repeat("a", 31) + " b", strings.Repeat("a", 31)},
+		// Транслитерация считается в байтах результата
+		{strings.Repeat("щ", 10), strings.Repeat("shch", 8)},
+	}
+	for _, tt := range tests {
+		got := Slug(tt.title)
+		if got != tt.want {
+			t.Errorf("Slug(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+		if got != "" && !Valid(got) {
+			t.Errorf("Slug(%q) = %q is not a valid command name", tt.title, got)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		title   string
+		want    string
+		wantErr error
+	}{
+		{"День рождения", "den_rozhdeniya", nil},
+		{"Start-Up", "start_up", nil},
+		{"Help", "", ErrReserved},
+		{"/LIST", "", ErrReserved},
+		{"set-date", "", ErrReserved},
+		{"🎉🎂", "", ErrEmpty},
+		{"!!! ???", "", ErrEmpty},
+		{"   ", "", ErrEmpty},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.title)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("Normalize(%q) = %q, %v; want %q, %v", tt.title, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizeRejectsEveryReservedCommand(t *testing.T) {
+	for name := range reserved {
+		if _, err := Normalize(name); !errors.Is(err, ErrReserved) {
+			t.Errorf("Normalize(%q) error = %v, want ErrReserved", name, err)
+		}
+		if _, err := Normalize("/" + strings.ToUpper(name)); !errors.Is(err, ErrReserved) {
+			t.Errorf("Normalize(%q) error = %v, want ErrReserved", "/"+strings.ToUpper(name), err)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"party", true},
+		{"new_year_2027", true},
+		{strings.Repeat("a", MaxLen), true},
+		{"", false},
+		{strings.Repeat("a", MaxLen+1), false},
+		{"Party", false},
+		{"new-year", false},
+		{"др", false},
+		{"a b", false},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.name); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Package recurrence реализует подмножество правил повторения RFC 5545 (RRULE):
+// FREQ=DAILY|WEEKLY|MONTHLY|YEARLY, INTERVAL, COUNT, UNTIL и BYDAY (только для WEEKLY).
+//
+// Как и в RFC 5545, несуществующие даты пропускаются: ежемесячное событие 31-го числа
+// не наступает в месяцах короче 31 дня, ежегодное 29 февраля — в невисокосные годы.
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Freq — частота повторения.
+type Freq string
+
+const (
+	Daily   Freq = "DAILY"
+	Weekly  Freq = "WEEKLY"
+	Monthly Freq = "MONTHLY"
+	Yearly  Freq = "YEARLY"
+)
+
+// maxIterations ограничивает перебор вхождений, чтобы некорректное правило не зациклило бота.
+const maxIterations = 100000
+
+// Rule — разобранное правило повторения.
+type Rule struct {
+	Freq     Freq
+	Interval int            // >= 1
+	Count    int            // 0 — без ограничения
+	Until    time.Time      // нулевое значение — без ограничения
+	ByDay    []time.Weekday // только для WEEKLY; пусто — день недели начальной даты
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// shortcuts — короткие записи правил, которые удобно набирать в чате.
+var shortcuts = map[string]Freq{
+	"daily": Daily, "ежедневно": Daily,
+	"weekly": Weekly, "еженедельно": Weekly,
+	"monthly": Monthly, "ежемесячно": Monthly,
+	"yearly": Yearly, "annually": Yearly, "ежегодно": Yearly,
+}
+
+// Parse разбирает правило повторения. Поддерживаются:
+//   - короткие записи: yearly, monthly, weekly, daily (и русские ежегодно, ежемесячно, ...);
+//   - "каждые N дней/недель": 3d, 2w;
+//   - RRULE: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE" (с префиксом "RRULE:" или без).
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty recurrence rule")
+	}
+
+	lower := strings.ToLower(s)
+	if f, ok := shortcuts[lower]; ok {
+		return &Rule{Freq: f, Interval: 1}, nil
+	}
+	if n, unit, ok := splitEvery(lower); ok {
+		switch unit {
+		case "d":
+			return &Rule{Freq: Daily, Interval: n}, nil
+		case "w":
+			return &Rule{Freq: Weekly, Interval: n}, nil
+		}
+	}
+
+	return parseRRule(s)
+}
+
+// splitEvery разбирает запись вида "3d" или "2w".
+func splitEvery(s string) (int, string, bool) {
+	if len(s) < 2 {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 1 {
+		return 0, "", false
+	}
+	return n, s[len(s)-1:], true
+}
+
+func parseRRule(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(s), "RRULE:")
+	r := &Rule{Interval: 1}
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		switch key {
+		case "FREQ":
+			switch f := Freq(value); f {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = f
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = t
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				wd, ok := weekdayCodes[code]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY value %q", code)
+				}
+				if !containsWeekday(r.ByDay, wd) {
+					r.ByDay = append(r.ByDay, wd)
+				}
+			}
+		case "WKST":
+			if value != "MO" {
+				return nil, fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %q", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("RRULE without FREQ")
+	}
+	if len(r.ByDay) > 0 && r.Freq != Weekly {
+		return nil, fmt.Errorf("BYDAY is supported only with FREQ=WEEKLY")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("COUNT and UNTIL must not be used together")
+	}
+	return r, nil
+}
+
+func parseUntil(v string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			if layout == "20060102" {
+				// Дата без времени включает весь день
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", v)
+}
+
+// String возвращает правило в канонической форме RRULE (без префикса "RRULE:").
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, wd := range r.sortedByDay() {
+			for code, d := range weekdayCodes {
+				if d == wd {
+					codes = append(codes, code)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next возвращает первое вхождение правила, начинающегося в start, не раньше after.
+// Если вхождения закончились (COUNT/UNTIL), возвращает последнее вхождение и false.
+func (r *Rule) Next(start, after time.Time) (time.Time, bool) {
+	var last time.Time
+	found := false
+	r.each(start, func(t time.Time) bool {
+		if !t.Before(after) {
+			last, found = t, true
+			return false
+		}
+		last = t
+		return true
+	})
+	return last, found
+}
+
+// each перебирает вхождения по возрастанию, пока fn возвращает true.
+func (r *Rule) each(start time.Time, fn func(time.Time) bool) {
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		if r.Count > 0 && emitted >= r.Count {
+			return false
+		}
+		emitted++
+		return fn(t)
+	}
+
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	if r.Freq == Weekly && len(r.ByDay) > 0 {
+		// Неделя начинается с понедельника (WKST=MO)
+		weekStart := start.AddDate(0, 0, -int((start.Weekday()+6)%7))
+		days := r.sortedByDay()
+		for w := 0; w < maxIterations; w += interval {
+			for _, wd := range days {
+				t := weekStart.AddDate(0, 0, w*7+int((wd+6)%7))
+				if t.Before(start) {
+					continue
+				}
+				if !emit(t) {
+					return
+				}
+			}
+		}
+		return
+	}
+
+	for k := 0; k < maxIterations; k++ {
+		n := k * interval
+		var t time.Time
+		switch r.Freq {
+		case Daily:
+			t = start.AddDate(0, 0, n)
+		case Weekly:
+			t = start.AddDate(0, 0, 7*n)
+		case Monthly:
+			t = start.AddDate(0, n, 0)
+			if t.Day() != start.Day() {
+				continue // в месяце нет такого числа
+			}
+		case Yearly:
+			t = start.AddDate(n, 0, 0)
+			if t.Day() != start.Day() {
+				continue // 29 февраля в невисокосный год
+			}
+		default:
+			return
+		}
+		if !emit(t) {
+			return
+		}
+	}
+}
+
+func (r *Rule) sortedByDay() []time.Weekday {
+	days := append([]time.Weekday(nil), r.ByDay...)
+	sort.Slice(days, func(i, j int) bool { return (days[i]+6)%7 < (days[j]+6)%7 })
+	return days
+}
+
+func containsWeekday(days []time.Weekday, wd time.Weekday) bool {
+	for _, d := range days {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
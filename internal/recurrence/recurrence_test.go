@@ -0,0 +1,208 @@
+package recurrence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 10, 0, 0, 0, time.UTC)
+}
+
+// first возвращает не больше n первых вхождений правила.
+func first(r *Rule, start time.Time, n int) []time.Time {
+	var got []time.Time
+	r.each(start, func(t time.Time) bool {
+		got = append(got, t)
+		return len(got) < n
+	})
+	return got
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rule
+	}{
+		{"yearly", Rule{Freq: Yearly, Interval: 1}},
+		{"Ежемесячно", Rule{Freq: Monthly, Interval: 1}},
+		{"3d", Rule{Freq: Daily, Interval: 3}},
+		{"2w", Rule{Freq: Weekly, Interval: 2}},
+		{"RRULE:FREQ=DAILY;COUNT=5", Rule{Freq: Daily, Interval: 1, Count: 5}},
+		{"freq=weekly;interval=2;byday=we,mo,we", Rule{Freq: Weekly, Interval: 2, ByDay: []time.Weekday{time.Wednesday, time.Monday}}},
+		{"FREQ=MONTHLY;UNTIL=20261231", Rule{Freq: Monthly, Interval: 1, Until: time.Date(2026, time.December, 31, 23, 59, 59, 0, time.UTC)}},
+		{"FREQ=YEARLY;UNTIL=20300101T000000Z;WKST=MO", Rule{Freq: Yearly, Interval: 1, Until: time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, *got, tt.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"hourly",
+		"0d",
+		"3m",
+		"FREQ=HOURLY",
+		"INTERVAL=2",
+		"FREQ",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;COUNT=2;UNTIL=20261231",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;WKST=SU",
+		"FREQ=MONTHLY;BYMONTHDAY=1",
+	} {
+		if r, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %v, want error", in, r)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"daily", "FREQ=DAILY"},
+		{"3d", "FREQ=DAILY;INTERVAL=3"},
+		{"2w", "FREQ=WEEKLY;INTERVAL=2"},
+		{"rrule:freq=daily;count=5", "FREQ=DAILY;COUNT=5"},
+		{"FREQ=WEEKLY;BYDAY=SU,WE,MO", "FREQ=WEEKLY;BYDAY=MO,WE,SU"},
+		{"FREQ=MONTHLY;UNTIL=20261231", "FREQ=MONTHLY;UNTIL=20261231T235959Z"},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=FR", "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=FR"},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		got := r.String()
+		if got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+
+		// Каноническая форма разбирается в то же правило
+		again, err := Parse(got)
+		if err != nil {
+			t.Errorf("Parse(%q) of canonical form: %v", got, err)
+			continue
+		}
+		if again.String() != got {
+			t.Errorf("round-trip of %q = %q", got, again.String())
+		}
+	}
+}
+
+func TestEach(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "monthly on the 31st skips short months",
+			rule:  "monthly",
+			start: date(2026, time.January, 31),
+			want: []time.Time{
+				date(2026, time.January, 31), date(2026, time.March, 31), date(2026, time.May, 31),
+				date(2026, time.July, 31), date(2026, time.August, 31),
+			},
+		},
+		{
+			name:  "yearly on Feb 29 only in leap years",
+			rule:  "yearly",
+			start: date(2024, time.February, 29),
+			want:  []time.Time{date(2024, time.February, 29), date(2028, time.February, 29), date(2032, time.February, 29)},
+		},
+		{
+			name:  "every other week on Monday and Wednesday",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+			start: date(2026, time.October, 14), // среда: понедельник той же недели уже прошёл
+			want: []time.Time{
+				date(2026, time.October, 14), date(2026, time.October, 26), date(2026, time.October, 28),
+				date(2026, time.November, 9), date(2026, time.November, 11),
+			},
+		},
+		{
+			name:  "every 3 days",
+			rule:  "3d",
+			start: date(2026, time.October, 30),
+			want:  []time.Time{date(2026, time.October, 30), date(2026, time.November, 2), date(2026, time.November, 5)},
+		},
+		{
+			name:  "every 2 weeks",
+			rule:  "2w",
+			start: date(2026, time.December, 24),
+			want:  []time.Time{date(2026, time.December, 24), date(2027, time.January, 7), date(2027, time.January, 21)},
+		},
+		{
+			name:  "COUNT limits occurrences",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: date(2026, time.October, 14),
+			want:  []time.Time{date(2026, time.October, 14), date(2026, time.October, 15), date(2026, time.October, 16)},
+		},
+		{
+			name:  "UNTIL date includes the whole day",
+			rule:  "FREQ=WEEKLY;UNTIL=20261028",
+			start: date(2026, time.October, 14),
+			want:  []time.Time{date(2026, time.October, 14), date(2026, time.October, 21), date(2026, time.October, 28)},
+		},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: Parse(%q): %v", tt.name, tt.rule, err)
+		}
+		// Правила без COUNT и UNTIL бесконечны: сравниваем начало, ограниченные — целиком
+		n := len(tt.want)
+		if r.Count > 0 || !r.Until.IsZero() {
+			n = len(tt.want) + 1
+		}
+		if got := first(r, tt.start, n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	start := date(2026, time.October, 14)
+	tests := []struct {
+		rule   string
+		after  time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{"daily", start.Add(-time.Hour), start, true},
+		{"daily", start, start, true},
+		{"daily", start.Add(time.Second), date(2026, time.October, 15), true},
+		{"monthly", date(2026, time.October, 14).AddDate(0, 1, 1), date(2026, time.December, 14), true},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", date(2026, time.October, 15), date(2026, time.October, 26), true},
+		// COUNT исчерпан: последнее вхождение и false
+		{"FREQ=DAILY;COUNT=3", date(2026, time.October, 17), date(2026, time.October, 16), false},
+		// UNTIL исчерпан: последнее вхождение и false
+		{"FREQ=WEEKLY;UNTIL=20261028", date(2026, time.October, 29), date(2026, time.October, 28), false},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.rule, err)
+		}
+		got, ok := r.Next(start, tt.after)
+		if !got.Equal(tt.want) || ok != tt.wantOK {
+			t.Errorf("%s Next(after %v) = %v, %v; want %v, %v", tt.rule, tt.after, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------- Admin ----------
+
+// Stats — сводка по всем чатам для администратора бота (/admin_stats).
+type Stats struct {
+	Chats         int // чаты с событиями или настройками
+	Users         int // пользователи, создававшие события
+	Events        int
+	ActiveEvents  int
+	RemindersSent int
+	DigestsSent   int
+}
+
+// ChatStats — число событий одного чата (/admin_chats).
+type ChatStats struct {
+	ChatID       int64
+	Events       int
+	ActiveEvents int
+}
+
+// knownChatsQuery — чаты, о которых знает бот: с событиями или с сохранёнными настройками.
+const knownChatsQuery = `SELECT chat_id FROM events UNION SELECT chat_id FROM chat_settings`
+
+// statsQuery одинаков для PostgreSQL и SQLite.
+const statsQuery = `SELECT
+	(SELECT count(*) FROM (` + knownChatsQuery + `) c),
+	(SELECT count(DISTINCT user_id) FROM user_events),
+	(SELECT count(*) FROM events),
+	(SELECT count(*) FROM events WHERE status = 'active'),
+	(SELECT count(*) FROM sent_reminders),
+	(SELECT count(*) FROM sent_digests)`
+
+// chatStatsQuery — страница чатов, от чатов с наибольшим числом событий. Параметры: limit, offset.
+const chatStatsQuery = `SELECT c.chat_id, count(e.id),
+	COALESCE(SUM(CASE WHEN e.status = 'active' THEN 1 ELSE 0 END), 0)
+	FROM (` + knownChatsQuery + `) c LEFT JOIN events e ON e.chat_id = c.chat_id
+	GROUP BY c.chat_id ORDER BY count(e.id) DESC, c.chat_id`
+
+// Stats возвращает сводку по всем чатам.
+func (s *PostgresStorage) Stats(ctx context.Context) (*Stats, error) {
+	var st Stats
+	err := s.pool.QueryRow(ctx, statsQuery).Scan(
+		&st.Chats, &st.Users, &st.Events, &st.ActiveEvents, &st.RemindersSent, &st.DigestsSent,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// ListChatStats возвращает страницу чатов с числом событий и общее число чатов.
+func (s *PostgresStorage) ListChatStats(ctx context.Context, offset, limit int) ([]ChatStats, int, error) {
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT count(*) FROM (`+knownChatsQuery+`) c`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.pool.Query(ctx, chatStatsQuery+` LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	chats, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ChatStats, error) {
+		var cs ChatStats
+		err := row.Scan(&cs.ChatID, &cs.Events, &cs.ActiveEvents)
+		return cs, err
+	})
+	return chats, total, err
+}
+
+// ListKnownChats возвращает все чаты с событиями или настройками (получатели рассылки).
+func (s *PostgresStorage) ListKnownChats(ctx context.Context) ([]int64, error) {
+	rows, err := s.pool.Query(ctx, knownChatsQuery+` ORDER BY chat_id`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// PurgeChat удаляет все данные чата: события (вместе с напоминаниями, привязками и отсчётами),
+// настройки, дайджесты, сессии, подборку и подписки. Возвращает число удалённых событий.
+func (s *PostgresStorage) PurgeChat(ctx context.Context, chatID int64) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM events WHERE chat_id = $1`, chatID)
+	if err != nil {
+		return 0, err
+	}
+	for _, q := range []string{
+		`DELETE FROM user_events WHERE chat_id = $1`,
+		`DELETE FROM chat_settings WHERE chat_id = $1`,
+		`DELETE FROM sent_digests WHERE chat_id = $1`,
+		`DELETE FROM sessions WHERE chat_id = $1`,
+		`DELETE FROM collections WHERE chat_id = $1`,
+		`DELETE FROM collection_subscriptions WHERE chat_id = $1`,
+	} {
+		if _, err := tx.Exec(ctx, q, chatID); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------- Chat settings ----------
+
+// ChatSettings — настройки чата. Пустые поля означают значения по умолчанию из конфигурации.
+type ChatSettings struct {
+	ChatID   int64
+	Timezone string // IANA-имя часового пояса, например "Europe/Moscow"
+	Digest   Digest
+}
+
+// Расписания дайджеста предстоящих событий.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Digest — расписание дайджеста предстоящих событий чата (/digest).
+type Digest struct {
+	Schedule string       // DigestDaily, DigestWeekly; пусто — дайджест выключен
+	Weekday  time.Weekday // день отправки еженедельного дайджеста
+	Minute   int          // время отправки: минуты от полуночи по времени чата
+	Days     int          // на сколько дней вперёд показывать события
+}
+
+// chatSettingsColumns — колонки chat_settings в порядке полей, которые сканируют бэкенды.
+const chatSettingsColumns = `chat_id, timezone, digest, digest_weekday, digest_minute, digest_days`
+
+// GetChatSettings возвращает настройки чата. Если чат ничего не настраивал,
+// возвращаются пустые настройки без ошибки.
+func (s *PostgresStorage) GetChatSettings(ctx context.Context, chatID int64) (*ChatSettings, error) {
+	cs, err := scanPostgresChatSettings(s.pool.QueryRow(ctx,
+		`SELECT `+chatSettingsColumns+` FROM chat_settings WHERE chat_id = $1`,
+		chatID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &ChatSettings{ChatID: chatID}, nil
+	}
+	return cs, err
+}
+
+func scanPostgresChatSettings(row pgx.Row) (*ChatSettings, error) {
+	var (
+		cs      ChatSettings
+		weekday int16
+	)
+	err := row.Scan(&cs.ChatID, &cs.Timezone, &cs.Digest.Schedule, &weekday, &cs.Digest.Minute, &cs.Digest.Days)
+	if err != nil {
+		return nil, err
+	}
+	cs.Digest.Weekday = time.Weekday(weekday)
+	return &cs, nil
+}
+
+// SetChatTimezone сохраняет часовой пояс чата. Пустая строка возвращает пояс по умолчанию.
+func (s *PostgresStorage) SetChatTimezone(ctx context.Context, chatID int64, timezone string) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO chat_settings (chat_id, timezone) VALUES ($1, $2)
+		 ON CONFLICT (chat_id) DO UPDATE SET timezone = EXCLUDED.timezone, updated_at = now()`,
+		chatID, timezone,
+	)
+	return err
+}
+
+// SetChatDigest сохраняет расписание дайджеста чата. Пустой Schedule выключает дайджест.
+func (s *PostgresStorage) SetChatDigest(ctx context.Context, chatID int64, d Digest) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO chat_settings (chat_id, digest, digest_weekday, digest_minute, digest_days) VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (chat_id) DO UPDATE SET digest = EXCLUDED.digest, digest_weekday = EXCLUDED.digest_weekday,
+		 digest_minute = EXCLUDED.digest_minute, digest_days = EXCLUDED.digest_days, updated_at = now()`,
+		chatID, d.Schedule, int16(d.Weekday), d.Minute, d.Days,
+	)
+	return err
+}
+
+// ListDigestChats возвращает настройки чатов с включённым дайджестом.
+func (s *PostgresStorage) ListDigestChats(ctx context.Context) ([]ChatSettings, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+chatSettingsColumns+` FROM chat_settings WHERE digest <> '' ORDER BY chat_id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []ChatSettings
+	for rows.Next() {
+		cs, err := scanPostgresChatSettings(rows)
+		if err != nil {
+			return nil, err
+		}
+		chats = append(chats, *cs)
+	}
+	return chats, rows.Err()
+}
+
+// ClaimDigest атомарно помечает выпуск дайджеста (момент отправки slot) как отправленный.
+// Возвращает false, если он уже был отмечен — тогда отправлять не нужно.
+func (s *PostgresStorage) ClaimDigest(ctx context.Context, chatID int64, slot time.Time) (bool, error) {
+	tag, err := s.pool.Exec(ctx,
+		`INSERT INTO sent_digests (chat_id, slot) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		chatID, slot,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseDigest снимает отметку об отправке, если доставить дайджест не удалось.
+func (s *PostgresStorage) ReleaseDigest(ctx context.Context, chatID int64, slot time.Time) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM sent_digests WHERE chat_id = $1 AND slot = $2`, chatID, slot)
+	return err
+}
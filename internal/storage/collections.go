@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------- Collections ----------
+
+// Collection — опубликованная подборка событий чата (/publish). У чата не больше одной подборки,
+// в неё входят все его события.
+type Collection struct {
+	ChatID int64
+	Code   string // код для /subscribe
+	Title  string // название группы на момент публикации, для личного чата пусто
+}
+
+// CreateCollection публикует подборку чата c.ChatID. Возвращает false, если у чата уже есть подборка
+// (она не меняется), и ErrCodeTaken, если код занят подборкой другого чата.
+func (s *PostgresStorage) CreateCollection(ctx context.Context, c *Collection) (bool, error) {
+	tag, err := s.pool.Exec(ctx,
+		`INSERT INTO collections (chat_id, code, title) VALUES ($1, $2, $3) ON CONFLICT (chat_id) DO NOTHING`,
+		c.ChatID, c.Code, c.Title,
+	)
+	if isUniqueViolation(err) {
+		return false, ErrCodeTaken
+	}
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetCollection возвращает подборку чата или ErrNotFound.
+func (s *PostgresStorage) GetCollection(ctx context.Context, chatID int64) (*Collection, error) {
+	return s.getCollection(ctx, `SELECT chat_id, code, title FROM collections WHERE chat_id = $1`, chatID)
+}
+
+// GetCollectionByCode возвращает подборку по коду или ErrNotFound.
+func (s *PostgresStorage) GetCollectionByCode(ctx context.Context, code string) (*Collection, error) {
+	return s.getCollection(ctx, `SELECT chat_id, code, title FROM collections WHERE code = $1`, code)
+}
+
+func (s *PostgresStorage) getCollection(ctx context.Context, query string, arg any) (*Collection, error) {
+	var c Collection
+	err := s.pool.QueryRow(ctx, query, arg).Scan(&c.ChatID, &c.Code, &c.Title)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// DeleteCollection снимает подборку чата с публикации вместе со всеми подписками на неё.
+// Возвращает false, если подборки не было.
+func (s *PostgresStorage) DeleteCollection(ctx context.Context, chatID int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM collections WHERE chat_id = $1`, chatID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Subscribe подписывает чат chatID на подборку чата sourceChatID. Возвращает false, если подписка
+// уже была, и ErrNotFound, если подборки нет.
+func (s *PostgresStorage) Subscribe(ctx context.Context, chatID, sourceChatID int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx,
+		`INSERT INTO collection_subscriptions (chat_id, source_chat_id)
+		 SELECT $1, chat_id FROM collections WHERE chat_id = $2
+		 ON CONFLICT (chat_id, source_chat_id) DO NOTHING`,
+		chatID, sourceChatID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() > 0 {
+		return true, nil
+	}
+	if _, err := s.GetCollection(ctx, sourceChatID); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// Unsubscribe отменяет подписку чата chatID на подборку чата sourceChatID.
+// Возвращает false, если подписки не было.
+func (s *PostgresStorage) Unsubscribe(ctx context.Context, chatID, sourceChatID int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx,
+		`DELETE FROM collection_subscriptions WHERE chat_id = $1 AND source_chat_id = $2`,
+		chatID, sourceChatID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListSubscriptions возвращает подборки, на которые подписан чат, в порядке подписки.
+func (s *PostgresStorage) ListSubscriptions(ctx context.Context, chatID int64) ([]Collection, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT c.chat_id, c.code, c.title FROM collection_subscriptions cs
+		 JOIN collections c ON c.chat_id = cs.source_chat_id
+		 WHERE cs.chat_id = $1 ORDER BY cs.created_at, c.chat_id`,
+		chatID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Collection, error) {
+		var c Collection
+		err := row.Scan(&c.ChatID, &c.Code, &c.Title)
+		return c, err
+	})
+}
+
+// FindSubscribedEvent ищет событие по имени в подборках, на которые подписан чат.
+// Из нескольких совпадений возвращается созданное раньше всех.
+func (s *PostgresStorage) FindSubscribedEvent(ctx context.Context, chatID int64, name string) (*Event, error) {
+	return scanEvent(s.pool.QueryRow(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE name = $2
+		 AND chat_id IN (SELECT source_chat_id FROM collection_subscriptions WHERE chat_id = $1)
+		 ORDER BY id LIMIT 1`,
+		chatID, name,
+	))
+}
@@ -0,0 +1,878 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStorage — потокобезопасная реализация Store в памяти процесса.
+// Повторяет семантику PostgresStorage (включая UNIQUE(chat_id, name) и каскадное удаление),
+// но не переживает перезапуск. Подходит для разработки и локальной отладки (DATABASE_URL=memory://).
+type MemoryStorage struct {
+	mu sync.RWMutex
+
+	nextID int64
+	events map[int64]*Event
+	// userEvents — привязки событий к пользователям: event_id → множество (chat_id, user_id)
+	userEvents     map[int64]map[userEventKey]struct{}
+	sentReminders  map[reminderKey]struct{}
+	eventReminders map[int64]map[time.Duration]struct{}
+	chatSettings   map[int64]ChatSettings
+	sessions       map[sessionKey]Session
+	pins           map[int64]Pin // event_id → закреплённое сообщение
+	sentDigests    map[digestKey]struct{}
+	collections    map[int64]Collection // chat_id → опубликованная подборка
+	// subscriptions — подписки на подборки: chat_id → chat_id подборок в порядке подписки
+	subscriptions map[int64][]int64
+}
+
+type sessionKey struct {
+	chatID, userID int64
+	kind           string
+}
+
+type digestKey struct {
+	chatID int64
+	slot   int64 // микросекунды Unix — точность TIMESTAMPTZ
+}
+
+type userEventKey struct {
+	chatID, userID int64
+}
+
+type reminderKey struct {
+	eventID   int64
+	eventDate int64 // микросекунды Unix — точность TIMESTAMPTZ
+	offset    time.Duration
+}
+
+// NewMemoryStorage создаёт пустое хранилище в памяти.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		events:         make(map[int64]*Event),
+		userEvents:     make(map[int64]map[userEventKey]struct{}),
+		sentReminders:  make(map[reminderKey]struct{}),
+		eventReminders: make(map[int64]map[time.Duration]struct{}),
+		chatSettings:   make(map[int64]ChatSettings),
+		sessions:       make(map[sessionKey]Session),
+		pins:           make(map[int64]Pin),
+		sentDigests:    make(map[digestKey]struct{}),
+		collections:    make(map[int64]Collection),
+		subscriptions:  make(map[int64][]int64),
+	}
+}
+
+// Close ничего не делает: у хранилища в памяти нет внешних ресурсов.
+func (s *MemoryStorage) Close() {}
+
+// Ping всегда успешен.
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// normalizeTime приводит момент времени к точности TIMESTAMPTZ, как при сохранении в PostgreSQL.
+func normalizeTime(t time.Time) time.Time {
+	return t.Truncate(time.Microsecond)
+}
+
+// ---------- Events CRUD ----------
+
+// findEvent возвращает событие по chat_id + name. Вызывается под s.mu.
+func (s *MemoryStorage) findEvent(chatID int64, name string) *Event {
+	for _, e := range s.events {
+		if e.ChatID == chatID && e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// filterEvents возвращает копии событий, удовлетворяющих match, по возрастанию даты и id.
+func (s *MemoryStorage) filterEvents(match func(*Event) bool) []Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []Event
+	for _, e := range s.events {
+		if match(e) {
+			events = append(events, *e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].Date.Equal(events[j].Date) {
+			return events[i].Date.Before(events[j].Date)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events
+}
+
+// CreateEvent создаёт событие и заполняет e.ID и e.CreatedAt.
+// Возвращает ErrEventExists, если событие с таким именем в чате уже существует.
+func (s *MemoryStorage) CreateEvent(ctx context.Context, e *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findEvent(e.ChatID, e.Name) != nil {
+		return ErrEventExists
+	}
+	if e.Status == "" {
+		e.Status = StatusActive
+	}
+	defaultTitle(e)
+	s.nextID++
+	e.ID = s.nextID
+	e.CreatedAt = normalizeTime(time.Now())
+
+	stored := *e
+	stored.Date = normalizeTime(e.Date)
+	s.events[stored.ID] = &stored
+	return nil
+}
+
+// GetEvent возвращает событие по chat_id + name.
+func (s *MemoryStorage) GetEvent(ctx context.Context, chatID int64, name string) (*Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e := s.findEvent(chatID, name)
+	if e == nil {
+		return nil, ErrNotFound
+	}
+	cp := *e
+	return &cp, nil
+}
+
+// GetEventByID возвращает событие по его id.
+func (s *MemoryStorage) GetEventByID(ctx context.Context, id int64) (*Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.events[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cp := *e
+	return &cp, nil
+}
+
+// ListEvents возвращает все события чата.
+func (s *MemoryStorage) ListEvents(ctx context.Context, chatID int64) ([]Event, error) {
+	return s.filterEvents(func(e *Event) bool { return e.ChatID == chatID }), nil
+}
+
+// ListEventsBetween возвращает события чата с датой в полуинтервале [from, to), по возрастанию даты.
+func (s *MemoryStorage) ListEventsBetween(ctx context.Context, chatID int64, from, to time.Time) ([]Event, error) {
+	return s.filterEvents(func(e *Event) bool {
+		return e.ChatID == chatID && !e.Date.Before(from) && e.Date.Before(to)
+	}), nil
+}
+
+// ListAllEventsBetween возвращает события всех чатов с датой в полуинтервале [from, to).
+func (s *MemoryStorage) ListAllEventsBetween(ctx context.Context, from, to time.Time) ([]Event, error) {
+	return s.filterEvents(func(e *Event) bool {
+		return !e.Date.Before(from) && e.Date.Before(to)
+	}), nil
+}
+
+// ListEventChats возвращает id чатов, в которых есть хотя бы одно событие, по возрастанию.
+func (s *MemoryStorage) ListEventChats(ctx context.Context) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[int64]bool)
+	var chats []int64
+	for _, e := range s.events {
+		if !seen[e.ChatID] {
+			seen[e.ChatID] = true
+			chats = append(chats, e.ChatID)
+		}
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i] < chats[j] })
+	return chats, nil
+}
+
+// UpdateEvent сохраняет имя, название, дату, описание, статус и правило повторения события с id e.ID.
+// Возвращает ErrEventExists, если новое название уже занято в чате, и ErrNotFound, если события нет.
+func (s *MemoryStorage) UpdateEvent(ctx context.Context, e *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.events[e.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if other := s.findEvent(stored.ChatID, e.Name); other != nil && other.ID != e.ID {
+		return ErrEventExists
+	}
+	defaultTitle(e)
+	stored.Name = e.Name
+	stored.Title = e.Title
+	stored.Date = normalizeTime(e.Date)
+	stored.Description = e.Description
+	stored.Status = e.Status
+	stored.Recurrence = e.Recurrence
+	return nil
+}
+
+// UpdateEventStatus обновляет статус события.
+func (s *MemoryStorage) UpdateEventStatus(ctx context.Context, chatID int64, name, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e := s.findEvent(chatID, name); e != nil {
+		e.Status = status
+	}
+	return nil
+}
+
+// UpdateEventRecurrence задаёт правило повторения события.
+// Повторяющееся событие снова становится активным, даже если ранее было помечено устаревшим.
+func (s *MemoryStorage) UpdateEventRecurrence(ctx context.Context, chatID int64, name, recurrence string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e := s.findEvent(chatID, name); e != nil {
+		e.Recurrence = recurrence
+		if recurrence != "" {
+			e.Status = StatusActive
+		}
+	}
+	return nil
+}
+
+// DeleteEvent удаляет событие вместе с привязками к пользователям и напоминаниями.
+func (s *MemoryStorage) DeleteEvent(ctx context.Context, chatID int64, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.findEvent(chatID, name)
+	if e == nil {
+		return nil
+	}
+	s.deleteEventLocked(e.ID)
+	return nil
+}
+
+// deleteEventLocked удаляет событие и зависимые записи (аналог ON DELETE CASCADE). Вызывается под s.mu.
+func (s *MemoryStorage) deleteEventLocked(id int64) {
+	delete(s.events, id)
+	delete(s.userEvents, id)
+	delete(s.eventReminders, id)
+	delete(s.pins, id)
+	for k := range s.sentReminders {
+		if k.eventID == id {
+			delete(s.sentReminders, k)
+		}
+	}
+}
+
+// ---------- User-Events ----------
+
+// AddEventToUser привязывает событие к пользователю.
+func (s *MemoryStorage) AddEventToUser(ctx context.Context, chatID, userID, eventID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[eventID]; !ok {
+		return ErrNotFound
+	}
+	users, ok := s.userEvents[eventID]
+	if !ok {
+		users = make(map[userEventKey]struct{})
+		s.userEvents[eventID] = users
+	}
+	users[userEventKey{chatID, userID}] = struct{}{}
+	return nil
+}
+
+// IsEventOwner сообщает, привязано ли событие к пользователю (создал ли он его).
+func (s *MemoryStorage) IsEventOwner(ctx context.Context, eventID, userID int64) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for k := range s.userEvents[eventID] {
+		if k.userID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ---------- Reminders ----------
+
+// ListActiveEvents возвращает активные события всех чатов, по возрастанию id.
+func (s *MemoryStorage) ListActiveEvents(ctx context.Context) ([]Event, error) {
+	events := s.filterEvents(func(e *Event) bool { return e.Status == StatusActive })
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+func newReminderKey(eventID int64, eventDate time.Time, offset time.Duration) reminderKey {
+	return reminderKey{eventID: eventID, eventDate: eventDate.UnixMicro(), offset: offset.Truncate(time.Second)}
+}
+
+// ListSentReminders возвращает смещения напоминаний, уже отправленных для события с указанной датой.
+func (s *MemoryStorage) ListSentReminders(ctx context.Context, eventID int64, eventDate time.Time) ([]time.Duration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	date := eventDate.UnixMicro()
+	var offsets []time.Duration
+	for k := range s.sentReminders {
+		if k.eventID == eventID && k.eventDate == date {
+			offsets = append(offsets, k.offset)
+		}
+	}
+	return offsets, nil
+}
+
+// ClaimReminder атомарно помечает напоминание как отправленное.
+// Возвращает false, если оно уже было отмечено.
+func (s *MemoryStorage) ClaimReminder(ctx context.Context, eventID int64, eventDate time.Time, offset time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[eventID]; !ok {
+		return false, ErrNotFound
+	}
+	key := newReminderKey(eventID, eventDate, offset)
+	if _, ok := s.sentReminders[key]; ok {
+		return false, nil
+	}
+	s.sentReminders[key] = struct{}{}
+	return true, nil
+}
+
+// ReleaseReminder снимает отметку об отправке напоминания.
+func (s *MemoryStorage) ReleaseReminder(ctx context.Context, eventID int64, eventDate time.Time, offset time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sentReminders, newReminderKey(eventID, eventDate, offset))
+	return nil
+}
+
+// ---------- Per-event reminder offsets ----------
+
+// ListEventReminders возвращает собственные смещения напоминаний события (по убыванию).
+func (s *MemoryStorage) ListEventReminders(ctx context.Context, eventID int64) ([]time.Duration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var offsets []time.Duration
+	for o := range s.eventReminders[eventID] {
+		offsets = append(offsets, o)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+	return offsets, nil
+}
+
+// AddEventReminder добавляет смещение напоминания событию. Повторное добавление игнорируется.
+func (s *MemoryStorage) AddEventReminder(ctx context.Context, eventID int64, offset time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[eventID]; !ok {
+		return ErrNotFound
+	}
+	offsets, ok := s.eventReminders[eventID]
+	if !ok {
+		offsets = make(map[time.Duration]struct{})
+		s.eventReminders[eventID] = offsets
+	}
+	offsets[offset.Truncate(time.Second)] = struct{}{}
+	return nil
+}
+
+// DeleteEventReminder удаляет смещение напоминания. Возвращает false, если такого смещения не было.
+func (s *MemoryStorage) DeleteEventReminder(ctx context.Context, eventID int64, offset time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	offset = offset.Truncate(time.Second)
+	if _, ok := s.eventReminders[eventID][offset]; !ok {
+		return false, nil
+	}
+	delete(s.eventReminders[eventID], offset)
+	return true, nil
+}
+
+// ClearEventReminders удаляет все собственные смещения события.
+func (s *MemoryStorage) ClearEventReminders(ctx context.Context, eventID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.eventReminders, eventID)
+	return nil
+}
+
+// ---------- Chat settings ----------
+
+// GetChatSettings возвращает настройки чата (пустые, если чат ничего не настраивал).
+func (s *MemoryStorage) GetChatSettings(ctx context.Context, chatID int64) (*ChatSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cs, ok := s.chatSettings[chatID]
+	if !ok {
+		cs = ChatSettings{ChatID: chatID}
+	}
+	return &cs, nil
+}
+
+// SetChatTimezone сохраняет часовой пояс чата.
+func (s *MemoryStorage) SetChatTimezone(ctx context.Context, chatID int64, timezone string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cs := s.chatSettings[chatID]
+	cs.ChatID = chatID
+	cs.Timezone = timezone
+	s.chatSettings[chatID] = cs
+	return nil
+}
+
+// SetChatDigest сохраняет расписание дайджеста чата. Пустой Schedule выключает дайджест.
+func (s *MemoryStorage) SetChatDigest(ctx context.Context, chatID int64, d Digest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cs := s.chatSettings[chatID]
+	cs.ChatID = chatID
+	cs.Digest = d
+	s.chatSettings[chatID] = cs
+	return nil
+}
+
+// ListDigestChats возвращает настройки чатов с включённым дайджестом.
+func (s *MemoryStorage) ListDigestChats(ctx context.Context) ([]ChatSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var chats []ChatSettings
+	for _, cs := range s.chatSettings {
+		if cs.Digest.Schedule != "" {
+			chats = append(chats, cs)
+		}
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i].ChatID < chats[j].ChatID })
+	return chats, nil
+}
+
+// ClaimDigest атомарно помечает выпуск дайджеста как отправленный.
+func (s *MemoryStorage) ClaimDigest(ctx context.Context, chatID int64, slot time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := digestKey{chatID, normalizeTime(slot).UnixMicro()}
+	if _, ok := s.sentDigests[key]; ok {
+		return false, nil
+	}
+	s.sentDigests[key] = struct{}{}
+	return true, nil
+}
+
+// ReleaseDigest снимает отметку об отправке дайджеста.
+func (s *MemoryStorage) ReleaseDigest(ctx context.Context, chatID int64, slot time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sentDigests, digestKey{chatID, normalizeTime(slot).UnixMicro()})
+	return nil
+}
+
+// ---------- Sessions ----------
+
+// GetSession возвращает сессию. Истёкшая сессия считается отсутствующей: возвращается ErrNotFound.
+func (s *MemoryStorage) GetSession(ctx context.Context, chatID, userID int64, kind string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[sessionKey{chatID, userID, kind}]
+	if !ok || !sess.ExpiresAt.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+	sess.Data = append([]byte(nil), sess.Data...)
+	return &sess, nil
+}
+
+// SetSession создаёт или заменяет сессию (chat_id, user_id, kind) и заполняет sess.CreatedAt.
+// Продление живой сессии сохраняет время её создания, истёкшая сессия заменяется новой.
+func (s *MemoryStorage) SetSession(ctx context.Context, sess *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := sessionKey{sess.ChatID, sess.UserID, sess.Kind}
+	now := normalizeTime(time.Now())
+	sess.CreatedAt = now
+	if prev, ok := s.sessions[key]; ok && prev.ExpiresAt.After(now) {
+		sess.CreatedAt = prev.CreatedAt
+	}
+
+	stored := *sess
+	stored.Data = append([]byte(nil), sess.Data...)
+	stored.ExpiresAt = normalizeTime(sess.ExpiresAt)
+	s.sessions[key] = stored
+	return nil
+}
+
+// DeleteSession удаляет сессию. Отсутствие сессии ошибкой не считается.
+func (s *MemoryStorage) DeleteSession(ctx context.Context, chatID, userID int64, kind string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sessionKey{chatID, userID, kind})
+	return nil
+}
+
+// DeleteExpiredSessions удаляет сессии, истёкшие к моменту now, и возвращает их.
+func (s *MemoryStorage) DeleteExpiredSessions(ctx context.Context, now time.Time) ([]Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []Session
+	for key, sess := range s.sessions {
+		if !sess.ExpiresAt.After(now) {
+			delete(s.sessions, key)
+			expired = append(expired, sess)
+		}
+	}
+	return expired, nil
+}
+
+// CountSessions возвращает число живых сессий по видам.
+func (s *MemoryStorage) CountSessions(ctx context.Context) (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	counts := make(map[string]int)
+	for key, sess := range s.sessions {
+		if sess.ExpiresAt.After(now) {
+			counts[key.kind]++
+		}
+	}
+	return counts, nil
+}
+
+// ---------- Pinned countdowns ----------
+
+// SetPin создаёт или заменяет закреплённое сообщение события.
+func (s *MemoryStorage) SetPin(ctx context.Context, p *Pin) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[p.EventID]; !ok {
+		return ErrNotFound
+	}
+	stored := *p
+	stored.NextUpdateAt = normalizeTime(p.NextUpdateAt)
+	s.pins[p.EventID] = stored
+	return nil
+}
+
+// GetPin возвращает закреплённое сообщение события или ErrNotFound.
+func (s *MemoryStorage) GetPin(ctx context.Context, eventID int64) (*Pin, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.pins[eventID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+// DeletePin удаляет закреплённое сообщение события. Отсутствие записи ошибкой не считается.
+func (s *MemoryStorage) DeletePin(ctx context.Context, eventID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pins, eventID)
+	return nil
+}
+
+// ClaimDuePins возвращает сообщения, которые пора обновить к моменту now, и откладывает
+// их следующее обновление на lease.
+func (s *MemoryStorage) ClaimDuePins(ctx context.Context, now time.Time, lease time.Duration) ([]Pin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []Pin
+	for id, p := range s.pins {
+		if p.NextUpdateAt.After(now) {
+			continue
+		}
+		p.NextUpdateAt = normalizeTime(now.Add(lease))
+		s.pins[id] = p
+		due = append(due, p)
+	}
+	return due, nil
+}
+
+// ReschedulePin назначает следующее обновление сообщения события. Если сообщения нет, ничего не делает.
+func (s *MemoryStorage) ReschedulePin(ctx context.Context, eventID int64, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.pins[eventID]; ok {
+		p.NextUpdateAt = normalizeTime(next)
+		s.pins[eventID] = p
+	}
+	return nil
+}
+
+// ---------- Collections ----------
+
+// CreateCollection публикует подборку чата c.ChatID. Возвращает false, если у чата уже есть подборка
+// (она не меняется), и ErrCodeTaken, если код занят подборкой другого чата.
+func (s *MemoryStorage) CreateCollection(ctx context.Context, c *Collection) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.collections[c.ChatID]; ok {
+		return false, nil
+	}
+	for _, other := range s.collections {
+		if other.Code == c.Code {
+			return false, ErrCodeTaken
+		}
+	}
+	s.collections[c.ChatID] = *c
+	return true, nil
+}
+
+// GetCollection возвращает подборку чата или ErrNotFound.
+func (s *MemoryStorage) GetCollection(ctx context.Context, chatID int64) (*Collection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.collections[chatID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &c, nil
+}
+
+// GetCollectionByCode возвращает подборку по коду или ErrNotFound.
+func (s *MemoryStorage) GetCollectionByCode(ctx context.Context, code string) (*Collection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, c := range s.collections {
+		if c.Code == code {
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// DeleteCollection снимает подборку чата с публикации вместе со всеми подписками на неё.
+// Возвращает false, если подборки не было.
+func (s *MemoryStorage) DeleteCollection(ctx context.Context, chatID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteCollectionLocked(chatID), nil
+}
+
+// deleteCollectionLocked удаляет подборку и подписки на неё (аналог ON DELETE CASCADE). Вызывается под s.mu.
+func (s *MemoryStorage) deleteCollectionLocked(chatID int64) bool {
+	if _, ok := s.collections[chatID]; !ok {
+		return false
+	}
+	delete(s.collections, chatID)
+	for subscriber := range s.subscriptions {
+		s.unsubscribeLocked(subscriber, chatID)
+	}
+	return true
+}
+
+// unsubscribeLocked удаляет подписку chatID на подборку sourceChatID. Вызывается под s.mu.
+func (s *MemoryStorage) unsubscribeLocked(chatID, sourceChatID int64) bool {
+	sources := s.subscriptions[chatID]
+	i := slices.Index(sources, sourceChatID)
+	if i < 0 {
+		return false
+	}
+	if sources = slices.Delete(sources, i, i+1); len(sources) == 0 {
+		delete(s.subscriptions, chatID)
+	} else {
+		s.subscriptions[chatID] = sources
+	}
+	return true
+}
+
+// Subscribe подписывает чат chatID на подборку чата sourceChatID. Возвращает false, если подписка
+// уже была, и ErrNotFound, если подборки нет.
+func (s *MemoryStorage) Subscribe(ctx context.Context, chatID, sourceChatID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.collections[sourceChatID]; !ok {
+		return false, ErrNotFound
+	}
+	if slices.Contains(s.subscriptions[chatID], sourceChatID) {
+		return false, nil
+	}
+	s.subscriptions[chatID] = append(s.subscriptions[chatID], sourceChatID)
+	return true, nil
+}
+
+// Unsubscribe отменяет подписку чата chatID на подборку чата sourceChatID.
+// Возвращает false, если подписки не было.
+func (s *MemoryStorage) Unsubscribe(ctx context.Context, chatID, sourceChatID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.unsubscribeLocked(chatID, sourceChatID), nil
+}
+
+// ListSubscriptions возвращает подборки, на которые подписан чат, в порядке подписки.
+func (s *MemoryStorage) ListSubscriptions(ctx context.Context, chatID int64) ([]Collection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var collections []Collection
+	for _, id := range s.subscriptions[chatID] {
+		collections = append(collections, s.collections[id])
+	}
+	return collections, nil
+}
+
+// FindSubscribedEvent ищет событие по имени в подборках, на которые подписан чат.
+// Из нескольких совпадений возвращается созданное раньше всех.
+func (s *MemoryStorage) FindSubscribedEvent(ctx context.Context, chatID int64, name string) (*Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sources := s.subscriptions[chatID]
+	var found *Event
+	for _, e := range s.events {
+		if e.Name == name && slices.Contains(sources, e.ChatID) && (found == nil || e.ID < found.ID) {
+			found = e
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	cp := *found
+	return &cp, nil
+}
+
+// ---------- Admin ----------
+
+// knownChatsLocked возвращает чаты с событиями или настройками. Вызывать под s.mu.
+func (s *MemoryStorage) knownChatsLocked() map[int64]*ChatStats {
+	chats := make(map[int64]*ChatStats)
+	for id := range s.chatSettings {
+		chats[id] = &ChatStats{ChatID: id}
+	}
+	for _, e := range s.events {
+		cs, ok := chats[e.ChatID]
+		if !ok {
+			cs = &ChatStats{ChatID: e.ChatID}
+			chats[e.ChatID] = cs
+		}
+		cs.Events++
+		if e.Status == StatusActive {
+			cs.ActiveEvents++
+		}
+	}
+	return chats
+}
+
+// Stats возвращает сводку по всем чатам.
+func (s *MemoryStorage) Stats(ctx context.Context) (*Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make(map[int64]bool)
+	for _, keys := range s.userEvents {
+		for k := range keys {
+			users[k.userID] = true
+		}
+	}
+	st := Stats{
+		Chats:         len(s.knownChatsLocked()),
+		Users:         len(users),
+		Events:        len(s.events),
+		RemindersSent: len(s.sentReminders),
+		DigestsSent:   len(s.sentDigests),
+	}
+	for _, e := range s.events {
+		if e.Status == StatusActive {
+			st.ActiveEvents++
+		}
+	}
+	return &st, nil
+}
+
+// ListChatStats возвращает страницу чатов с числом событий и общее число чатов.
+func (s *MemoryStorage) ListChatStats(ctx context.Context, offset, limit int) ([]ChatStats, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var chats []ChatStats
+	for _, cs := range s.knownChatsLocked() {
+		chats = append(chats, *cs)
+	}
+	sort.Slice(chats, func(i, j int) bool {
+		if chats[i].Events != chats[j].Events {
+			return chats[i].Events > chats[j].Events
+		}
+		return chats[i].ChatID < chats[j].ChatID
+	})
+	total := len(chats)
+	if offset > total {
+		offset = total
+	}
+	end := min(offset+limit, total)
+	return chats[offset:end], total, nil
+}
+
+// ListKnownChats возвращает все чаты с событиями или настройками (получатели рассылки).
+func (s *MemoryStorage) ListKnownChats(ctx context.Context) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var chats []int64
+	for id := range s.knownChatsLocked() {
+		chats = append(chats, id)
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i] < chats[j] })
+	return chats, nil
+}
+
+// PurgeChat удаляет все данные чата: события (вместе с напоминаниями, привязками и отсчётами),
+// настройки, дайджесты, сессии, подборку и подписки. Возвращает число удалённых событий.
+func (s *MemoryStorage) PurgeChat(ctx context.Context, chatID int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for id, e := range s.events {
+		if e.ChatID == chatID {
+			s.deleteEventLocked(id)
+			deleted++
+		}
+	}
+	delete(s.chatSettings, chatID)
+	for k := range s.sentDigests {
+		if k.chatID == chatID {
+			delete(s.sentDigests, k)
+		}
+	}
+	for k := range s.sessions {
+		if k.chatID == chatID {
+			delete(s.sessions, k)
+		}
+	}
+	s.deleteCollectionLocked(chatID)
+	delete(s.subscriptions, chatID)
+	return deleted, nil
+}
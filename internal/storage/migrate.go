@@ -0,0 +1,505 @@
+package storage
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------- миграции ----------
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrationsFS embed.FS
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrationsFS embed.FS
+
+// migrationLockID — ключ advisory lock, под которым применяются миграции,
+// чтобы две реплики бота не применяли их одновременно.
+const migrationLockID int64 = 0x74696d6572626f74 // "timerbot"
+
+// migration — одна версия схемы: SQL-файл из migrations/postgres или Go-функция
+// для миграций данных, которые неудобно выразить на SQL.
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+	Up      func(ctx context.Context, s *PostgresStorage, tx pgx.Tx) error
+}
+
+// goMigrations — миграции данных на Go. Версии не должны совпадать с версиями SQL-файлов.
+var goMigrations = []migration{
+	{Version: 5, Name: "text_dates_to_timestamptz", Up: migrateTextDates},
+}
+
+// MigrationStatus — состояние одной миграции.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // nil — миграция ещё не применена
+}
+
+// loadMigrations собирает SQL-миграции из каталога dir и Go-миграции extra в порядке версий.
+// Имена файлов имеют вид NNNN_name.sql.
+func loadMigrations(fsys fs.FS, dir string, extra []migration) ([]migration, error) {
+	files, err := fs.Glob(fsys, dir+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := append([]migration(nil), extra...)
+	for _, f := range files {
+		num, name, ok := strings.Cut(strings.TrimSuffix(path.Base(f), ".sql"), "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid migration file name %q (expected NNNN_name.sql)", f)
+		}
+		body, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{Version: version, Name: name, SQL: string(body)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// Migrate применяет все неприменённые миграции по порядку, каждую в своей транзакции.
+// На время работы берётся advisory lock, поэтому параллельный запуск нескольких реплик безопасен:
+// вторая дождётся первой и увидит, что применять нечего. Возвращает применённые миграции.
+func (s *PostgresStorage) Migrate(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(postgresMigrationsFS, "migrations/postgres", goMigrations)
+	if err != nil {
+		return nil, err
+	}
+
+	// Advisory lock привязан к соединению, поэтому все шаги выполняются на одном соединении
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT        NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(ctx, conn.Conn())
+	if err != nil {
+		return nil, err
+	}
+
+	var done []MigrationStatus
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return done, err
+		}
+		if m.Up != nil {
+			err = m.Up(ctx, s, tx)
+		} else {
+			_, err = tx.Exec(ctx, m.SQL)
+		}
+		if err == nil {
+			_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+		}
+		if err != nil {
+			tx.Rollback(ctx)
+			return done, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+
+		now := time.Now()
+		done = append(done, MigrationStatus{Version: m.Version, Name: m.Name, AppliedAt: &now})
+		log.Printf("migration: applied %04d_%s", m.Version, m.Name)
+	}
+	return done, nil
+}
+
+// MigrationStatus возвращает состояние всех известных миграций. Схему не изменяет.
+func (s *PostgresStorage) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(postgresMigrationsFS, "migrations/postgres", goMigrations)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int]time.Time{}
+	var exists bool
+	if err := s.pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists {
+		conn, err := s.pool.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Release()
+		if applied, err = appliedMigrations(ctx, conn.Conn()); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			st.AppliedAt = &at
+		}
+		result = append(result, st)
+	}
+	return result, nil
+}
+
+// PendingMigrations возвращает число неприменённых миграций.
+func (s *PostgresStorage) PendingMigrations(ctx context.Context) (int, error) {
+	statuses, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return countPending(statuses), nil
+}
+
+func countPending(statuses []MigrationStatus) int {
+	pending := 0
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending
+}
+
+func appliedMigrations(ctx context.Context, conn *pgx.Conn) (map[int]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version int
+			at      time.Time
+		)
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// ---------- миграции SQLite ----------
+
+// Migrate применяет неприменённые миграции SQLite (migrations/sqlite), каждую в своей транзакции.
+// SQLite блокирует базу на запись на время транзакции, поэтому отдельная блокировка не нужна.
+func (s *SQLiteStorage) Migrate(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(sqliteMigrationsFS, "migrations/sqlite", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return nil, err
+	}
+
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []MigrationStatus
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		now := time.Now()
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return done, err
+		}
+		_, err = tx.ExecContext(ctx, m.SQL)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				m.Version, m.Name, formatSQLiteTime(now))
+		}
+		if err != nil {
+			tx.Rollback()
+			return done, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+
+		done = append(done, MigrationStatus{Version: m.Version, Name: m.Name, AppliedAt: &now})
+		log.Printf("migration: applied sqlite %04d_%s", m.Version, m.Name)
+	}
+	return done, nil
+}
+
+// MigrationStatus возвращает состояние всех известных миграций SQLite. Схему не изменяет.
+func (s *SQLiteStorage) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(sqliteMigrationsFS, "migrations/sqlite", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int]time.Time{}
+	var exists int
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`,
+	).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists > 0 {
+		if applied, err = s.appliedMigrations(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			st.AppliedAt = &at
+		}
+		result = append(result, st)
+	}
+	return result, nil
+}
+
+// PendingMigrations возвращает число неприменённых миграций SQLite.
+func (s *SQLiteStorage) PendingMigrations(ctx context.Context) (int, error) {
+	statuses, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return countPending(statuses), nil
+}
+
+func (s *SQLiteStorage) appliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version int
+			raw     string
+		)
+		if err := rows.Scan(&version, &raw); err != nil {
+			return nil, err
+		}
+		at, err := parseSQLiteTime(raw)
+		if err != nil {
+			return nil, fmt.Errorf("schema_migrations %d: invalid applied_at %q", version, raw)
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// ---------- миграции данных ----------
+
+// legacyDateFormats — форматы, в которых старые версии бота сохраняли даты.
+var legacyDateFormats = []string{"2006-01-02 15:04", "2006-01-02", "02.01.2006"}
+
+// migrateTextDates переводит events.date и sent_reminders.event_date из TEXT в TIMESTAMPTZ.
+// Текст интерпретируется в часовом поясе чата (chat_settings) или в legacyLoc.
+// Строки, которые не удалось разобрать, логируются; событию ставится дата создания и статус outdated.
+func migrateTextDates(ctx context.Context, s *PostgresStorage, tx pgx.Tx) error {
+	var dataType string
+	err := tx.QueryRow(ctx,
+		`SELECT data_type FROM information_schema.columns
+		 WHERE table_schema = current_schema() AND table_name = 'events' AND column_name = 'date'`,
+	).Scan(&dataType)
+	if err != nil {
+		return err
+	}
+	if dataType != "text" {
+		// База создана версией, которая уже хранила даты как TIMESTAMPTZ
+		_, err := tx.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_events_date ON events (date)`)
+		return err
+	}
+
+	rows, err := tx.Query(ctx,
+		`SELECT e.id, e.date, e.created_at, COALESCE(cs.timezone, '')
+		 FROM events e LEFT JOIN chat_settings cs ON cs.chat_id = e.chat_id`,
+	)
+	if err != nil {
+		return err
+	}
+	type converted struct {
+		id       int64
+		date     time.Time
+		outdated bool
+	}
+	var events []converted
+	locs := make(map[int64]*time.Location)
+	for rows.Next() {
+		var (
+			id        int64
+			raw, tz   string
+			createdAt time.Time
+		)
+		if err := rows.Scan(&id, &raw, &createdAt, &tz); err != nil {
+			rows.Close()
+			return err
+		}
+		loc := s.legacyLocation(tz)
+		locs[id] = loc
+		if t, ok := parseLegacyDate(raw, loc); ok {
+			events = append(events, converted{id: id, date: t})
+			continue
+		}
+		log.Printf("migration: event %d has unparseable date %q, using created_at %s and status outdated",
+			id, raw, createdAt.Format(time.RFC3339))
+		events = append(events, converted{id: id, date: createdAt, outdated: true})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Отметки напоминаний хранят вхождение события тем же текстом во времени чата
+	rows, err = tx.Query(ctx, `SELECT id, event_id, event_date FROM sent_reminders`)
+	if err != nil {
+		return err
+	}
+	type convertedReminder struct {
+		id   int64
+		date time.Time
+	}
+	var reminders []convertedReminder
+	var broken []int64
+	for rows.Next() {
+		var (
+			id, eventID int64
+			raw         string
+		)
+		if err := rows.Scan(&id, &eventID, &raw); err != nil {
+			rows.Close()
+			return err
+		}
+		loc := locs[eventID]
+		if loc == nil {
+			loc = s.legacyLoc
+		}
+		if t, ok := parseLegacyDate(raw, loc); ok {
+			reminders = append(reminders, convertedReminder{id: id, date: t})
+			continue
+		}
+		log.Printf("migration: sent reminder %d has unparseable date %q, dropping it", id, raw)
+		broken = append(broken, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	stmts := []string{
+		`ALTER TABLE events ADD COLUMN date_ts TIMESTAMPTZ`,
+		`ALTER TABLE sent_reminders ADD COLUMN event_date_ts TIMESTAMPTZ`,
+	}
+	for _, q := range stmts {
+		if _, err := tx.Exec(ctx, q); err != nil {
+			return err
+		}
+	}
+	for _, e := range events {
+		if _, err := tx.Exec(ctx,
+			`UPDATE events SET date_ts = $1, status = CASE WHEN $2 THEN 'outdated' ELSE status END WHERE id = $3`,
+			e.date, e.outdated, e.id,
+		); err != nil {
+			return err
+		}
+	}
+	for _, r := range reminders {
+		if _, err := tx.Exec(ctx, `UPDATE sent_reminders SET event_date_ts = $1 WHERE id = $2`, r.date, r.id); err != nil {
+			return err
+		}
+	}
+	for _, id := range broken {
+		if _, err := tx.Exec(ctx, `DELETE FROM sent_reminders WHERE id = $1`, id); err != nil {
+			return err
+		}
+	}
+
+	stmts = []string{
+		`ALTER TABLE events DROP COLUMN date`,
+		`ALTER TABLE events RENAME COLUMN date_ts TO date`,
+		`ALTER TABLE events ALTER COLUMN date SET NOT NULL`,
+		`ALTER TABLE sent_reminders DROP COLUMN event_date`,
+		`ALTER TABLE sent_reminders RENAME COLUMN event_date_ts TO event_date`,
+		`ALTER TABLE sent_reminders ALTER COLUMN event_date SET NOT NULL`,
+		`ALTER TABLE sent_reminders ADD UNIQUE (event_id, event_date, offset_seconds)`,
+		`CREATE INDEX IF NOT EXISTS idx_events_date ON events (date)`,
+	}
+	for _, q := range stmts {
+		if _, err := tx.Exec(ctx, q); err != nil {
+			return fmt.Errorf("%w\nquery: %s", err, q)
+		}
+	}
+
+	log.Printf("migration: converted %d event dates and %d sent reminders to TIMESTAMPTZ", len(events), len(reminders))
+	return nil
+}
+
+func (s *PostgresStorage) legacyLocation(tz string) *time.Location {
+	if tz == "" {
+		return s.legacyLoc
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return s.legacyLoc
+	}
+	return loc
+}
+
+func parseLegacyDate(raw string, loc *time.Location) (time.Time, bool) {
+	for _, f := range legacyDateFormats {
+		if t, err := time.ParseInLocation(f, raw, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
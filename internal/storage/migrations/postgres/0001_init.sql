@@ -0,0 +1,21 @@
+-- Исходная схема. IF NOT EXISTS — базы, созданные до появления schema_migrations, уже её содержат.
+CREATE TABLE IF NOT EXISTS events (
+    id          BIGSERIAL PRIMARY KEY,
+    chat_id     BIGINT      NOT NULL,
+    name        TEXT        NOT NULL,
+    date        TEXT        NOT NULL,
+    description TEXT        NOT NULL DEFAULT '',
+    status      TEXT        NOT NULL DEFAULT 'active',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE(chat_id, name)
+);
+CREATE INDEX IF NOT EXISTS idx_events_chat_id ON events (chat_id);
+CREATE INDEX IF NOT EXISTS idx_events_name    ON events (name);
+
+CREATE TABLE IF NOT EXISTS user_events (
+    id       BIGSERIAL PRIMARY KEY,
+    chat_id  BIGINT NOT NULL,
+    user_id  BIGINT NOT NULL,
+    event_id BIGINT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    UNIQUE(chat_id, user_id, event_id)
+);
@@ -0,0 +1,17 @@
+-- Журнал отправленных напоминаний и собственные смещения напоминаний событий
+CREATE TABLE IF NOT EXISTS sent_reminders (
+    id             BIGSERIAL PRIMARY KEY,
+    event_id       BIGINT      NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    event_date     TEXT        NOT NULL,
+    offset_seconds BIGINT      NOT NULL,
+    sent_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE(event_id, event_date, offset_seconds)
+);
+
+CREATE TABLE IF NOT EXISTS event_reminders (
+    id             BIGSERIAL PRIMARY KEY,
+    event_id       BIGINT      NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    offset_seconds BIGINT      NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE(event_id, offset_seconds)
+);
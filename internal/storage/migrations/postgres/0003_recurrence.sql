@@ -0,0 +1,2 @@
+-- Правило повторения в формате RRULE; пустая строка — однократное событие
+ALTER TABLE events ADD COLUMN IF NOT EXISTS recurrence TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS chat_settings (
+    chat_id    BIGINT PRIMARY KEY,
+    timezone   TEXT        NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
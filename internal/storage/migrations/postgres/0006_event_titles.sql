@@ -0,0 +1,3 @@
+-- Название события для отображения; name остаётся именем команды (slug)
+ALTER TABLE events ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+UPDATE events SET title = name WHERE title = '';
@@ -0,0 +1,11 @@
+-- Состояние многошаговых диалогов (календарь, ожидание ввода), общее для всех реплик
+CREATE TABLE IF NOT EXISTS sessions (
+    chat_id    BIGINT      NOT NULL,
+    user_id    BIGINT      NOT NULL,
+    kind       TEXT        NOT NULL,
+    data       TEXT        NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (chat_id, user_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions (expires_at);
@@ -0,0 +1,2 @@
+-- Время начала диалога: для метрик и сообщений об истечении сессии
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1,10 @@
+-- Закреплённые сообщения с обратным отсчётом (/pin): одно на событие
+CREATE TABLE IF NOT EXISTS pinned_countdowns (
+    event_id       BIGINT      PRIMARY KEY REFERENCES events(id) ON DELETE CASCADE,
+    chat_id        BIGINT      NOT NULL,
+    message_id     BIGINT      NOT NULL,
+    next_update_at TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pinned_countdowns_next_update_at ON pinned_countdowns (next_update_at);
@@ -0,0 +1,13 @@
+-- Дайджест предстоящих событий (/digest): расписание в настройках чата
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS digest         TEXT     NOT NULL DEFAULT '';
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS digest_weekday SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS digest_minute  INTEGER  NOT NULL DEFAULT 0;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS digest_days    INTEGER  NOT NULL DEFAULT 0;
+
+-- Отправленные дайджесты: не дают отправить выпуск повторно после перезапуска или с другой реплики
+CREATE TABLE IF NOT EXISTS sent_digests (
+    chat_id BIGINT      NOT NULL,
+    slot    TIMESTAMPTZ NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (chat_id, slot)
+);
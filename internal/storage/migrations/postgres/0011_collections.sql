@@ -0,0 +1,17 @@
+-- Опубликованные подборки событий (/publish): не больше одной на чат
+CREATE TABLE IF NOT EXISTS collections (
+    chat_id    BIGINT      PRIMARY KEY,
+    code       TEXT        NOT NULL UNIQUE,
+    title      TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Подписки чатов на подборки (/subscribe): события подборки показываются в списках чата
+CREATE TABLE IF NOT EXISTS collection_subscriptions (
+    chat_id        BIGINT      NOT NULL,
+    source_chat_id BIGINT      NOT NULL REFERENCES collections(chat_id) ON DELETE CASCADE,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (chat_id, source_chat_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_subscriptions_source_chat_id ON collection_subscriptions (source_chat_id);
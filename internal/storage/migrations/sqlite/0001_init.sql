@@ -0,0 +1,49 @@
+-- Схема SQLite соответствует схеме PostgreSQL после миграции 0005.
+-- Моменты времени хранятся текстом в UTC с фиксированной шириной
+-- (2006-01-02T15:04:05.000000Z), поэтому сравниваются и сортируются как строки.
+
+CREATE TABLE IF NOT EXISTS events (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id     INTEGER NOT NULL,
+    name        TEXT    NOT NULL,
+    date        TEXT    NOT NULL,
+    description TEXT    NOT NULL DEFAULT '',
+    status      TEXT    NOT NULL DEFAULT 'active',
+    recurrence  TEXT    NOT NULL DEFAULT '',
+    created_at  TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    UNIQUE(chat_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_name ON events (name);
+CREATE INDEX IF NOT EXISTS idx_events_date ON events (date);
+
+CREATE TABLE IF NOT EXISTS user_events (
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id  INTEGER NOT NULL,
+    user_id  INTEGER NOT NULL,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    UNIQUE(chat_id, user_id, event_id)
+);
+
+CREATE TABLE IF NOT EXISTS sent_reminders (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id       INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    event_date     TEXT    NOT NULL,
+    offset_seconds INTEGER NOT NULL,
+    sent_at        TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    UNIQUE(event_id, event_date, offset_seconds)
+);
+
+CREATE TABLE IF NOT EXISTS event_reminders (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id       INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    offset_seconds INTEGER NOT NULL,
+    created_at     TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    UNIQUE(event_id, offset_seconds)
+);
+
+CREATE TABLE IF NOT EXISTS chat_settings (
+    chat_id    INTEGER PRIMARY KEY,
+    timezone   TEXT NOT NULL DEFAULT '',
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
@@ -0,0 +1,3 @@
+-- Время начала диалога: для метрик и сообщений об истечении сессии
+ALTER TABLE sessions ADD COLUMN created_at TEXT NOT NULL DEFAULT '';
+UPDATE sessions SET created_at = strftime('%Y-%m-%dT%H:%M:%S', 'now') || '.000000Z' WHERE created_at = '';
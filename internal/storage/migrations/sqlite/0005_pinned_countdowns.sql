@@ -0,0 +1,10 @@
+-- Закреплённые сообщения с обратным отсчётом (/pin): одно на событие
+CREATE TABLE IF NOT EXISTS pinned_countdowns (
+    event_id       INTEGER PRIMARY KEY REFERENCES events(id) ON DELETE CASCADE,
+    chat_id        INTEGER NOT NULL,
+    message_id     INTEGER NOT NULL,
+    next_update_at TEXT    NOT NULL,
+    created_at     TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pinned_countdowns_next_update_at ON pinned_countdowns (next_update_at);
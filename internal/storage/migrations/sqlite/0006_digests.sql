@@ -0,0 +1,13 @@
+-- Дайджест предстоящих событий (/digest): расписание в настройках чата
+ALTER TABLE chat_settings ADD COLUMN digest         TEXT    NOT NULL DEFAULT '';
+ALTER TABLE chat_settings ADD COLUMN digest_weekday INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE chat_settings ADD COLUMN digest_minute  INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE chat_settings ADD COLUMN digest_days    INTEGER NOT NULL DEFAULT 0;
+
+-- Отправленные дайджесты: не дают отправить выпуск повторно после перезапуска
+CREATE TABLE IF NOT EXISTS sent_digests (
+    chat_id INTEGER NOT NULL,
+    slot    TEXT    NOT NULL,
+    sent_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    PRIMARY KEY (chat_id, slot)
+);
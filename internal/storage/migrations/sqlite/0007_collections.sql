@@ -0,0 +1,17 @@
+-- Опубликованные подборки событий (/publish): не больше одной на чат
+CREATE TABLE IF NOT EXISTS collections (
+    chat_id    INTEGER PRIMARY KEY,
+    code       TEXT    NOT NULL UNIQUE,
+    title      TEXT    NOT NULL DEFAULT '',
+    created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
+-- Подписки чатов на подборки (/subscribe): события подборки показываются в списках чата
+CREATE TABLE IF NOT EXISTS collection_subscriptions (
+    chat_id        INTEGER NOT NULL,
+    source_chat_id INTEGER NOT NULL REFERENCES collections(chat_id) ON DELETE CASCADE,
+    created_at     TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    PRIMARY KEY (chat_id, source_chat_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_subscriptions_source_chat_id ON collection_subscriptions (source_chat_id);
@@ -0,0 +1,398 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// ---------- Observed store ----------
+
+// ObserveFunc получает имя метода хранилища, длительность вызова и его ошибку.
+type ObserveFunc func(op string, took time.Duration, err error)
+
+// observedStore передаёт вызовы хранилищу s и сообщает о каждом в observe.
+type observedStore struct {
+	s       Store
+	observe ObserveFunc
+}
+
+var _ Store = (*observedStore)(nil)
+
+// Observed оборачивает хранилище так, что о каждом вызове сообщается в observe
+// (например, для метрик задержки запросов). Close не наблюдается.
+func Observed(s Store, observe ObserveFunc) Store {
+	return &observedStore{s: s, observe: observe}
+}
+
+// done сообщает о завершении вызова op, начатого в start.
+func (o *observedStore) done(op string, start time.Time, err error) {
+	o.observe(op, time.Since(start), err)
+}
+
+// EventStore
+
+func (o *observedStore) CreateEvent(ctx context.Context, e *Event) error {
+	start := time.Now()
+	err := o.s.CreateEvent(ctx, e)
+	o.done("CreateEvent", start, err)
+	return err
+}
+
+func (o *observedStore) GetEvent(ctx context.Context, chatID int64, name string) (*Event, error) {
+	start := time.Now()
+	e, err := o.s.GetEvent(ctx, chatID, name)
+	o.done("GetEvent", start, err)
+	return e, err
+}
+
+func (o *observedStore) GetEventByID(ctx context.Context, id int64) (*Event, error) {
+	start := time.Now()
+	e, err := o.s.GetEventByID(ctx, id)
+	o.done("GetEventByID", start, err)
+	return e, err
+}
+
+func (o *observedStore) ListEvents(ctx context.Context, chatID int64) ([]Event, error) {
+	start := time.Now()
+	events, err := o.s.ListEvents(ctx, chatID)
+	o.done("ListEvents", start, err)
+	return events, err
+}
+
+func (o *observedStore) ListEventsBetween(ctx context.Context, chatID int64, from, to time.Time) ([]Event, error) {
+	start := time.Now()
+	events, err := o.s.ListEventsBetween(ctx, chatID, from, to)
+	o.done("ListEventsBetween", start, err)
+	return events, err
+}
+
+func (o *observedStore) ListAllEventsBetween(ctx context.Context, from, to time.Time) ([]Event, error) {
+	start := time.Now()
+	events, err := o.s.ListAllEventsBetween(ctx, from, to)
+	o.done("ListAllEventsBetween", start, err)
+	return events, err
+}
+
+func (o *observedStore) ListEventChats(ctx context.Context) ([]int64, error) {
+	start := time.Now()
+	chats, err := o.s.ListEventChats(ctx)
+	o.done("ListEventChats", start, err)
+	return chats, err
+}
+
+func (o *observedStore) UpdateEvent(ctx context.Context, e *Event) error {
+	start := time.Now()
+	err := o.s.UpdateEvent(ctx, e)
+	o.done("UpdateEvent", start, err)
+	return err
+}
+
+func (o *observedStore) UpdateEventStatus(ctx context.Context, chatID int64, name, status string) error {
+	start := time.Now()
+	err := o.s.UpdateEventStatus(ctx, chatID, name, status)
+	o.done("UpdateEventStatus", start, err)
+	return err
+}
+
+func (o *observedStore) UpdateEventRecurrence(ctx context.Context, chatID int64, name, recurrence string) error {
+	start := time.Now()
+	err := o.s.UpdateEventRecurrence(ctx, chatID, name, recurrence)
+	o.done("UpdateEventRecurrence", start, err)
+	return err
+}
+
+func (o *observedStore) DeleteEvent(ctx context.Context, chatID int64, name string) error {
+	start := time.Now()
+	err := o.s.DeleteEvent(ctx, chatID, name)
+	o.done("DeleteEvent", start, err)
+	return err
+}
+
+func (o *observedStore) AddEventToUser(ctx context.Context, chatID, userID, eventID int64) error {
+	start := time.Now()
+	err := o.s.AddEventToUser(ctx, chatID, userID, eventID)
+	o.done("AddEventToUser", start, err)
+	return err
+}
+
+func (o *observedStore) IsEventOwner(ctx context.Context, eventID, userID int64) (bool, error) {
+	start := time.Now()
+	ok, err := o.s.IsEventOwner(ctx, eventID, userID)
+	o.done("IsEventOwner", start, err)
+	return ok, err
+}
+
+// ReminderStore
+
+func (o *observedStore) ListActiveEvents(ctx context.Context) ([]Event, error) {
+	start := time.Now()
+	events, err := o.s.ListActiveEvents(ctx)
+	o.done("ListActiveEvents", start, err)
+	return events, err
+}
+
+func (o *observedStore) ListSentReminders(ctx context.Context, eventID int64, eventDate time.Time) ([]time.Duration, error) {
+	start := time.Now()
+	offsets, err := o.s.ListSentReminders(ctx, eventID, eventDate)
+	o.done("ListSentReminders", start, err)
+	return offsets, err
+}
+
+func (o *observedStore) ClaimReminder(ctx context.Context, eventID int64, eventDate time.Time, offset time.Duration) (bool, error) {
+	start := time.Now()
+	ok, err := o.s.ClaimReminder(ctx, eventID, eventDate, offset)
+	o.done("ClaimReminder", start, err)
+	return ok, err
+}
+
+func (o *observedStore) ReleaseReminder(ctx context.Context, eventID int64, eventDate time.Time, offset time.Duration) error {
+	start := time.Now()
+	err := o.s.ReleaseReminder(ctx, eventID, eventDate, offset)
+	o.done("ReleaseReminder", start, err)
+	return err
+}
+
+func (o *observedStore) ListEventReminders(ctx context.Context, eventID int64) ([]time.Duration, error) {
+	start := time.Now()
+	offsets, err := o.s.ListEventReminders(ctx, eventID)
+	o.done("ListEventReminders", start, err)
+	return offsets, err
+}
+
+func (o *observedStore) AddEventReminder(ctx context.Context, eventID int64, offset time.Duration) error {
+	start := time.Now()
+	err := o.s.AddEventReminder(ctx, eventID, offset)
+	o.done("AddEventReminder", start, err)
+	return err
+}
+
+func (o *observedStore) DeleteEventReminder(ctx context.Context, eventID int64, offset time.Duration) (bool, error) {
+	start := time.Now()
+	ok, err := o.s.DeleteEventReminder(ctx, eventID, offset)
+	o.done("DeleteEventReminder", start, err)
+	return ok, err
+}
+
+func (o *observedStore) ClearEventReminders(ctx context.Context, eventID int64) error {
+	start := time.Now()
+	err := o.s.ClearEventReminders(ctx, eventID)
+	o.done("ClearEventReminders", start, err)
+	return err
+}
+
+// ChatSettingsStore
+
+func (o *observedStore) GetChatSettings(ctx context.Context, chatID int64) (*ChatSettings, error) {
+	start := time.Now()
+	cs, err := o.s.GetChatSettings(ctx, chatID)
+	o.done("GetChatSettings", start, err)
+	return cs, err
+}
+
+func (o *observedStore) SetChatTimezone(ctx context.Context, chatID int64, timezone string) error {
+	start := time.Now()
+	err := o.s.SetChatTimezone(ctx, chatID, timezone)
+	o.done("SetChatTimezone", start, err)
+	return err
+}
+
+func (o *observedStore) SetChatDigest(ctx context.Context, chatID int64, d Digest) error {
+	start := time.Now()
+	err := o.s.SetChatDigest(ctx, chatID, d)
+	o.done("SetChatDigest", start, err)
+	return err
+}
+
+func (o *observedStore) ListDigestChats(ctx context.Context) ([]ChatSettings, error) {
+	start := time.Now()
+	chats, err := o.s.ListDigestChats(ctx)
+	o.done("ListDigestChats", start, err)
+	return chats, err
+}
+
+func (o *observedStore) ClaimDigest(ctx context.Context, chatID int64, slot time.Time) (bool, error) {
+	start := time.Now()
+	ok, err := o.s.ClaimDigest(ctx, chatID, slot)
+	o.done("ClaimDigest", start, err)
+	return ok, err
+}
+
+func (o *observedStore) ReleaseDigest(ctx context.Context, chatID int64, slot time.Time) error {
+	start := time.Now()
+	err := o.s.ReleaseDigest(ctx, chatID, slot)
+	o.done("ReleaseDigest", start, err)
+	return err
+}
+
+// SessionStore
+
+func (o *observedStore) GetSession(ctx context.Context, chatID, userID int64, kind string) (*Session, error) {
+	start := time.Now()
+	sess, err := o.s.GetSession(ctx, chatID, userID, kind)
+	o.done("GetSession", start, err)
+	return sess, err
+}
+
+func (o *observedStore) SetSession(ctx context.Context, sess *Session) error {
+	start := time.Now()
+	err := o.s.SetSession(ctx, sess)
+	o.done("SetSession", start, err)
+	return err
+}
+
+func (o *observedStore) DeleteSession(ctx context.Context, chatID, userID int64, kind string) error {
+	start := time.Now()
+	err := o.s.DeleteSession(ctx, chatID, userID, kind)
+	o.done("DeleteSession", start, err)
+	return err
+}
+
+func (o *observedStore) DeleteExpiredSessions(ctx context.Context, now time.Time) ([]Session, error) {
+	start := time.Now()
+	expired, err := o.s.DeleteExpiredSessions(ctx, now)
+	o.done("DeleteExpiredSessions", start, err)
+	return expired, err
+}
+
+func (o *observedStore) CountSessions(ctx context.Context) (map[string]int, error) {
+	start := time.Now()
+	counts, err := o.s.CountSessions(ctx)
+	o.done("CountSessions", start, err)
+	return counts, err
+}
+
+// PinStore
+
+func (o *observedStore) SetPin(ctx context.Context, p *Pin) error {
+	start := time.Now()
+	err := o.s.SetPin(ctx, p)
+	o.done("SetPin", start, err)
+	return err
+}
+
+func (o *observedStore) GetPin(ctx context.Context, eventID int64) (*Pin, error) {
+	start := time.Now()
+	p, err := o.s.GetPin(ctx, eventID)
+	o.done("GetPin", start, err)
+	return p, err
+}
+
+func (o *observedStore) DeletePin(ctx context.Context, eventID int64) error {
+	start := time.Now()
+	err := o.s.DeletePin(ctx, eventID)
+	o.done("DeletePin", start, err)
+	return err
+}
+
+func (o *observedStore) ClaimDuePins(ctx context.Context, now time.Time, lease time.Duration) ([]Pin, error) {
+	start := time.Now()
+	pins, err := o.s.ClaimDuePins(ctx, now, lease)
+	o.done("ClaimDuePins", start, err)
+	return pins, err
+}
+
+func (o *observedStore) ReschedulePin(ctx context.Context, eventID int64, next time.Time) error {
+	start := time.Now()
+	err := o.s.ReschedulePin(ctx, eventID, next)
+	o.done("ReschedulePin", start, err)
+	return err
+}
+
+// CollectionStore
+
+func (o *observedStore) CreateCollection(ctx context.Context, c *Collection) (bool, error) {
+	start := time.Now()
+	ok, err := o.s.CreateCollection(ctx, c)
+	o.done("CreateCollection", start, err)
+	return ok, err
+}
+
+func (o *observedStore) GetCollection(ctx context.Context, chatID int64) (*Collection, error) {
+	start := time.Now()
+	c, err := o.s.GetCollection(ctx, chatID)
+	o.done("GetCollection", start, err)
+	return c, err
+}
+
+func (o *observedStore) GetCollectionByCode(ctx context.Context, code string) (*Collection, error) {
+	start := time.Now()
+	c, err := o.s.GetCollectionByCode(ctx, code)
+	o.done("GetCollectionByCode", start, err)
+	return c, err
+}
+
+func (o *observedStore) DeleteCollection(ctx context.Context, chatID int64) (bool, error) {
+	start := time.Now()
+	ok, err := o.s.DeleteCollection(ctx, chatID)
+	o.done("DeleteCollection", start, err)
+	return ok, err
+}
+
+func (o *observedStore) Subscribe(ctx context.Context, chatID, sourceChatID int64) (bool, error) {
+	start := time.Now()
+	ok, err := o.s.Subscribe(ctx, chatID, sourceChatID)
+	o.done("Subscribe", start, err)
+	return ok, err
+}
+
+func (o *observedStore) Unsubscribe(ctx context.Context, chatID, sourceChatID int64) (bool, error) {
+	start := time.Now()
+	ok, err := o.s.Unsubscribe(ctx, chatID, sourceChatID)
+	o.done("Unsubscribe", start, err)
+	return ok, err
+}
+
+func (o *observedStore) ListSubscriptions(ctx context.Context, chatID int64) ([]Collection, error) {
+	start := time.Now()
+	collections, err := o.s.ListSubscriptions(ctx, chatID)
+	o.done("ListSubscriptions", start, err)
+	return collections, err
+}
+
+func (o *observedStore) FindSubscribedEvent(ctx context.Context, chatID int64, name string) (*Event, error) {
+	start := time.Now()
+	e, err := o.s.FindSubscribedEvent(ctx, chatID, name)
+	o.done("FindSubscribedEvent", start, err)
+	return e, err
+}
+
+// AdminStore
+
+func (o *observedStore) Stats(ctx context.Context) (*Stats, error) {
+	start := time.Now()
+	st, err := o.s.Stats(ctx)
+	o.done("Stats", start, err)
+	return st, err
+}
+
+func (o *observedStore) ListChatStats(ctx context.Context, offset, limit int) ([]ChatStats, int, error) {
+	start := time.Now()
+	chats, total, err := o.s.ListChatStats(ctx, offset, limit)
+	o.done("ListChatStats", start, err)
+	return chats, total, err
+}
+
+func (o *observedStore) ListKnownChats(ctx context.Context) ([]int64, error) {
+	start := time.Now()
+	chats, err := o.s.ListKnownChats(ctx)
+	o.done("ListKnownChats", start, err)
+	return chats, err
+}
+
+func (o *observedStore) PurgeChat(ctx context.Context, chatID int64) (int, error) {
+	start := time.Now()
+	n, err := o.s.PurgeChat(ctx, chatID)
+	o.done("PurgeChat", start, err)
+	return n, err
+}
+
+func (o *observedStore) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := o.s.Ping(ctx)
+	o.done("Ping", start, err)
+	return err
+}
+
+func (o *observedStore) Close() {
+	o.s.Close()
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------- Pinned countdowns ----------
+
+// Pin — закреплённое сообщение с обратным отсчётом до события (/pin). У события не больше одного.
+type Pin struct {
+	EventID      int64
+	ChatID       int64
+	MessageID    int
+	NextUpdateAt time.Time // когда сообщение нужно обновить в следующий раз
+}
+
+// SetPin создаёт или заменяет закреплённое сообщение события.
+func (s *PostgresStorage) SetPin(ctx context.Context, p *Pin) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO pinned_countdowns (event_id, chat_id, message_id, next_update_at) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (event_id) DO UPDATE SET chat_id = EXCLUDED.chat_id, message_id = EXCLUDED.message_id,
+		 next_update_at = EXCLUDED.next_update_at, created_at = now()`,
+		p.EventID, p.ChatID, p.MessageID, p.NextUpdateAt,
+	)
+	return err
+}
+
+// GetPin возвращает закреплённое сообщение события или ErrNotFound.
+func (s *PostgresStorage) GetPin(ctx context.Context, eventID int64) (*Pin, error) {
+	p := Pin{EventID: eventID}
+	err := s.pool.QueryRow(ctx,
+		`SELECT chat_id, message_id, next_update_at FROM pinned_countdowns WHERE event_id = $1`,
+		eventID,
+	).Scan(&p.ChatID, &p.MessageID, &p.NextUpdateAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// DeletePin удаляет закреплённое сообщение события. Отсутствие записи ошибкой не считается.
+func (s *PostgresStorage) DeletePin(ctx context.Context, eventID int64) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM pinned_countdowns WHERE event_id = $1`, eventID)
+	return err
+}
+
+// ClaimDuePins возвращает сообщения, которые пора обновить к моменту now, и откладывает
+// их следующее обновление на lease. Выборка и сдвиг атомарны: каждое сообщение получает
+// одна реплика, а если она не успеет обновить его, через lease сообщение получит другая.
+func (s *PostgresStorage) ClaimDuePins(ctx context.Context, now time.Time, lease time.Duration) ([]Pin, error) {
+	rows, err := s.pool.Query(ctx,
+		`UPDATE pinned_countdowns SET next_update_at = $2 WHERE next_update_at <= $1
+		 RETURNING event_id, chat_id, message_id, next_update_at`,
+		now, now.Add(lease),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Pin, error) {
+		var p Pin
+		err := row.Scan(&p.EventID, &p.ChatID, &p.MessageID, &p.NextUpdateAt)
+		return p, err
+	})
+}
+
+// ReschedulePin назначает следующее обновление сообщения события. Если сообщения нет, ничего не делает.
+func (s *PostgresStorage) ReschedulePin(ctx context.Context, eventID int64, next time.Time) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE pinned_countdowns SET next_update_at = $2 WHERE event_id = $1`,
+		eventID, next,
+	)
+	return err
+}
@@ -2,30 +2,33 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// PostgresStorage реализует хранилище на базе PostgreSQL с пулом соединений (pgxpool).
+// PostgresStorage реализует Store на базе PostgreSQL с пулом соединений (pgxpool).
 type PostgresStorage struct {
 	pool *pgxpool.Pool
-}
 
-// NewPostgresStorage создаёт подключение к PostgreSQL, применяет миграции и возвращает *PostgresStorage.
-// Принимает строку подключения (config.DatabaseURL).
-func NewPostgresStorage(dbURL string) *PostgresStorage {
-	if dbURL == "" {
-		panic("DATABASE_URL is not set (pass config.DatabaseURL)")
-	}
+	// legacyLoc — часовой пояс для дат, сохранённых текстом до перехода на TIMESTAMPTZ,
+	// если у чата нет собственного часового пояса
+	legacyLoc *time.Location
+}
 
+// OpenPostgresStorage подключается к PostgreSQL без применения миграций
+// (например, для команды migrate status).
+func OpenPostgresStorage(dbURL string, defaultLoc *time.Location) (*PostgresStorage, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	poolCfg, err := pgxpool.ParseConfig(dbURL)
 	if err != nil {
-		panic(fmt.Sprintf("failed to parse DATABASE_URL: %v", err))
+		return nil, fmt.Errorf("failed to parse DATABASE_URL: %v", err)
 	}
 
 	// Настройки пула
@@ -36,17 +39,19 @@ func NewPostgresStorage(dbURL string) *PostgresStorage {
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
-		panic(fmt.Sprintf("failed to connect to postgres: %v", err))
+		return nil, fmt.Errorf("failed to connect to postgres: %v", err)
 	}
 
 	// Проверка подключения
 	if err := pool.Ping(ctx); err != nil {
-		panic(fmt.Sprintf("failed to ping postgres: %v", err))
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %v", err)
 	}
 
-	s := &PostgresStorage{pool: pool}
-	s.migrate(ctx)
-	return s
+	if defaultLoc == nil {
+		defaultLoc = time.UTC
+	}
+	return &PostgresStorage{pool: pool, legacyLoc: defaultLoc}, nil
 }
 
 // Close закрывает пул соединений. Вызывайте при завершении приложения.
@@ -64,97 +69,152 @@ func (s *PostgresStorage) Pool() *pgxpool.Pool {
 	return s.pool
 }
 
-// ---------- миграции ----------
-
-func (s *PostgresStorage) migrate(ctx context.Context) {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS events (
-			id          BIGSERIAL PRIMARY KEY,
-			chat_id     BIGINT      NOT NULL,
-			name        TEXT        NOT NULL,
-			date        TEXT        NOT NULL,
-			description TEXT        NOT NULL DEFAULT '',
-			status      TEXT        NOT NULL DEFAULT 'active',
-			created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
-			UNIQUE(chat_id, name)
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_events_chat_id ON events (chat_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_events_name    ON events (name)`,
-
-		`CREATE TABLE IF NOT EXISTS user_events (
-			id       BIGSERIAL PRIMARY KEY,
-			chat_id  BIGINT NOT NULL,
-			user_id  BIGINT NOT NULL,
-			event_id BIGINT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
-			UNIQUE(chat_id, user_id, event_id)
-		)`,
-	}
-
-	for _, q := range queries {
-		if _, err := s.pool.Exec(ctx, q); err != nil {
-			panic(fmt.Sprintf("migration failed: %v\nquery: %s", err, q))
-		}
+// PoolStats возвращает состояние пула соединений.
+func (s *PostgresStorage) PoolStats() PoolStats {
+	st := s.pool.Stat()
+	return PoolStats{
+		MaxConns:     int(st.MaxConns()),
+		OpenConns:    int(st.TotalConns()),
+		InUseConns:   int(st.AcquiredConns()),
+		IdleConns:    int(st.IdleConns()),
+		WaitCount:    st.EmptyAcquireCount(),
+		WaitDuration: st.EmptyAcquireWaitTime(),
 	}
 }
 
 // ---------- Events CRUD ----------
 
-// CreateEvent создаёт событие. Возвращает ошибку, если событие с таким именем в чате уже существует.
-func (s *PostgresStorage) CreateEvent(ctx context.Context, chatID int64, name, date, description string) error {
-	_, err := s.pool.Exec(ctx,
-		`INSERT INTO events (chat_id, name, date, description) VALUES ($1, $2, $3, $4)`,
-		chatID, name, date, description,
-	)
+// eventColumns — колонки events в порядке, ожидаемом scanEvent.
+const eventColumns = `id, chat_id, name, title, date, description, status, recurrence, created_at`
+
+func scanEvent(row pgx.Row) (*Event, error) {
+	var e Event
+	if err := row.Scan(&e.ID, &e.ChatID, &e.Name, &e.Title, &e.Date, &e.Description, &e.Status, &e.Recurrence, &e.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+// isUniqueViolation сообщает, нарушено ли ограничение уникальности (SQLSTATE 23505).
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func collectEvents(rows pgx.Rows) ([]Event, error) {
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+// CreateEvent создаёт событие и заполняет e.ID и e.CreatedAt.
+// Возвращает ErrEventExists, если событие с таким именем в чате уже существует.
+func (s *PostgresStorage) CreateEvent(ctx context.Context, e *Event) error {
+	if e.Status == "" {
+		e.Status = StatusActive
+	}
+	defaultTitle(e)
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO events (chat_id, name, title, date, description, status, recurrence) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		e.ChatID, e.Name, e.Title, e.Date, e.Description, e.Status, e.Recurrence,
+	).Scan(&e.ID, &e.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrEventExists
+	}
 	return err
 }
 
 // GetEvent возвращает событие по chat_id + name.
 func (s *PostgresStorage) GetEvent(ctx context.Context, chatID int64, name string) (*Event, error) {
-	row := s.pool.QueryRow(ctx,
-		`SELECT id, chat_id, name, date, description, status FROM events WHERE chat_id = $1 AND name = $2`,
+	return scanEvent(s.pool.QueryRow(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE chat_id = $1 AND name = $2`,
 		chatID, name,
-	)
-	var e Event
-	if err := row.Scan(&e.ID, &e.ChatID, &e.Name, &e.Date, &e.Description, &e.Status); err != nil {
-		return nil, err
-	}
-	return &e, nil
+	))
+}
+
+// GetEventByID возвращает событие по его id.
+func (s *PostgresStorage) GetEventByID(ctx context.Context, id int64) (*Event, error) {
+	return scanEvent(s.pool.QueryRow(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE id = $1`,
+		id,
+	))
 }
 
 // ListEvents возвращает все события чата.
 func (s *PostgresStorage) ListEvents(ctx context.Context, chatID int64) ([]Event, error) {
 	rows, err := s.pool.Query(ctx,
-		`SELECT id, chat_id, name, date, description, status FROM events WHERE chat_id = $1 ORDER BY created_at`,
+		`SELECT `+eventColumns+` FROM events WHERE chat_id = $1 ORDER BY date, id`,
 		chatID,
 	)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return collectEvents(rows)
+}
 
-	var events []Event
-	for rows.Next() {
-		var e Event
-		if err := rows.Scan(&e.ID, &e.ChatID, &e.Name, &e.Date, &e.Description, &e.Status); err != nil {
-			return nil, err
-		}
-		events = append(events, e)
+// ListEventsBetween возвращает события чата с датой в полуинтервале [from, to), по возрастанию даты.
+// Повторяющиеся события попадают в выборку по дате первого вхождения.
+func (s *PostgresStorage) ListEventsBetween(ctx context.Context, chatID int64, from, to time.Time) ([]Event, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE chat_id = $1 AND date >= $2 AND date < $3 ORDER BY date, id`,
+		chatID, from, to,
+	)
+	if err != nil {
+		return nil, err
 	}
-	return events, rows.Err()
+	return collectEvents(rows)
 }
 
-// FindEventAcrossChats ищет событие по имени во всех чатах, исключая excludeChatID.
-// Возвращает событие и chat_id, в котором оно найдено.
-func (s *PostgresStorage) FindEventAcrossChats(ctx context.Context, name string, excludeChatID int64) (*Event, int64, error) {
-	row := s.pool.QueryRow(ctx,
-		`SELECT id, chat_id, name, date, description, status FROM events WHERE name = $1 AND chat_id <> $2 LIMIT 1`,
-		name, excludeChatID,
+// ListAllEventsBetween возвращает события всех чатов с датой в полуинтервале [from, to).
+func (s *PostgresStorage) ListAllEventsBetween(ctx context.Context, from, to time.Time) ([]Event, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE date >= $1 AND date < $2 ORDER BY date, id`,
+		from, to,
 	)
-	var e Event
-	if err := row.Scan(&e.ID, &e.ChatID, &e.Name, &e.Date, &e.Description, &e.Status); err != nil {
-		return nil, 0, err
+	if err != nil {
+		return nil, err
 	}
-	return &e, e.ChatID, nil
+	return collectEvents(rows)
+}
+
+// ListEventChats возвращает id чатов, в которых есть хотя бы одно событие, по возрастанию.
+func (s *PostgresStorage) ListEventChats(ctx context.Context) ([]int64, error) {
+	rows, err := s.pool.Query(ctx, `SELECT DISTINCT chat_id FROM events ORDER BY chat_id`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// UpdateEvent сохраняет имя, название, дату, описание, статус и правило повторения события с id e.ID.
+// Возвращает ErrEventExists, если новое название уже занято в чате, и ErrNotFound, если события нет.
+func (s *PostgresStorage) UpdateEvent(ctx context.Context, e *Event) error {
+	defaultTitle(e)
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE events SET name = $1, title = $2, date = $3, description = $4, status = $5, recurrence = $6 WHERE id = $7`,
+		e.Name, e.Title, e.Date, e.Description, e.Status, e.Recurrence, e.ID,
+	)
+	if isUniqueViolation(err) {
+		return ErrEventExists
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
 // UpdateEventStatus обновляет статус события.
@@ -166,6 +226,17 @@ func (s *PostgresStorage) UpdateEventStatus(ctx context.Context, chatID int64, n
 	return err
 }
 
+// UpdateEventRecurrence задаёт правило повторения события.
+// Повторяющееся событие снова становится активным, даже если ранее было помечено устаревшим.
+func (s *PostgresStorage) UpdateEventRecurrence(ctx context.Context, chatID int64, name, recurrence string) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE events SET recurrence = $1, status = CASE WHEN $1 <> '' THEN 'active' ELSE status END
+		 WHERE chat_id = $2 AND name = $3`,
+		recurrence, chatID, name,
+	)
+	return err
+}
+
 // DeleteEvent удаляет событие.
 func (s *PostgresStorage) DeleteEvent(ctx context.Context, chatID int64, name string) error {
 	_, err := s.pool.Exec(ctx,
@@ -186,17 +257,12 @@ func (s *PostgresStorage) AddEventToUser(ctx context.Context, chatID, userID, ev
 	return err
 }
 
-// ---------- модель (локальная, пока нет пакета models) ----------
-
-// Event — строка таблицы events.
-type Event struct {
-	ID          int64
-	ChatID      int64
-	Name        string
-	Date        string
-	Description string
-	Status      string
+// IsEventOwner сообщает, привязано ли событие к пользователю (создал ли он его).
+func (s *PostgresStorage) IsEventOwner(ctx context.Context, eventID, userID int64) (bool, error) {
+	var owner bool
+	err := s.pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM user_events WHERE event_id = $1 AND user_id = $2)`,
+		eventID, userID,
+	).Scan(&owner)
+	return owner, err
 }
-
-// cfg := config.LoadConfig()
-// store := storage.NewPostgresStorage(cfg.DatabaseURL)
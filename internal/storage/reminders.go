@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// ---------- Reminders ----------
+
+// ListActiveEvents возвращает активные события всех чатов — для фонового планировщика напоминаний.
+func (s *PostgresStorage) ListActiveEvents(ctx context.Context) ([]Event, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE status = 'active' ORDER BY id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectEvents(rows)
+}
+
+// ListSentReminders возвращает смещения напоминаний, уже отправленных для события с указанной датой.
+// Дата входит в ключ, чтобы после переноса события (или для очередного вхождения
+// повторяющегося события) напоминания сработали заново.
+func (s *PostgresStorage) ListSentReminders(ctx context.Context, eventID int64, eventDate time.Time) ([]time.Duration, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT offset_seconds FROM sent_reminders WHERE event_id = $1 AND event_date = $2`,
+		eventID, eventDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offsets []time.Duration
+	for rows.Next() {
+		var sec int64
+		if err := rows.Scan(&sec); err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, time.Duration(sec)*time.Second)
+	}
+	return offsets, rows.Err()
+}
+
+// ClaimReminder атомарно помечает напоминание как отправленное.
+// Возвращает false, если оно уже было отмечено (например, другой репликой) — тогда отправлять не нужно.
+func (s *PostgresStorage) ClaimReminder(ctx context.Context, eventID int64, eventDate time.Time, offset time.Duration) (bool, error) {
+	tag, err := s.pool.Exec(ctx,
+		`INSERT INTO sent_reminders (event_id, event_date, offset_seconds) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		eventID, eventDate, int64(offset/time.Second),
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseReminder снимает отметку об отправке, если доставить напоминание не удалось,
+// чтобы планировщик повторил попытку на следующем проходе.
+func (s *PostgresStorage) ReleaseReminder(ctx context.Context, eventID int64, eventDate time.Time, offset time.Duration) error {
+	_, err := s.pool.Exec(ctx,
+		`DELETE FROM sent_reminders WHERE event_id = $1 AND event_date = $2 AND offset_seconds = $3`,
+		eventID, eventDate, int64(offset/time.Second),
+	)
+	return err
+}
+
+// ---------- Per-event reminder offsets ----------
+
+// ListEventReminders возвращает собственные смещения напоминаний события (по убыванию).
+// Пустой список означает, что для события действуют смещения по умолчанию.
+func (s *PostgresStorage) ListEventReminders(ctx context.Context, eventID int64) ([]time.Duration, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT offset_seconds FROM event_reminders WHERE event_id = $1 ORDER BY offset_seconds DESC`,
+		eventID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offsets []time.Duration
+	for rows.Next() {
+		var sec int64
+		if err := rows.Scan(&sec); err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, time.Duration(sec)*time.Second)
+	}
+	return offsets, rows.Err()
+}
+
+// AddEventReminder добавляет смещение напоминания событию. Повторное добавление игнорируется.
+func (s *PostgresStorage) AddEventReminder(ctx context.Context, eventID int64, offset time.Duration) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO event_reminders (event_id, offset_seconds) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		eventID, int64(offset/time.Second),
+	)
+	return err
+}
+
+// DeleteEventReminder удаляет смещение напоминания. Возвращает false, если такого смещения не было.
+func (s *PostgresStorage) DeleteEventReminder(ctx context.Context, eventID int64, offset time.Duration) (bool, error) {
+	tag, err := s.pool.Exec(ctx,
+		`DELETE FROM event_reminders WHERE event_id = $1 AND offset_seconds = $2`,
+		eventID, int64(offset/time.Second),
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ClearEventReminders удаляет все собственные смещения события (возврат к смещениям по умолчанию).
+func (s *PostgresStorage) ClearEventReminders(ctx context.Context, eventID int64) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM event_reminders WHERE event_id = $1`, eventID)
+	return err
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------- Sessions ----------
+
+// Session — состояние многошагового диалога пользователя в чате. Один пользователь может
+// одновременно находиться в нескольких диалогах разных видов (Kind).
+type Session struct {
+	ChatID    int64
+	UserID    int64
+	Kind      string
+	Data      []byte    // состояние диалога (JSON), формат определяет вызывающий код
+	CreatedAt time.Time // начало диалога; заполняется хранилищем и сохраняется при продлении сессии
+	ExpiresAt time.Time
+}
+
+// GetSession возвращает сессию. Истёкшая сессия считается отсутствующей: возвращается ErrNotFound.
+func (s *PostgresStorage) GetSession(ctx context.Context, chatID, userID int64, kind string) (*Session, error) {
+	sess := Session{ChatID: chatID, UserID: userID, Kind: kind}
+	err := s.pool.QueryRow(ctx,
+		`SELECT data, created_at, expires_at FROM sessions
+		 WHERE chat_id = $1 AND user_id = $2 AND kind = $3 AND expires_at > now()`,
+		chatID, userID, kind,
+	).Scan(&sess.Data, &sess.CreatedAt, &sess.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+// SetSession создаёт или заменяет сессию (chat_id, user_id, kind) и заполняет sess.CreatedAt.
+// Продление живой сессии сохраняет время её создания, истёкшая сессия заменяется новой.
+func (s *PostgresStorage) SetSession(ctx context.Context, sess *Session) error {
+	return s.pool.QueryRow(ctx,
+		`INSERT INTO sessions (chat_id, user_id, kind, data, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (chat_id, user_id, kind) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at,
+		 created_at = CASE WHEN sessions.expires_at > EXCLUDED.created_at THEN sessions.created_at ELSE EXCLUDED.created_at END
+		 RETURNING created_at`,
+		sess.ChatID, sess.UserID, sess.Kind, string(sess.Data), time.Now(), sess.ExpiresAt,
+	).Scan(&sess.CreatedAt)
+}
+
+// DeleteSession удаляет сессию. Отсутствие сессии ошибкой не считается.
+func (s *PostgresStorage) DeleteSession(ctx context.Context, chatID, userID int64, kind string) error {
+	_, err := s.pool.Exec(ctx,
+		`DELETE FROM sessions WHERE chat_id = $1 AND user_id = $2 AND kind = $3`,
+		chatID, userID, kind,
+	)
+	return err
+}
+
+// DeleteExpiredSessions удаляет сессии, истёкшие к моменту now, и возвращает их.
+// Удаление и выборка атомарны: каждую истёкшую сессию получает только одна реплика бота.
+func (s *PostgresStorage) DeleteExpiredSessions(ctx context.Context, now time.Time) ([]Session, error) {
+	rows, err := s.pool.Query(ctx,
+		`DELETE FROM sessions WHERE expires_at <= $1
+		 RETURNING chat_id, user_id, kind, data, created_at, expires_at`,
+		now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Session, error) {
+		var sess Session
+		err := row.Scan(&sess.ChatID, &sess.UserID, &sess.Kind, &sess.Data, &sess.CreatedAt, &sess.ExpiresAt)
+		return sess, err
+	})
+}
+
+// CountSessions возвращает число живых сессий по видам.
+func (s *PostgresStorage) CountSessions(ctx context.Context) (map[string]int, error) {
+	rows, err := s.pool.Query(ctx, `SELECT kind, count(*) FROM sessions WHERE expires_at > now() GROUP BY kind`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			kind string
+			n    int
+		)
+		if err := rows.Scan(&kind, &n); err != nil {
+			return nil, err
+		}
+		counts[kind] = n
+	}
+	return counts, rows.Err()
+}
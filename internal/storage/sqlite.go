@@ -0,0 +1,909 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteStorage реализует Store на базе SQLite (modernc.org/sqlite, без cgo).
+// Рассчитан на небольшие установки без отдельного сервера БД: DATABASE_URL=sqlite:///data/bot.db.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+// sqliteTimeLayout — формат моментов времени в SQLite: UTC фиксированной ширины,
+// чтобы строки сравнивались и сортировались в хронологическом порядке.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+// OpenSQLiteStorage открывает (или создаёт) базу SQLite без применения миграций.
+// Принимает DATABASE_URL вида sqlite:///path/to/bot.db, sqlite://bot.db или file:bot.db.
+func OpenSQLiteStorage(dbURL string) (*SQLiteStorage, error) {
+	dsn, err := sqliteDSN(dbURL)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %v", err)
+	}
+	// SQLite допускает одного писателя; одно соединение исключает SQLITE_BUSY
+	// и делает проверки вида «вставить, если нет» атомарными
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite: %v", err)
+	}
+	return &SQLiteStorage{db: db}, nil
+}
+
+// sqliteDSN превращает DATABASE_URL в DSN драйвера и включает нужные PRAGMA:
+// внешние ключи (для ON DELETE CASCADE), ожидание блокировки и журнал WAL.
+func sqliteDSN(dbURL string) (string, error) {
+	var dsn string
+	switch {
+	case strings.HasPrefix(dbURL, "sqlite://"):
+		dsn = "file:" + strings.TrimPrefix(dbURL, "sqlite://")
+	case strings.HasPrefix(dbURL, "file:"):
+		dsn = dbURL
+	default:
+		return "", fmt.Errorf("unsupported sqlite DATABASE_URL %q (expected sqlite://path or file:path)", dbURL)
+	}
+	if dsn == "file:" {
+		return "", fmt.Errorf("sqlite DATABASE_URL %q has no database path", dbURL)
+	}
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", nil
+}
+
+// Close закрывает базу. Вызывайте при завершении приложения.
+func (s *SQLiteStorage) Close() {
+	s.db.Close()
+}
+
+// Ping проверяет доступность базы данных.
+func (s *SQLiteStorage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// DB возвращает *sql.DB для прямых запросов, если нужно.
+func (s *SQLiteStorage) DB() *sql.DB {
+	return s.db
+}
+
+// PoolStats возвращает состояние пула соединений database/sql.
+func (s *SQLiteStorage) PoolStats() PoolStats {
+	st := s.db.Stats()
+	return PoolStats{
+		MaxConns:     st.MaxOpenConnections,
+		OpenConns:    st.OpenConnections,
+		InUseConns:   st.InUse,
+		IdleConns:    st.Idle,
+		WaitCount:    st.WaitCount,
+		WaitDuration: st.WaitDuration,
+	}
+}
+
+func formatSQLiteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+func parseSQLiteTime(v string) (time.Time, error) {
+	return time.Parse(sqliteTimeLayout, v)
+}
+
+// parseSQLiteCreatedAt разбирает значение created_at по умолчанию (strftime с миллисекундами).
+func parseSQLiteCreatedAt(v string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, v)
+}
+
+// isSQLiteUniqueViolation сообщает, нарушено ли ограничение UNIQUE.
+func isSQLiteUniqueViolation(err error) bool {
+	var sqlErr *sqlite.Error
+	return errors.As(err, &sqlErr) && sqlErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// ---------- Events CRUD ----------
+
+func scanSQLiteEvent(row interface{ Scan(...any) error }) (*Event, error) {
+	var (
+		e             Event
+		date, created string
+	)
+	if err := row.Scan(&e.ID, &e.ChatID, &e.Name, &e.Title, &date, &e.Description, &e.Status, &e.Recurrence, &created); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	t, err := parseSQLiteTime(date)
+	if err != nil {
+		return nil, fmt.Errorf("event %d: invalid date %q: %v", e.ID, date, err)
+	}
+	e.Date = t
+	if e.CreatedAt, err = parseSQLiteCreatedAt(created); err != nil {
+		return nil, fmt.Errorf("event %d: invalid created_at %q: %v", e.ID, created, err)
+	}
+	return &e, nil
+}
+
+func (s *SQLiteStorage) queryEvents(ctx context.Context, query string, args ...any) ([]Event, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		e, err := scanSQLiteEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+// CreateEvent создаёт событие и заполняет e.ID и e.CreatedAt.
+// Возвращает ErrEventExists, если событие с таким именем в чате уже существует.
+func (s *SQLiteStorage) CreateEvent(ctx context.Context, e *Event) error {
+	if e.Status == "" {
+		e.Status = StatusActive
+	}
+	defaultTitle(e)
+	var created string
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO events (chat_id, name, title, date, description, status, recurrence) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id, created_at`,
+		e.ChatID, e.Name, e.Title, formatSQLiteTime(e.Date), e.Description, e.Status, e.Recurrence,
+	).Scan(&e.ID, &created)
+	if isSQLiteUniqueViolation(err) {
+		return ErrEventExists
+	}
+	if err != nil {
+		return err
+	}
+	e.CreatedAt, err = parseSQLiteCreatedAt(created)
+	return err
+}
+
+// GetEvent возвращает событие по chat_id + name.
+func (s *SQLiteStorage) GetEvent(ctx context.Context, chatID int64, name string) (*Event, error) {
+	return scanSQLiteEvent(s.db.QueryRowContext(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE chat_id = ? AND name = ?`,
+		chatID, name,
+	))
+}
+
+// GetEventByID возвращает событие по его id.
+func (s *SQLiteStorage) GetEventByID(ctx context.Context, id int64) (*Event, error) {
+	return scanSQLiteEvent(s.db.QueryRowContext(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE id = ?`,
+		id,
+	))
+}
+
+// ListEvents возвращает все события чата.
+func (s *SQLiteStorage) ListEvents(ctx context.Context, chatID int64) ([]Event, error) {
+	return s.queryEvents(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE chat_id = ? ORDER BY date, id`,
+		chatID,
+	)
+}
+
+// ListEventsBetween возвращает события чата с датой в полуинтервале [from, to), по возрастанию даты.
+func (s *SQLiteStorage) ListEventsBetween(ctx context.Context, chatID int64, from, to time.Time) ([]Event, error) {
+	return s.queryEvents(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE chat_id = ? AND date >= ? AND date < ? ORDER BY date, id`,
+		chatID, formatSQLiteTime(from), formatSQLiteTime(to),
+	)
+}
+
+// ListAllEventsBetween возвращает события всех чатов с датой в полуинтервале [from, to).
+func (s *SQLiteStorage) ListAllEventsBetween(ctx context.Context, from, to time.Time) ([]Event, error) {
+	return s.queryEvents(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE date >= ? AND date < ? ORDER BY date, id`,
+		formatSQLiteTime(from), formatSQLiteTime(to),
+	)
+}
+
+// ListEventChats возвращает id чатов, в которых есть хотя бы одно событие, по возрастанию.
+func (s *SQLiteStorage) ListEventChats(ctx context.Context) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT chat_id FROM events ORDER BY chat_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		chats = append(chats, id)
+	}
+	return chats, rows.Err()
+}
+
+// UpdateEvent сохраняет имя, название, дату, описание, статус и правило повторения события с id e.ID.
+// Возвращает ErrEventExists, если новое название уже занято в чате, и ErrNotFound, если события нет.
+func (s *SQLiteStorage) UpdateEvent(ctx context.Context, e *Event) error {
+	defaultTitle(e)
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE events SET name = ?, title = ?, date = ?, description = ?, status = ?, recurrence = ? WHERE id = ?`,
+		e.Name, e.Title, formatSQLiteTime(e.Date), e.Description, e.Status, e.Recurrence, e.ID,
+	)
+	if isSQLiteUniqueViolation(err) {
+		return ErrEventExists
+	}
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateEventStatus обновляет статус события.
+func (s *SQLiteStorage) UpdateEventStatus(ctx context.Context, chatID int64, name, status string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE events SET status = ? WHERE chat_id = ? AND name = ?`,
+		status, chatID, name,
+	)
+	return err
+}
+
+// UpdateEventRecurrence задаёт правило повторения события.
+// Повторяющееся событие снова становится активным, даже если ранее было помечено устаревшим.
+func (s *SQLiteStorage) UpdateEventRecurrence(ctx context.Context, chatID int64, name, recurrence string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE events SET recurrence = ?1, status = CASE WHEN ?1 <> '' THEN 'active' ELSE status END
+		 WHERE chat_id = ?2 AND name = ?3`,
+		recurrence, chatID, name,
+	)
+	return err
+}
+
+// DeleteEvent удаляет событие.
+func (s *SQLiteStorage) DeleteEvent(ctx context.Context, chatID int64, name string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM events WHERE chat_id = ? AND name = ?`,
+		chatID, name,
+	)
+	return err
+}
+
+// ---------- User-Events ----------
+
+// AddEventToUser привязывает событие к пользователю.
+func (s *SQLiteStorage) AddEventToUser(ctx context.Context, chatID, userID, eventID int64) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO user_events (chat_id, user_id, event_id) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`,
+		chatID, userID, eventID,
+	)
+	return err
+}
+
+// IsEventOwner сообщает, привязано ли событие к пользователю (создал ли он его).
+func (s *SQLiteStorage) IsEventOwner(ctx context.Context, eventID, userID int64) (bool, error) {
+	var owner bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM user_events WHERE event_id = ? AND user_id = ?)`,
+		eventID, userID,
+	).Scan(&owner)
+	return owner, err
+}
+
+// ---------- Reminders ----------
+
+// ListActiveEvents возвращает активные события всех чатов — для фонового планировщика напоминаний.
+func (s *SQLiteStorage) ListActiveEvents(ctx context.Context) ([]Event, error) {
+	return s.queryEvents(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE status = 'active' ORDER BY id`,
+	)
+}
+
+func (s *SQLiteStorage) queryOffsets(ctx context.Context, query string, args ...any) ([]time.Duration, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offsets []time.Duration
+	for rows.Next() {
+		var sec int64
+		if err := rows.Scan(&sec); err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, time.Duration(sec)*time.Second)
+	}
+	return offsets, rows.Err()
+}
+
+// ListSentReminders возвращает смещения напоминаний, уже отправленных для события с указанной датой.
+func (s *SQLiteStorage) ListSentReminders(ctx context.Context, eventID int64, eventDate time.Time) ([]time.Duration, error) {
+	return s.queryOffsets(ctx,
+		`SELECT offset_seconds FROM sent_reminders WHERE event_id = ? AND event_date = ?`,
+		eventID, formatSQLiteTime(eventDate),
+	)
+}
+
+// ClaimReminder атомарно помечает напоминание как отправленное.
+// Возвращает false, если оно уже было отмечено — тогда отправлять не нужно.
+func (s *SQLiteStorage) ClaimReminder(ctx context.Context, eventID int64, eventDate time.Time, offset time.Duration) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO sent_reminders (event_id, event_date, offset_seconds) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`,
+		eventID, formatSQLiteTime(eventDate), int64(offset/time.Second),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReleaseReminder снимает отметку об отправке, если доставить напоминание не удалось.
+func (s *SQLiteStorage) ReleaseReminder(ctx context.Context, eventID int64, eventDate time.Time, offset time.Duration) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM sent_reminders WHERE event_id = ? AND event_date = ? AND offset_seconds = ?`,
+		eventID, formatSQLiteTime(eventDate), int64(offset/time.Second),
+	)
+	return err
+}
+
+// ---------- Per-event reminder offsets ----------
+
+// ListEventReminders возвращает собственные смещения напоминаний события (по убыванию).
+func (s *SQLiteStorage) ListEventReminders(ctx context.Context, eventID int64) ([]time.Duration, error) {
+	return s.queryOffsets(ctx,
+		`SELECT offset_seconds FROM event_reminders WHERE event_id = ? ORDER BY offset_seconds DESC`,
+		eventID,
+	)
+}
+
+// AddEventReminder добавляет смещение напоминания событию. Повторное добавление игнорируется.
+func (s *SQLiteStorage) AddEventReminder(ctx context.Context, eventID int64, offset time.Duration) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO event_reminders (event_id, offset_seconds) VALUES (?, ?) ON CONFLICT DO NOTHING`,
+		eventID, int64(offset/time.Second),
+	)
+	return err
+}
+
+// DeleteEventReminder удаляет смещение напоминания. Возвращает false, если такого смещения не было.
+func (s *SQLiteStorage) DeleteEventReminder(ctx context.Context, eventID int64, offset time.Duration) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM event_reminders WHERE event_id = ? AND offset_seconds = ?`,
+		eventID, int64(offset/time.Second),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ClearEventReminders удаляет все собственные смещения события.
+func (s *SQLiteStorage) ClearEventReminders(ctx context.Context, eventID int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM event_reminders WHERE event_id = ?`, eventID)
+	return err
+}
+
+// ---------- Chat settings ----------
+
+// GetChatSettings возвращает настройки чата (пустые, если чат ничего не настраивал).
+func (s *SQLiteStorage) GetChatSettings(ctx context.Context, chatID int64) (*ChatSettings, error) {
+	var cs ChatSettings
+	err := scanSQLiteChatSettings(s.db.QueryRowContext(ctx,
+		`SELECT `+chatSettingsColumns+` FROM chat_settings WHERE chat_id = ?`,
+		chatID,
+	), &cs)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &ChatSettings{ChatID: chatID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cs, nil
+}
+
+func scanSQLiteChatSettings(row interface{ Scan(...any) error }, cs *ChatSettings) error {
+	var weekday int
+	if err := row.Scan(&cs.ChatID, &cs.Timezone, &cs.Digest.Schedule, &weekday, &cs.Digest.Minute, &cs.Digest.Days); err != nil {
+		return err
+	}
+	cs.Digest.Weekday = time.Weekday(weekday)
+	return nil
+}
+
+// SetChatTimezone сохраняет часовой пояс чата. Пустая строка возвращает пояс по умолчанию.
+func (s *SQLiteStorage) SetChatTimezone(ctx context.Context, chatID int64, timezone string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO chat_settings (chat_id, timezone) VALUES (?, ?)
+		 ON CONFLICT (chat_id) DO UPDATE SET timezone = excluded.timezone,
+		 updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')`,
+		chatID, timezone,
+	)
+	return err
+}
+
+// SetChatDigest сохраняет расписание дайджеста чата. Пустой Schedule выключает дайджест.
+func (s *SQLiteStorage) SetChatDigest(ctx context.Context, chatID int64, d Digest) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO chat_settings (chat_id, digest, digest_weekday, digest_minute, digest_days) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (chat_id) DO UPDATE SET digest = excluded.digest, digest_weekday = excluded.digest_weekday,
+		 digest_minute = excluded.digest_minute, digest_days = excluded.digest_days,
+		 updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')`,
+		chatID, d.Schedule, int(d.Weekday), d.Minute, d.Days,
+	)
+	return err
+}
+
+// ListDigestChats возвращает настройки чатов с включённым дайджестом.
+func (s *SQLiteStorage) ListDigestChats(ctx context.Context) ([]ChatSettings, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+chatSettingsColumns+` FROM chat_settings WHERE digest <> '' ORDER BY chat_id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []ChatSettings
+	for rows.Next() {
+		var cs ChatSettings
+		if err := scanSQLiteChatSettings(rows, &cs); err != nil {
+			return nil, err
+		}
+		chats = append(chats, cs)
+	}
+	return chats, rows.Err()
+}
+
+// ClaimDigest атомарно помечает выпуск дайджеста (момент отправки slot) как отправленный.
+// Возвращает false, если он уже был отмечен — тогда отправлять не нужно.
+func (s *SQLiteStorage) ClaimDigest(ctx context.Context, chatID int64, slot time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO sent_digests (chat_id, slot) VALUES (?, ?) ON CONFLICT DO NOTHING`,
+		chatID, formatSQLiteTime(slot),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReleaseDigest снимает отметку об отправке, если доставить дайджест не удалось.
+func (s *SQLiteStorage) ReleaseDigest(ctx context.Context, chatID int64, slot time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM sent_digests WHERE chat_id = ? AND slot = ?`,
+		chatID, formatSQLiteTime(slot),
+	)
+	return err
+}
+
+// ---------- Sessions ----------
+
+func scanSQLiteSession(row interface{ Scan(...any) error }, sess *Session) error {
+	var created, expires string
+	if err := row.Scan(&sess.ChatID, &sess.UserID, &sess.Kind, &sess.Data, &created, &expires); err != nil {
+		return err
+	}
+	var err error
+	if sess.CreatedAt, err = parseSQLiteTime(created); err != nil {
+		return fmt.Errorf("session %d:%d:%s: invalid created_at %q: %v", sess.ChatID, sess.UserID, sess.Kind, created, err)
+	}
+	if sess.ExpiresAt, err = parseSQLiteTime(expires); err != nil {
+		return fmt.Errorf("session %d:%d:%s: invalid expires_at %q: %v", sess.ChatID, sess.UserID, sess.Kind, expires, err)
+	}
+	return nil
+}
+
+// sessionColumns — колонки sessions в порядке, ожидаемом scanSQLiteSession.
+const sessionColumns = `chat_id, user_id, kind, data, created_at, expires_at`
+
+// GetSession возвращает сессию. Истёкшая сессия считается отсутствующей: возвращается ErrNotFound.
+func (s *SQLiteStorage) GetSession(ctx context.Context, chatID, userID int64, kind string) (*Session, error) {
+	var sess Session
+	err := scanSQLiteSession(s.db.QueryRowContext(ctx,
+		`SELECT `+sessionColumns+` FROM sessions
+		 WHERE chat_id = ? AND user_id = ? AND kind = ? AND expires_at > ?`,
+		chatID, userID, kind, formatSQLiteTime(time.Now()),
+	), &sess)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+// SetSession создаёт или заменяет сессию (chat_id, user_id, kind) и заполняет sess.CreatedAt.
+// Продление живой сессии сохраняет время её создания, истёкшая сессия заменяется новой.
+func (s *SQLiteStorage) SetSession(ctx context.Context, sess *Session) error {
+	var created string
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO sessions (chat_id, user_id, kind, data, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (chat_id, user_id, kind) DO UPDATE SET data = excluded.data, expires_at = excluded.expires_at,
+		 created_at = CASE WHEN sessions.expires_at > excluded.created_at THEN sessions.created_at ELSE excluded.created_at END
+		 RETURNING created_at`,
+		sess.ChatID, sess.UserID, sess.Kind, string(sess.Data), formatSQLiteTime(time.Now()), formatSQLiteTime(sess.ExpiresAt),
+	).Scan(&created)
+	if err != nil {
+		return err
+	}
+	sess.CreatedAt, err = parseSQLiteTime(created)
+	return err
+}
+
+// DeleteSession удаляет сессию. Отсутствие сессии ошибкой не считается.
+func (s *SQLiteStorage) DeleteSession(ctx context.Context, chatID, userID int64, kind string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM sessions WHERE chat_id = ? AND user_id = ? AND kind = ?`,
+		chatID, userID, kind,
+	)
+	return err
+}
+
+// DeleteExpiredSessions удаляет сессии, истёкшие к моменту now, и возвращает их.
+func (s *SQLiteStorage) DeleteExpiredSessions(ctx context.Context, now time.Time) ([]Session, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`DELETE FROM sessions WHERE expires_at <= ? RETURNING `+sessionColumns,
+		formatSQLiteTime(now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var sess Session
+		if err := scanSQLiteSession(rows, &sess); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	return sessions, rows.Err()
+}
+
+// CountSessions возвращает число живых сессий по видам.
+func (s *SQLiteStorage) CountSessions(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT kind, count(*) FROM sessions WHERE expires_at > ? GROUP BY kind`,
+		formatSQLiteTime(time.Now()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			kind string
+			n    int
+		)
+		if err := rows.Scan(&kind, &n); err != nil {
+			return nil, err
+		}
+		counts[kind] = n
+	}
+	return counts, rows.Err()
+}
+
+// ---------- Pinned countdowns ----------
+
+func scanSQLitePin(row interface{ Scan(...any) error }, p *Pin) error {
+	var next string
+	if err := row.Scan(&p.EventID, &p.ChatID, &p.MessageID, &next); err != nil {
+		return err
+	}
+	var err error
+	if p.NextUpdateAt, err = parseSQLiteTime(next); err != nil {
+		return fmt.Errorf("pin of event %d: invalid next_update_at %q: %v", p.EventID, next, err)
+	}
+	return nil
+}
+
+// pinColumns — колонки pinned_countdowns в порядке, ожидаемом scanSQLitePin.
+const pinColumns = `event_id, chat_id, message_id, next_update_at`
+
+// SetPin создаёт или заменяет закреплённое сообщение события.
+func (s *SQLiteStorage) SetPin(ctx context.Context, p *Pin) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO pinned_countdowns (event_id, chat_id, message_id, next_update_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (event_id) DO UPDATE SET chat_id = excluded.chat_id, message_id = excluded.message_id,
+		 next_update_at = excluded.next_update_at, created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')`,
+		p.EventID, p.ChatID, p.MessageID, formatSQLiteTime(p.NextUpdateAt),
+	)
+	return err
+}
+
+// GetPin возвращает закреплённое сообщение события или ErrNotFound.
+func (s *SQLiteStorage) GetPin(ctx context.Context, eventID int64) (*Pin, error) {
+	var p Pin
+	err := scanSQLitePin(s.db.QueryRowContext(ctx,
+		`SELECT `+pinColumns+` FROM pinned_countdowns WHERE event_id = ?`,
+		eventID,
+	), &p)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// DeletePin удаляет закреплённое сообщение события. Отсутствие записи ошибкой не считается.
+func (s *SQLiteStorage) DeletePin(ctx context.Context, eventID int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM pinned_countdowns WHERE event_id = ?`, eventID)
+	return err
+}
+
+// ClaimDuePins возвращает сообщения, которые пора обновить к моменту now, и откладывает
+// их следующее обновление на lease.
+func (s *SQLiteStorage) ClaimDuePins(ctx context.Context, now time.Time, lease time.Duration) ([]Pin, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`UPDATE pinned_countdowns SET next_update_at = ? WHERE next_update_at <= ? RETURNING `+pinColumns,
+		formatSQLiteTime(now.Add(lease)), formatSQLiteTime(now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pins []Pin
+	for rows.Next() {
+		var p Pin
+		if err := scanSQLitePin(rows, &p); err != nil {
+			return nil, err
+		}
+		pins = append(pins, p)
+	}
+	return pins, rows.Err()
+}
+
+// ReschedulePin назначает следующее обновление сообщения события. Если сообщения нет, ничего не делает.
+func (s *SQLiteStorage) ReschedulePin(ctx context.Context, eventID int64, next time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE pinned_countdowns SET next_update_at = ? WHERE event_id = ?`,
+		formatSQLiteTime(next), eventID,
+	)
+	return err
+}
+
+// ---------- Collections ----------
+
+// collectionColumns — колонки collections в порядке полей Collection.
+const collectionColumns = `chat_id, code, title`
+
+// CreateCollection публикует подборку чата c.ChatID. Возвращает false, если у чата уже есть подборка
+// (она не меняется), и ErrCodeTaken, если код занят подборкой другого чата.
+func (s *SQLiteStorage) CreateCollection(ctx context.Context, c *Collection) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO collections (chat_id, code, title) VALUES (?, ?, ?) ON CONFLICT (chat_id) DO NOTHING`,
+		c.ChatID, c.Code, c.Title,
+	)
+	if isSQLiteUniqueViolation(err) {
+		return false, ErrCodeTaken
+	}
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetCollection возвращает подборку чата или ErrNotFound.
+func (s *SQLiteStorage) GetCollection(ctx context.Context, chatID int64) (*Collection, error) {
+	return s.getCollection(ctx, `SELECT `+collectionColumns+` FROM collections WHERE chat_id = ?`, chatID)
+}
+
+// GetCollectionByCode возвращает подборку по коду или ErrNotFound.
+func (s *SQLiteStorage) GetCollectionByCode(ctx context.Context, code string) (*Collection, error) {
+	return s.getCollection(ctx, `SELECT `+collectionColumns+` FROM collections WHERE code = ?`, code)
+}
+
+func (s *SQLiteStorage) getCollection(ctx context.Context, query string, arg any) (*Collection, error) {
+	var c Collection
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(&c.ChatID, &c.Code, &c.Title)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// DeleteCollection снимает подборку чата с публикации вместе со всеми подписками на неё.
+// Возвращает false, если подборки не было.
+func (s *SQLiteStorage) DeleteCollection(ctx context.Context, chatID int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM collections WHERE chat_id = ?`, chatID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Subscribe подписывает чат chatID на подборку чата sourceChatID. Возвращает false, если подписка
+// уже была, и ErrNotFound, если подборки нет.
+func (s *SQLiteStorage) Subscribe(ctx context.Context, chatID, sourceChatID int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO collection_subscriptions (chat_id, source_chat_id)
+		 SELECT ?, chat_id FROM collections WHERE chat_id = ?
+		 ON CONFLICT (chat_id, source_chat_id) DO NOTHING`,
+		chatID, sourceChatID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return n > 0, err
+	}
+	if _, err := s.GetCollection(ctx, sourceChatID); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// Unsubscribe отменяет подписку чата chatID на подборку чата sourceChatID.
+// Возвращает false, если подписки не было.
+func (s *SQLiteStorage) Unsubscribe(ctx context.Context, chatID, sourceChatID int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM collection_subscriptions WHERE chat_id = ? AND source_chat_id = ?`,
+		chatID, sourceChatID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListSubscriptions возвращает подборки, на которые подписан чат, в порядке подписки.
+func (s *SQLiteStorage) ListSubscriptions(ctx context.Context, chatID int64) ([]Collection, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT c.chat_id, c.code, c.title FROM collection_subscriptions cs
+		 JOIN collections c ON c.chat_id = cs.source_chat_id
+		 WHERE cs.chat_id = ? ORDER BY cs.created_at, c.chat_id`,
+		chatID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collections []Collection
+	for rows.Next() {
+		var c Collection
+		if err := rows.Scan(&c.ChatID, &c.Code, &c.Title); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+// FindSubscribedEvent ищет событие по имени в подборках, на которые подписан чат.
+// Из нескольких совпадений возвращается созданное раньше всех.
+func (s *SQLiteStorage) FindSubscribedEvent(ctx context.Context, chatID int64, name string) (*Event, error) {
+	return scanSQLiteEvent(s.db.QueryRowContext(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE name = ?
+		 AND chat_id IN (SELECT source_chat_id FROM collection_subscriptions WHERE chat_id = ?)
+		 ORDER BY id LIMIT 1`,
+		name, chatID,
+	))
+}
+
+// ---------- Admin ----------
+
+// Stats возвращает сводку по всем чатам.
+func (s *SQLiteStorage) Stats(ctx context.Context) (*Stats, error) {
+	var st Stats
+	err := s.db.QueryRowContext(ctx, statsQuery).Scan(
+		&st.Chats, &st.Users, &st.Events, &st.ActiveEvents, &st.RemindersSent, &st.DigestsSent,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// ListChatStats возвращает страницу чатов с числом событий и общее число чатов.
+func (s *SQLiteStorage) ListChatStats(ctx context.Context, offset, limit int) ([]ChatStats, int, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM (`+knownChatsQuery+`) c`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.db.QueryContext(ctx, chatStatsQuery+` LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var chats []ChatStats
+	for rows.Next() {
+		var cs ChatStats
+		if err := rows.Scan(&cs.ChatID, &cs.Events, &cs.ActiveEvents); err != nil {
+			return nil, 0, err
+		}
+		chats = append(chats, cs)
+	}
+	return chats, total, rows.Err()
+}
+
+// ListKnownChats возвращает все чаты с событиями или настройками (получатели рассылки).
+func (s *SQLiteStorage) ListKnownChats(ctx context.Context) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, knownChatsQuery+` ORDER BY chat_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		chats = append(chats, id)
+	}
+	return chats, rows.Err()
+}
+
+// PurgeChat удаляет все данные чата: события (вместе с напоминаниями, привязками и отсчётами),
+// настройки, дайджесты, сессии, подборку и подписки. Возвращает число удалённых событий.
+func (s *SQLiteStorage) PurgeChat(ctx context.Context, chatID int64) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM events WHERE chat_id = ?`, chatID)
+	if err != nil {
+		return 0, err
+	}
+	for _, q := range []string{
+		`DELETE FROM user_events WHERE chat_id = ?`,
+		`DELETE FROM chat_settings WHERE chat_id = ?`,
+		`DELETE FROM sent_digests WHERE chat_id = ?`,
+		`DELETE FROM sessions WHERE chat_id = ?`,
+		`DELETE FROM collections WHERE chat_id = ?`,
+		`DELETE FROM collection_subscriptions WHERE chat_id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, q, chatID); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
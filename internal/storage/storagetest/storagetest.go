@@ -0,0 +1,1085 @@
+// Package storagetest — проверка соответствия реализаций storage.Store общему контракту.
+// Одни и те же проверки прогоняются тестами internal/storage против PostgreSQL, SQLite
+// и хранилища в памяти, чтобы бэкенды вели себя одинаково.
+//
+// Проверки работают с синтетическими чатами (отрицательные id за пределами диапазона Telegram)
+// и удаляют все их данные (PurgeChat).
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/storage"
+)
+
+// Result — итог одной проверки. Err == nil — проверка пройдена.
+type Result struct {
+	Name string
+	Err  error
+}
+
+// env — окружение одной проверки: хранилище и пара синтетических чатов.
+type env struct {
+	s            storage.Store
+	chatA, chatB int64
+	base         time.Time
+}
+
+type check struct {
+	name string
+	run  func(ctx context.Context, e *env) error
+}
+
+var checks = []check{
+	{"create_and_get", checkCreateAndGet},
+	{"unique_name_per_chat", checkUniqueName},
+	{"not_found", checkNotFound},
+	{"list_order_and_range", checkListOrderAndRange},
+	{"update_status_and_recurrence", checkUpdates},
+	{"update_event", checkUpdateEvent},
+	{"active_events", checkActiveEvents},
+	{"delete_cascade", checkDeleteCascade},
+	{"sent_reminders", checkSentReminders},
+	{"concurrent_claims", checkConcurrentClaims},
+	{"event_reminders", checkEventReminders},
+	{"chat_settings", checkChatSettings},
+	{"digests", checkDigests},
+	{"sessions", checkSessions},
+	{"pinned_countdowns", checkPins},
+	{"collections", checkCollections},
+	{"admin", checkAdmin},
+}
+
+// Run выполняет все проверки против s и возвращает их результаты в фиксированном порядке.
+func Run(ctx context.Context, s storage.Store) []Result {
+	results := make([]Result, 0, len(checks))
+	for i, c := range checks {
+		// Не пересекаются ни с реальными чатами (|id| < 2^53), ни между проверками и запусками
+		seed := time.Now().UnixNano() % 1_000_000_000
+		e := &env{
+			s:     s,
+			chatA: -(1 << 60) - seed*100 - int64(i)*2,
+			chatB: -(1 << 60) - seed*100 - int64(i)*2 - 1,
+			// Далёкое будущее: события проверок не попадают в выборки по реальным датам
+			base: time.Date(2999, 1, 1, 12, 0, 0, 0, time.UTC).Add(time.Duration(seed) * time.Microsecond),
+		}
+		err := c.run(ctx, e)
+		if cerr := e.cleanup(ctx); err == nil && cerr != nil {
+			err = fmt.Errorf("cleanup: %w", cerr)
+		}
+		results = append(results, Result{Name: c.name, Err: err})
+	}
+	return results
+}
+
+func (e *env) cleanup(ctx context.Context) error {
+	for _, chatID := range []int64{e.chatA, e.chatB} {
+		if _, err := e.s.PurgeChat(ctx, chatID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *env) create(ctx context.Context, chatID int64, name string, date time.Time) (*storage.Event, error) {
+	ev := &storage.Event{ChatID: chatID, Name: name, Date: date, Description: "описание " + name}
+	if err := e.s.CreateEvent(ctx, ev); err != nil {
+		return nil, fmt.Errorf("CreateEvent(%q): %w", name, err)
+	}
+	return ev, nil
+}
+
+func eventNames(events []storage.Event, chatIDs ...int64) []string {
+	var names []string
+	for _, ev := range events {
+		for _, id := range chatIDs {
+			if ev.ChatID == id {
+				names = append(names, ev.Name)
+			}
+		}
+	}
+	return names
+}
+
+func expectNames(what string, got []string, want ...string) error {
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		return fmt.Errorf("%s: got %v, want %v", what, got, want)
+	}
+	return nil
+}
+
+func expectOffsets(what string, got []time.Duration, want ...time.Duration) error {
+	sort.Slice(got, func(i, j int) bool { return got[i] > got[j] })
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		return fmt.Errorf("%s: got %v, want %v", what, got, want)
+	}
+	return nil
+}
+
+// ---------- проверки ----------
+
+func checkCreateAndGet(ctx context.Context, e *env) error {
+	date := e.base.Add(1500 * time.Millisecond)
+	ev := &storage.Event{ChatID: e.chatA, Name: "birthday", Title: "День рождения", Date: date, Description: "Торт 🎂", Recurrence: "FREQ=YEARLY"}
+	if err := e.s.CreateEvent(ctx, ev); err != nil {
+		return err
+	}
+	if ev.ID <= 0 {
+		return fmt.Errorf("CreateEvent did not set ID: %d", ev.ID)
+	}
+	if ev.Status != storage.StatusActive {
+		return fmt.Errorf("default status: got %q, want %q", ev.Status, storage.StatusActive)
+	}
+
+	got, err := e.s.GetEvent(ctx, e.chatA, "birthday")
+	if err != nil {
+		return fmt.Errorf("GetEvent: %w", err)
+	}
+	if got.ID != ev.ID || got.ChatID != e.chatA || got.Name != "birthday" || got.Title != "День рождения" || got.Description != "Торт 🎂" ||
+		got.Status != storage.StatusActive || got.Recurrence != "FREQ=YEARLY" {
+		return fmt.Errorf("GetEvent: got %+v, want %+v", *got, *ev)
+	}
+	if !got.Date.Equal(date) {
+		return fmt.Errorf("GetEvent date: got %s, want %s", got.Date, date)
+	}
+
+	byID, err := e.s.GetEventByID(ctx, ev.ID)
+	if err != nil {
+		return fmt.Errorf("GetEventByID: %w", err)
+	}
+	if byID.Name != "birthday" || !byID.Date.Equal(date) {
+		return fmt.Errorf("GetEventByID: got %+v", *byID)
+	}
+
+	// Привязка к пользователю идемпотентна
+	for i := 0; i < 2; i++ {
+		if err := e.s.AddEventToUser(ctx, e.chatA, 42, ev.ID); err != nil {
+			return fmt.Errorf("AddEventToUser #%d: %w", i+1, err)
+		}
+	}
+	for user, want := range map[int64]bool{42: true, 43: false} {
+		owner, err := e.s.IsEventOwner(ctx, ev.ID, user)
+		if err != nil {
+			return fmt.Errorf("IsEventOwner: %w", err)
+		}
+		if owner != want {
+			return fmt.Errorf("IsEventOwner(user %d): got %v, want %v", user, owner, want)
+		}
+	}
+	return nil
+}
+
+func checkUniqueName(ctx context.Context, e *env) error {
+	if _, err := e.create(ctx, e.chatA, "dup", e.base); err != nil {
+		return err
+	}
+	err := e.s.CreateEvent(ctx, &storage.Event{ChatID: e.chatA, Name: "dup", Date: e.base.Add(time.Hour)})
+	if !errors.Is(err, storage.ErrEventExists) {
+		return fmt.Errorf("duplicate CreateEvent: got %v, want ErrEventExists", err)
+	}
+	// Одинаковые имена в разных чатах допустимы
+	if _, err := e.create(ctx, e.chatB, "dup", e.base); err != nil {
+		return err
+	}
+	events, err := e.s.ListEvents(ctx, e.chatA)
+	if err != nil {
+		return err
+	}
+	return expectNames("ListEvents after duplicate", eventNames(events, e.chatA), "dup")
+}
+
+func checkNotFound(ctx context.Context, e *env) error {
+	if _, err := e.s.GetEvent(ctx, e.chatA, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("GetEvent: got %v, want ErrNotFound", err)
+	}
+	if _, err := e.s.GetEventByID(ctx, -1); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("GetEventByID: got %v, want ErrNotFound", err)
+	}
+	if _, err := e.s.GetCollection(ctx, e.chatA); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("GetCollection: got %v, want ErrNotFound", err)
+	}
+	if _, err := e.s.GetCollectionByCode(ctx, fmt.Sprintf("missing_%d", -e.chatA)); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("GetCollectionByCode: got %v, want ErrNotFound", err)
+	}
+	events, err := e.s.ListEvents(ctx, e.chatA)
+	if err != nil {
+		return err
+	}
+	if len(events) != 0 {
+		return fmt.Errorf("ListEvents of an empty chat: got %d events", len(events))
+	}
+	// Изменение и удаление несуществующего события — не ошибка
+	if err := e.s.UpdateEventStatus(ctx, e.chatA, "missing", storage.StatusOutdated); err != nil {
+		return fmt.Errorf("UpdateEventStatus: %w", err)
+	}
+	if err := e.s.DeleteEvent(ctx, e.chatA, "missing"); err != nil {
+		return fmt.Errorf("DeleteEvent: %w", err)
+	}
+	return nil
+}
+
+func checkListOrderAndRange(ctx context.Context, e *env) error {
+	for _, c := range []struct {
+		name string
+		at   time.Duration
+	}{{"c", 2 * time.Hour}, {"a", 0}, {"b2", time.Hour}, {"b1", time.Hour}} {
+		if _, err := e.create(ctx, e.chatA, c.name, e.base.Add(c.at)); err != nil {
+			return err
+		}
+	}
+	if _, err := e.create(ctx, e.chatB, "other", e.base.Add(time.Hour)); err != nil {
+		return err
+	}
+
+	events, err := e.s.ListEvents(ctx, e.chatA)
+	if err != nil {
+		return err
+	}
+	// По дате, при равной дате — в порядке создания
+	if err := expectNames("ListEvents", eventNames(events, e.chatA, e.chatB), "a", "b2", "b1", "c"); err != nil {
+		return err
+	}
+
+	between, err := e.s.ListEventsBetween(ctx, e.chatA, e.base.Add(time.Hour), e.base.Add(2*time.Hour))
+	if err != nil {
+		return err
+	}
+	if err := expectNames("ListEventsBetween [from, to)", eventNames(between, e.chatA, e.chatB), "b2", "b1"); err != nil {
+		return err
+	}
+
+	all, err := e.s.ListAllEventsBetween(ctx, e.base, e.base.Add(time.Hour+time.Microsecond))
+	if err != nil {
+		return err
+	}
+	if err := expectNames("ListAllEventsBetween", eventNames(all, e.chatA, e.chatB), "a", "b2", "b1", "other"); err != nil {
+		return err
+	}
+
+	chats, err := e.s.ListEventChats(ctx)
+	if err != nil {
+		return err
+	}
+	var ours []int64
+	for _, id := range chats {
+		if id == e.chatA || id == e.chatB {
+			ours = append(ours, id)
+		}
+	}
+	// chatB < chatA: id синтетических чатов отрицательные
+	if fmt.Sprint(ours) != fmt.Sprint([]int64{e.chatB, e.chatA}) {
+		return fmt.Errorf("ListEventChats: got %v, want [%d %d] (each chat once, ascending)", ours, e.chatB, e.chatA)
+	}
+	return nil
+}
+
+func checkUpdates(ctx context.Context, e *env) error {
+	if _, err := e.create(ctx, e.chatA, "ev", e.base); err != nil {
+		return err
+	}
+	if err := e.s.UpdateEventStatus(ctx, e.chatA, "ev", storage.StatusOutdated); err != nil {
+		return err
+	}
+	got, err := e.s.GetEvent(ctx, e.chatA, "ev")
+	if err != nil {
+		return err
+	}
+	if got.Status != storage.StatusOutdated {
+		return fmt.Errorf("UpdateEventStatus: got %q", got.Status)
+	}
+
+	// Правило повторения возвращает событие в активные
+	if err := e.s.UpdateEventRecurrence(ctx, e.chatA, "ev", "FREQ=WEEKLY"); err != nil {
+		return err
+	}
+	if got, err = e.s.GetEvent(ctx, e.chatA, "ev"); err != nil {
+		return err
+	}
+	if got.Recurrence != "FREQ=WEEKLY" || got.Status != storage.StatusActive {
+		return fmt.Errorf("UpdateEventRecurrence: got recurrence %q, status %q", got.Recurrence, got.Status)
+	}
+
+	// Снятие правила статус не меняет
+	if err := e.s.UpdateEventStatus(ctx, e.chatA, "ev", storage.StatusOutdated); err != nil {
+		return err
+	}
+	if err := e.s.UpdateEventRecurrence(ctx, e.chatA, "ev", ""); err != nil {
+		return err
+	}
+	if got, err = e.s.GetEvent(ctx, e.chatA, "ev"); err != nil {
+		return err
+	}
+	if got.Recurrence != "" || got.Status != storage.StatusOutdated {
+		return fmt.Errorf("clearing recurrence: got recurrence %q, status %q", got.Recurrence, got.Status)
+	}
+	return nil
+}
+
+func checkUpdateEvent(ctx context.Context, e *env) error {
+	ev, err := e.create(ctx, e.chatA, "old_name", e.base)
+	if err != nil {
+		return err
+	}
+	if _, err := e.create(ctx, e.chatA, "taken", e.base); err != nil {
+		return err
+	}
+	if err := e.s.AddEventReminder(ctx, ev.ID, time.Hour); err != nil {
+		return err
+	}
+	// Без названия событие отображается под своим именем
+	if ev.Title != "old_name" {
+		return fmt.Errorf("default title: got %q, want %q", ev.Title, "old_name")
+	}
+
+	ev.Name = "new_name"
+	ev.Title = "Новое имя 🎉"
+	ev.Date = e.base.Add(48 * time.Hour)
+	ev.Description = "новое описание"
+	ev.Status = storage.StatusOutdated
+	ev.Recurrence = "FREQ=DAILY"
+	if err := e.s.UpdateEvent(ctx, ev); err != nil {
+		return fmt.Errorf("UpdateEvent: %w", err)
+	}
+	if _, err := e.s.GetEvent(ctx, e.chatA, "old_name"); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("old name after rename: got %v, want ErrNotFound", err)
+	}
+	got, err := e.s.GetEvent(ctx, e.chatA, "new_name")
+	if err != nil {
+		return fmt.Errorf("GetEvent after rename: %w", err)
+	}
+	if got.ID != ev.ID || got.Title != ev.Title || !got.Date.Equal(ev.Date) || got.Description != ev.Description ||
+		got.Status != storage.StatusOutdated || got.Recurrence != "FREQ=DAILY" {
+		return fmt.Errorf("UpdateEvent: got %+v, want %+v", *got, *ev)
+	}
+	// Напоминания привязаны к id и переживают переименование
+	offsets, err := e.s.ListEventReminders(ctx, ev.ID)
+	if err != nil {
+		return err
+	}
+	if err := expectOffsets("reminders after rename", offsets, time.Hour); err != nil {
+		return err
+	}
+
+	ev.Name = "taken"
+	if err := e.s.UpdateEvent(ctx, ev); !errors.Is(err, storage.ErrEventExists) {
+		return fmt.Errorf("rename to a taken name: got %v, want ErrEventExists", err)
+	}
+	missing := *ev
+	missing.ID = -1
+	missing.Name = "ghost"
+	if err := e.s.UpdateEvent(ctx, &missing); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("UpdateEvent of a missing event: got %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func checkActiveEvents(ctx context.Context, e *env) error {
+	for _, name := range []string{"active1", "old", "active2"} {
+		if _, err := e.create(ctx, e.chatA, name, e.base); err != nil {
+			return err
+		}
+	}
+	if err := e.s.UpdateEventStatus(ctx, e.chatA, "old", storage.StatusOutdated); err != nil {
+		return err
+	}
+	active, err := e.s.ListActiveEvents(ctx)
+	if err != nil {
+		return err
+	}
+	return expectNames("ListActiveEvents", eventNames(active, e.chatA), "active1", "active2")
+}
+
+func checkDeleteCascade(ctx context.Context, e *env) error {
+	ev, err := e.create(ctx, e.chatA, "gone", e.base)
+	if err != nil {
+		return err
+	}
+	if err := e.s.AddEventToUser(ctx, e.chatA, 42, ev.ID); err != nil {
+		return err
+	}
+	if err := e.s.AddEventReminder(ctx, ev.ID, time.Hour); err != nil {
+		return err
+	}
+	if _, err := e.s.ClaimReminder(ctx, ev.ID, ev.Date, time.Hour); err != nil {
+		return err
+	}
+
+	if err := e.s.DeleteEvent(ctx, e.chatA, "gone"); err != nil {
+		return err
+	}
+	if owner, err := e.s.IsEventOwner(ctx, ev.ID, 42); err != nil || owner {
+		return fmt.Errorf("user link after delete: got %v, %v, want false", owner, err)
+	}
+	if _, err := e.s.GetEventByID(ctx, ev.ID); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("GetEventByID after delete: got %v, want ErrNotFound", err)
+	}
+	offsets, err := e.s.ListEventReminders(ctx, ev.ID)
+	if err != nil {
+		return err
+	}
+	if err := expectOffsets("event reminders after delete", offsets); err != nil {
+		return err
+	}
+	sent, err := e.s.ListSentReminders(ctx, ev.ID, ev.Date)
+	if err != nil {
+		return err
+	}
+	if err := expectOffsets("sent reminders after delete", sent); err != nil {
+		return err
+	}
+
+	// Имя освобождается, новое событие получает новый id
+	again, err := e.create(ctx, e.chatA, "gone", e.base)
+	if err != nil {
+		return err
+	}
+	if again.ID == ev.ID {
+		return fmt.Errorf("recreated event reused id %d", ev.ID)
+	}
+	return nil
+}
+
+func checkSentReminders(ctx context.Context, e *env) error {
+	ev, err := e.create(ctx, e.chatA, "remind", e.base)
+	if err != nil {
+		return err
+	}
+	next := e.base.AddDate(0, 0, 7)
+
+	claimed, err := e.s.ClaimReminder(ctx, ev.ID, e.base, time.Hour)
+	if err != nil || !claimed {
+		return fmt.Errorf("first ClaimReminder: got %v, %v, want true", claimed, err)
+	}
+	if claimed, err = e.s.ClaimReminder(ctx, ev.ID, e.base, time.Hour); err != nil || claimed {
+		return fmt.Errorf("repeated ClaimReminder: got %v, %v, want false", claimed, err)
+	}
+	// Другое вхождение события — отдельный ключ
+	if claimed, err = e.s.ClaimReminder(ctx, ev.ID, next, time.Hour); err != nil || !claimed {
+		return fmt.Errorf("ClaimReminder for the next occurrence: got %v, %v, want true", claimed, err)
+	}
+	if _, err := e.s.ClaimReminder(ctx, ev.ID, e.base, 0); err != nil {
+		return err
+	}
+
+	sent, err := e.s.ListSentReminders(ctx, ev.ID, e.base)
+	if err != nil {
+		return err
+	}
+	if err := expectOffsets("ListSentReminders", sent, time.Hour, 0); err != nil {
+		return err
+	}
+
+	if err := e.s.ReleaseReminder(ctx, ev.ID, e.base, time.Hour); err != nil {
+		return err
+	}
+	if claimed, err = e.s.ClaimReminder(ctx, ev.ID, e.base, time.Hour); err != nil || !claimed {
+		return fmt.Errorf("ClaimReminder after release: got %v, %v, want true", claimed, err)
+	}
+	return nil
+}
+
+func checkConcurrentClaims(ctx context.Context, e *env) error {
+	ev, err := e.create(ctx, e.chatA, "race", e.base)
+	if err != nil {
+		return err
+	}
+
+	const workers = 16
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		claimed int
+		errs    []error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := e.s.ClaimReminder(ctx, ev.ID, e.base, 24*time.Hour)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if ok {
+				claimed++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("concurrent ClaimReminder: %w", errs[0])
+	}
+	if claimed != 1 {
+		return fmt.Errorf("concurrent ClaimReminder: %d of %d claims succeeded, want exactly 1", claimed, workers)
+	}
+	return nil
+}
+
+func checkEventReminders(ctx context.Context, e *env) error {
+	ev, err := e.create(ctx, e.chatA, "offsets", e.base)
+	if err != nil {
+		return err
+	}
+	for _, o := range []time.Duration{time.Hour, 72 * time.Hour, 0, time.Hour} {
+		if err := e.s.AddEventReminder(ctx, ev.ID, o); err != nil {
+			return err
+		}
+	}
+	offsets, err := e.s.ListEventReminders(ctx, ev.ID)
+	if err != nil {
+		return err
+	}
+	// Порядок по убыванию — часть контракта, поэтому сравниваем без сортировки
+	if fmt.Sprint(offsets) != fmt.Sprint([]time.Duration{72 * time.Hour, time.Hour, 0}) {
+		return fmt.Errorf("ListEventReminders: got %v, want [72h 1h 0s] (descending)", offsets)
+	}
+
+	deleted, err := e.s.DeleteEventReminder(ctx, ev.ID, time.Hour)
+	if err != nil || !deleted {
+		return fmt.Errorf("DeleteEventReminder: got %v, %v, want true", deleted, err)
+	}
+	if deleted, err = e.s.DeleteEventReminder(ctx, ev.ID, time.Hour); err != nil || deleted {
+		return fmt.Errorf("repeated DeleteEventReminder: got %v, %v, want false", deleted, err)
+	}
+
+	if err := e.s.ClearEventReminders(ctx, ev.ID); err != nil {
+		return err
+	}
+	if offsets, err = e.s.ListEventReminders(ctx, ev.ID); err != nil {
+		return err
+	}
+	return expectOffsets("ListEventReminders after clear", offsets)
+}
+
+func checkChatSettings(ctx context.Context, e *env) error {
+	cs, err := e.s.GetChatSettings(ctx, e.chatA)
+	if err != nil {
+		return err
+	}
+	if cs.ChatID != e.chatA || cs.Timezone != "" {
+		return fmt.Errorf("default settings: got %+v", *cs)
+	}
+
+	for _, tz := range []string{"Europe/Berlin", "Asia/Tokyo"} {
+		if err := e.s.SetChatTimezone(ctx, e.chatA, tz); err != nil {
+			return err
+		}
+		if cs, err = e.s.GetChatSettings(ctx, e.chatA); err != nil {
+			return err
+		}
+		if cs.Timezone != tz {
+			return fmt.Errorf("SetChatTimezone(%q): got %q", tz, cs.Timezone)
+		}
+	}
+
+	other, err := e.s.GetChatSettings(ctx, e.chatB)
+	if err != nil {
+		return err
+	}
+	if other.Timezone != "" {
+		return fmt.Errorf("settings leaked to another chat: %+v", *other)
+	}
+
+	if err := e.s.SetChatTimezone(ctx, e.chatA, ""); err != nil {
+		return err
+	}
+	if cs, err = e.s.GetChatSettings(ctx, e.chatA); err != nil {
+		return err
+	}
+	if cs.Timezone != "" {
+		return fmt.Errorf("reset timezone: got %q", cs.Timezone)
+	}
+	return nil
+}
+
+// digestChat ищет настройки чата среди результатов ListDigestChats.
+func digestChat(chats []storage.ChatSettings, chatID int64) *storage.ChatSettings {
+	for i := range chats {
+		if chats[i].ChatID == chatID {
+			return &chats[i]
+		}
+	}
+	return nil
+}
+
+func checkDigests(ctx context.Context, e *env) error {
+	defer e.s.SetChatDigest(ctx, e.chatA, storage.Digest{})
+	defer e.s.SetChatTimezone(ctx, e.chatA, "")
+
+	weekly := storage.Digest{Schedule: storage.DigestWeekly, Weekday: time.Monday, Minute: 9 * 60, Days: 14}
+	if err := e.s.SetChatTimezone(ctx, e.chatA, "Asia/Tokyo"); err != nil {
+		return err
+	}
+	if err := e.s.SetChatDigest(ctx, e.chatA, weekly); err != nil {
+		return fmt.Errorf("SetChatDigest: %w", err)
+	}
+	cs, err := e.s.GetChatSettings(ctx, e.chatA)
+	if err != nil {
+		return err
+	}
+	if cs.Digest != weekly || cs.Timezone != "Asia/Tokyo" {
+		return fmt.Errorf("settings after SetChatDigest: got %+v", *cs)
+	}
+
+	// Смена часового пояса не сбрасывает дайджест
+	if err := e.s.SetChatTimezone(ctx, e.chatA, "Europe/Berlin"); err != nil {
+		return err
+	}
+	chats, err := e.s.ListDigestChats(ctx)
+	if err != nil {
+		return fmt.Errorf("ListDigestChats: %w", err)
+	}
+	got := digestChat(chats, e.chatA)
+	if got == nil || got.Digest != weekly || got.Timezone != "Europe/Berlin" {
+		return fmt.Errorf("ListDigestChats: got %+v for chat %d", got, e.chatA)
+	}
+	if digestChat(chats, e.chatB) != nil {
+		return fmt.Errorf("ListDigestChats: chat without digest listed")
+	}
+
+	// Выпуск отмечается один раз, снятая отметка позволяет отправить его снова
+	slot := e.base.Add(9 * time.Hour)
+	for i, want := range []bool{true, false} {
+		claimed, err := e.s.ClaimDigest(ctx, e.chatA, slot)
+		if err != nil {
+			return fmt.Errorf("ClaimDigest: %w", err)
+		}
+		if claimed != want {
+			return fmt.Errorf("ClaimDigest #%d: got %v, want %v", i+1, claimed, want)
+		}
+	}
+	if claimed, err := e.s.ClaimDigest(ctx, e.chatB, slot); err != nil || !claimed {
+		return fmt.Errorf("ClaimDigest for another chat: got %v, %v, want true", claimed, err)
+	}
+	if err := e.s.ReleaseDigest(ctx, e.chatA, slot); err != nil {
+		return fmt.Errorf("ReleaseDigest: %w", err)
+	}
+	if claimed, err := e.s.ClaimDigest(ctx, e.chatA, slot); err != nil || !claimed {
+		return fmt.Errorf("ClaimDigest after release: got %v, %v, want true", claimed, err)
+	}
+	for _, chatID := range []int64{e.chatA, e.chatB} {
+		if err := e.s.ReleaseDigest(ctx, chatID, slot); err != nil {
+			return err
+		}
+	}
+
+	if err := e.s.SetChatDigest(ctx, e.chatA, storage.Digest{}); err != nil {
+		return err
+	}
+	if chats, err = e.s.ListDigestChats(ctx); err != nil {
+		return err
+	}
+	if digestChat(chats, e.chatA) != nil {
+		return fmt.Errorf("ListDigestChats: disabled digest listed")
+	}
+	return nil
+}
+
+func checkSessions(ctx context.Context, e *env) error {
+	// Собственные виды сессий: счётчики не зависят от настоящих диалогов в базе
+	const (
+		user     = 42
+		pending  = "check_pending"
+		awaiting = "check_awaiting"
+	)
+	expires := time.Now().Add(time.Hour)
+	defer func() {
+		for _, kind := range []string{pending, awaiting} {
+			e.s.DeleteSession(ctx, e.chatA, user, kind)
+		}
+	}()
+
+	if _, err := e.s.GetSession(ctx, e.chatA, user, pending); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("missing session: got %v, want ErrNotFound", err)
+	}
+
+	// Продление живой сессии сохраняет время её создания
+	var created time.Time
+	for i, data := range []string{`{"step":1}`, `{"step":2}`} {
+		sess := &storage.Session{ChatID: e.chatA, UserID: user, Kind: pending, Data: []byte(data), ExpiresAt: expires}
+		if err := e.s.SetSession(ctx, sess); err != nil {
+			return fmt.Errorf("SetSession: %w", err)
+		}
+		if sess.CreatedAt.IsZero() {
+			return fmt.Errorf("SetSession did not set CreatedAt")
+		}
+		if i == 0 {
+			created = sess.CreatedAt
+		} else if !sess.CreatedAt.Equal(created) {
+			return fmt.Errorf("CreatedAt after update: got %s, want %s", sess.CreatedAt, created)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	got, err := e.s.GetSession(ctx, e.chatA, user, pending)
+	if err != nil {
+		return fmt.Errorf("GetSession: %w", err)
+	}
+	if string(got.Data) != `{"step":2}` || !got.ExpiresAt.Equal(expires.Truncate(time.Microsecond)) || !got.CreatedAt.Equal(created) {
+		return fmt.Errorf("GetSession: got data %s, created %s, expires %s", got.Data, got.CreatedAt, got.ExpiresAt)
+	}
+
+	// Виды сессий и пользователи независимы
+	if _, err := e.s.GetSession(ctx, e.chatA, user, awaiting); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("session of another kind: got %v, want ErrNotFound", err)
+	}
+	if _, err := e.s.GetSession(ctx, e.chatA, user+1, pending); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("session of another user: got %v, want ErrNotFound", err)
+	}
+
+	// Истёкшая сессия не возвращается, не считается и отдаётся очистке ровно один раз
+	expired := &storage.Session{ChatID: e.chatA, UserID: user, Kind: awaiting, Data: []byte(`{"msg":7}`), ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := e.s.SetSession(ctx, expired); err != nil {
+		return err
+	}
+	if _, err := e.s.GetSession(ctx, e.chatA, user, awaiting); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("expired session: got %v, want ErrNotFound", err)
+	}
+	counts, err := e.s.CountSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("CountSessions: %w", err)
+	}
+	if counts[pending] != 1 || counts[awaiting] != 0 {
+		return fmt.Errorf("CountSessions: got %v, want %s=1 and no %s", counts, pending, awaiting)
+	}
+	for round := 1; round <= 2; round++ {
+		deleted, err := e.s.DeleteExpiredSessions(ctx, time.Now())
+		if err != nil {
+			return fmt.Errorf("DeleteExpiredSessions: %w", err)
+		}
+		var ours []storage.Session
+		for _, sess := range deleted {
+			if sess.ChatID == e.chatA {
+				ours = append(ours, sess)
+			}
+		}
+		want := 1
+		if round == 2 {
+			want = 0
+		}
+		if len(ours) != want {
+			return fmt.Errorf("DeleteExpiredSessions round %d: got %d sessions of the check, want %d", round, len(ours), want)
+		}
+		if want == 1 && (ours[0].Kind != awaiting || ours[0].UserID != user || string(ours[0].Data) != `{"msg":7}` || ours[0].CreatedAt.IsZero()) {
+			return fmt.Errorf("DeleteExpiredSessions: got %+v", ours[0])
+		}
+	}
+	if _, err := e.s.GetSession(ctx, e.chatA, user, pending); err != nil {
+		return fmt.Errorf("live session after cleanup: %w", err)
+	}
+
+	if err := e.s.DeleteSession(ctx, e.chatA, user, pending); err != nil {
+		return fmt.Errorf("DeleteSession: %w", err)
+	}
+	if _, err := e.s.GetSession(ctx, e.chatA, user, pending); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("deleted session: got %v, want ErrNotFound", err)
+	}
+	return e.s.DeleteSession(ctx, e.chatA, user, pending)
+}
+
+// claimedPin ищет сообщение события среди полученных ClaimDuePins: в настоящей базе
+// могут быть и чужие закреплённые сообщения.
+func claimedPin(pins []storage.Pin, eventID int64) *storage.Pin {
+	for i := range pins {
+		if pins[i].EventID == eventID {
+			return &pins[i]
+		}
+	}
+	return nil
+}
+
+func checkPins(ctx context.Context, e *env) error {
+	ev, err := e.create(ctx, e.chatA, "pinned", e.base)
+	if err != nil {
+		return err
+	}
+	if _, err := e.s.GetPin(ctx, ev.ID); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("missing pin: got %v, want ErrNotFound", err)
+	}
+
+	now := time.Now()
+	if err := e.s.SetPin(ctx, &storage.Pin{EventID: ev.ID, ChatID: e.chatA, MessageID: 10, NextUpdateAt: now.Add(time.Hour)}); err != nil {
+		return fmt.Errorf("SetPin: %w", err)
+	}
+	// Повторное закрепление заменяет сообщение
+	if err := e.s.SetPin(ctx, &storage.Pin{EventID: ev.ID, ChatID: e.chatA, MessageID: 11, NextUpdateAt: now.Add(-time.Second)}); err != nil {
+		return fmt.Errorf("SetPin again: %w", err)
+	}
+	got, err := e.s.GetPin(ctx, ev.ID)
+	if err != nil {
+		return fmt.Errorf("GetPin: %w", err)
+	}
+	if got.ChatID != e.chatA || got.MessageID != 11 || !got.NextUpdateAt.Equal(now.Add(-time.Second).Truncate(time.Microsecond)) {
+		return fmt.Errorf("GetPin: got %+v", *got)
+	}
+
+	// Наступившее обновление выдаётся один раз и откладывается на lease
+	pins, err := e.s.ClaimDuePins(ctx, now, time.Minute)
+	if err != nil {
+		return fmt.Errorf("ClaimDuePins: %w", err)
+	}
+	p := claimedPin(pins, ev.ID)
+	if p == nil || p.MessageID != 11 || !p.NextUpdateAt.Equal(now.Add(time.Minute).Truncate(time.Microsecond)) {
+		return fmt.Errorf("ClaimDuePins: got %+v for event %d", pins, ev.ID)
+	}
+	if pins, err = e.s.ClaimDuePins(ctx, now, time.Minute); err != nil || claimedPin(pins, ev.ID) != nil {
+		return fmt.Errorf("second ClaimDuePins: got %+v, %v, want no pin of event %d", pins, err, ev.ID)
+	}
+
+	// Перенос обновления в прошлое делает сообщение снова доступным
+	if err := e.s.ReschedulePin(ctx, ev.ID, now.Add(-time.Minute)); err != nil {
+		return fmt.Errorf("ReschedulePin: %w", err)
+	}
+	if pins, err = e.s.ClaimDuePins(ctx, now, time.Minute); err != nil || claimedPin(pins, ev.ID) == nil {
+		return fmt.Errorf("ClaimDuePins after reschedule: got %+v, %v", pins, err)
+	}
+
+	if err := e.s.DeletePin(ctx, ev.ID); err != nil {
+		return fmt.Errorf("DeletePin: %w", err)
+	}
+	if _, err := e.s.GetPin(ctx, ev.ID); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("pin after DeletePin: got %v, want ErrNotFound", err)
+	}
+
+	// Удаление события удаляет и закреплённое сообщение
+	if err := e.s.SetPin(ctx, &storage.Pin{EventID: ev.ID, ChatID: e.chatA, MessageID: 12, NextUpdateAt: now}); err != nil {
+		return err
+	}
+	if err := e.s.DeleteEvent(ctx, e.chatA, "pinned"); err != nil {
+		return err
+	}
+	if _, err := e.s.GetPin(ctx, ev.ID); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("pin after event delete: got %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+// findChatStats листает ListChatStats и возвращает строку чата. Возвращает также общее число чатов.
+func findChatStats(ctx context.Context, s storage.Store, chatID int64) (*storage.ChatStats, int, error) {
+	const page = 100
+	for offset := 0; ; offset += page {
+		chats, total, err := s.ListChatStats(ctx, offset, page)
+		if err != nil {
+			return nil, 0, fmt.Errorf("ListChatStats: %w", err)
+		}
+		for i := range chats {
+			if chats[i].ChatID == chatID {
+				return &chats[i], total, nil
+			}
+		}
+		if len(chats) < page {
+			return nil, total, nil
+		}
+	}
+}
+
+func checkCollections(ctx context.Context, e *env) error {
+	// Чат B публикует подборку, чат A на неё подписывается
+	code := fmt.Sprintf("chk%d", -e.chatB)
+	if created, err := e.s.CreateCollection(ctx, &storage.Collection{ChatID: e.chatB, Code: code, Title: "Семья"}); err != nil || !created {
+		return fmt.Errorf("CreateCollection: got %v, %v, want true", created, err)
+	}
+	if created, err := e.s.CreateCollection(ctx, &storage.Collection{ChatID: e.chatB, Code: code + "x"}); err != nil || created {
+		return fmt.Errorf("CreateCollection for a chat with a collection: got %v, %v, want false", created, err)
+	}
+	if _, err := e.s.CreateCollection(ctx, &storage.Collection{ChatID: e.chatA, Code: code}); !errors.Is(err, storage.ErrCodeTaken) {
+		return fmt.Errorf("CreateCollection with a taken code: got %v, want ErrCodeTaken", err)
+	}
+	want := storage.Collection{ChatID: e.chatB, Code: code, Title: "Семья"}
+	if got, err := e.s.GetCollectionByCode(ctx, code); err != nil || *got != want {
+		return fmt.Errorf("GetCollectionByCode: got %+v, %v, want %+v", got, err, want)
+	}
+
+	if _, err := e.s.Subscribe(ctx, e.chatB, e.chatA); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("Subscribe to a chat without a collection: got %v, want ErrNotFound", err)
+	}
+	for i, wantNew := range []bool{true, false} {
+		if added, err := e.s.Subscribe(ctx, e.chatA, e.chatB); err != nil || added != wantNew {
+			return fmt.Errorf("Subscribe #%d: got %v, %v, want %v", i+1, added, err, wantNew)
+		}
+	}
+	subs, err := e.s.ListSubscriptions(ctx, e.chatA)
+	if err != nil {
+		return fmt.Errorf("ListSubscriptions: %w", err)
+	}
+	if len(subs) != 1 || subs[0] != want {
+		return fmt.Errorf("ListSubscriptions: got %+v, want [%+v]", subs, want)
+	}
+
+	// Событие подборки находится только из подписанного чата; из нескольких — созданное раньше
+	name := fmt.Sprintf("shared_%d", -e.chatA)
+	first, err := e.create(ctx, e.chatB, name, e.base)
+	if err != nil {
+		return err
+	}
+	if _, err := e.create(ctx, e.chatA, name, e.base); err != nil {
+		return err
+	}
+	if got, err := e.s.FindSubscribedEvent(ctx, e.chatA, name); err != nil || got.ID != first.ID {
+		return fmt.Errorf("FindSubscribedEvent: got %+v, %v, want event %d", got, err, first.ID)
+	}
+	if _, err := e.s.FindSubscribedEvent(ctx, e.chatB, name); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("FindSubscribedEvent without a subscription: got %v, want ErrNotFound", err)
+	}
+
+	if removed, err := e.s.Unsubscribe(ctx, e.chatA, e.chatB); err != nil || !removed {
+		return fmt.Errorf("Unsubscribe: got %v, %v, want true", removed, err)
+	}
+	if removed, err := e.s.Unsubscribe(ctx, e.chatA, e.chatB); err != nil || removed {
+		return fmt.Errorf("Unsubscribe twice: got %v, %v, want false", removed, err)
+	}
+	if _, err := e.s.FindSubscribedEvent(ctx, e.chatA, name); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("FindSubscribedEvent after Unsubscribe: got %v, want ErrNotFound", err)
+	}
+
+	// Снятие подборки с публикации удаляет подписки на неё
+	if _, err := e.s.Subscribe(ctx, e.chatA, e.chatB); err != nil {
+		return err
+	}
+	if deleted, err := e.s.DeleteCollection(ctx, e.chatB); err != nil || !deleted {
+		return fmt.Errorf("DeleteCollection: got %v, %v, want true", deleted, err)
+	}
+	if deleted, err := e.s.DeleteCollection(ctx, e.chatB); err != nil || deleted {
+		return fmt.Errorf("DeleteCollection twice: got %v, %v, want false", deleted, err)
+	}
+	if subs, err := e.s.ListSubscriptions(ctx, e.chatA); err != nil || len(subs) != 0 {
+		return fmt.Errorf("ListSubscriptions after DeleteCollection: got %+v, %v, want none", subs, err)
+	}
+
+	// Очистка чата удаляет его подборку и подписки на неё
+	if _, err := e.s.CreateCollection(ctx, &storage.Collection{ChatID: e.chatB, Code: code}); err != nil {
+		return err
+	}
+	if _, err := e.s.Subscribe(ctx, e.chatA, e.chatB); err != nil {
+		return err
+	}
+	if _, err := e.s.PurgeChat(ctx, e.chatB); err != nil {
+		return fmt.Errorf("PurgeChat: %w", err)
+	}
+	if _, err := e.s.GetCollection(ctx, e.chatB); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("GetCollection after PurgeChat: got %v, want ErrNotFound", err)
+	}
+	if subs, err := e.s.ListSubscriptions(ctx, e.chatA); err != nil || len(subs) != 0 {
+		return fmt.Errorf("ListSubscriptions after PurgeChat: got %+v, %v, want none", subs, err)
+	}
+	return nil
+}
+
+func checkAdmin(ctx context.Context, e *env) error {
+	before, err := e.s.Stats(ctx)
+	if err != nil {
+		return fmt.Errorf("Stats: %w", err)
+	}
+
+	// Чат A — два события (одно прошедшее), отправленное напоминание, дайджест и сессия;
+	// чат B — только настройки
+	active, err := e.create(ctx, e.chatA, "admin_active", e.base)
+	if err != nil {
+		return err
+	}
+	past, err := e.create(ctx, e.chatA, "admin_past", e.base.Add(time.Hour))
+	if err != nil {
+		return err
+	}
+	if err := e.s.UpdateEventStatus(ctx, e.chatA, past.Name, storage.StatusOutdated); err != nil {
+		return err
+	}
+	if err := e.s.AddEventToUser(ctx, e.chatA, e.chatA, active.ID); err != nil {
+		return err
+	}
+	if _, err := e.s.ClaimReminder(ctx, active.ID, active.Date, time.Hour); err != nil {
+		return err
+	}
+	if _, err := e.s.ClaimDigest(ctx, e.chatA, e.base); err != nil {
+		return err
+	}
+	sess := &storage.Session{ChatID: e.chatA, UserID: 42, Kind: "check_admin", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := e.s.SetSession(ctx, sess); err != nil {
+		return err
+	}
+	if err := e.s.SetChatTimezone(ctx, e.chatB, "Asia/Tokyo"); err != nil {
+		return err
+	}
+
+	after, err := e.s.Stats(ctx)
+	if err != nil {
+		return fmt.Errorf("Stats: %w", err)
+	}
+	want := storage.Stats{
+		Chats: before.Chats + 2, Users: before.Users + 1, Events: before.Events + 2, ActiveEvents: before.ActiveEvents + 1,
+		RemindersSent: before.RemindersSent + 1, DigestsSent: before.DigestsSent + 1,
+	}
+	if *after != want {
+		return fmt.Errorf("Stats: got %+v, want %+v", *after, want)
+	}
+
+	for _, c := range []storage.ChatStats{{ChatID: e.chatA, Events: 2, ActiveEvents: 1}, {ChatID: e.chatB}} {
+		got, total, err := findChatStats(ctx, e.s, c.ChatID)
+		if err != nil {
+			return err
+		}
+		if got == nil || *got != c {
+			return fmt.Errorf("ListChatStats: got %+v, want %+v", got, c)
+		}
+		if total != after.Chats {
+			return fmt.Errorf("ListChatStats total: got %d, want %d", total, after.Chats)
+		}
+	}
+	if chats, total, err := e.s.ListChatStats(ctx, after.Chats, 10); err != nil || len(chats) != 0 || total != after.Chats {
+		return fmt.Errorf("ListChatStats past the end: got %d chats, total %d, %v", len(chats), total, err)
+	}
+
+	known, err := e.s.ListKnownChats(ctx)
+	if err != nil {
+		return fmt.Errorf("ListKnownChats: %w", err)
+	}
+	if !sort.SliceIsSorted(known, func(i, j int) bool { return known[i] < known[j] }) {
+		return fmt.Errorf("ListKnownChats: not sorted")
+	}
+	if !containsChat(known, e.chatA) || !containsChat(known, e.chatB) {
+		return fmt.Errorf("ListKnownChats: synthetic chats missing")
+	}
+
+	// Очистка чата удаляет события со всеми связанными данными, настройки, дайджесты и сессии
+	n, err := e.s.PurgeChat(ctx, e.chatA)
+	if err != nil {
+		return fmt.Errorf("PurgeChat: %w", err)
+	}
+	if n != 2 {
+		return fmt.Errorf("PurgeChat: got %d events, want 2", n)
+	}
+	if _, err := e.s.GetEventByID(ctx, active.ID); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("event after PurgeChat: got %v, want ErrNotFound", err)
+	}
+	if _, err := e.s.GetSession(ctx, e.chatA, 42, "check_admin"); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("session after PurgeChat: got %v, want ErrNotFound", err)
+	}
+	if claimed, err := e.s.ClaimDigest(ctx, e.chatA, e.base); err != nil || !claimed {
+		return fmt.Errorf("ClaimDigest after PurgeChat: got %v, %v, want true", claimed, err)
+	}
+	if n, err := e.s.PurgeChat(ctx, e.chatB); err != nil || n != 0 {
+		return fmt.Errorf("PurgeChat without events: got %d, %v", n, err)
+	}
+	if cs, err := e.s.GetChatSettings(ctx, e.chatB); err != nil || cs.Timezone != "" {
+		return fmt.Errorf("settings after PurgeChat: got %+v, %v", cs, err)
+	}
+	if final, err := e.s.Stats(ctx); err != nil || final.Chats != before.Chats {
+		return fmt.Errorf("Stats after PurgeChat: got %+v, %v, want %d chats", final, err, before.Chats)
+	}
+	return nil
+}
+
+func containsChat(chats []int64, chatID int64) bool {
+	for _, id := range chats {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound — запрошенная запись не существует.
+	ErrNotFound = errors.New("not found")
+	// ErrEventExists — событие с таким именем в чате уже существует (UNIQUE(chat_id, name)).
+	ErrEventExists = errors.New("event with this name already exists in the chat")
+	// ErrCodeTaken — код подборки уже занят подборкой другого чата (UNIQUE(code)).
+	ErrCodeTaken = errors.New("collection code is already taken")
+)
+
+// Статусы событий.
+const (
+	StatusActive   = "active"
+	StatusOutdated = "outdated"
+)
+
+// Event — строка таблицы events.
+type Event struct {
+	ID          int64
+	ChatID      int64
+	Name        string // имя команды (slug): [a-z0-9_], до 32 символов
+	Title       string // название для отображения, как его ввёл пользователь
+	Date        time.Time
+	Description string
+	Status      string
+	Recurrence  string    // правило повторения (RRULE), пусто — однократное событие
+	CreatedAt   time.Time // момент создания, заполняется хранилищем
+}
+
+// DisplayTitle возвращает название события для сообщений: Title, а если он пуст — Name.
+func (e Event) DisplayTitle() string {
+	if e.Title != "" {
+		return e.Title
+	}
+	return e.Name
+}
+
+// defaultTitle заполняет пустой Title именем события перед сохранением.
+func defaultTitle(e *Event) {
+	if e.Title == "" {
+		e.Title = e.Name
+	}
+}
+
+// EventStore — события чатов и их привязка к пользователям.
+type EventStore interface {
+	CreateEvent(ctx context.Context, e *Event) error
+	GetEvent(ctx context.Context, chatID int64, name string) (*Event, error)
+	GetEventByID(ctx context.Context, id int64) (*Event, error)
+	ListEvents(ctx context.Context, chatID int64) ([]Event, error)
+	ListEventsBetween(ctx context.Context, chatID int64, from, to time.Time) ([]Event, error)
+	ListAllEventsBetween(ctx context.Context, from, to time.Time) ([]Event, error)
+	ListEventChats(ctx context.Context) ([]int64, error)
+	UpdateEvent(ctx context.Context, e *Event) error
+	UpdateEventStatus(ctx context.Context, chatID int64, name, status string) error
+	UpdateEventRecurrence(ctx context.Context, chatID int64, name, recurrence string) error
+	DeleteEvent(ctx context.Context, chatID int64, name string) error
+	AddEventToUser(ctx context.Context, chatID, userID, eventID int64) error
+	IsEventOwner(ctx context.Context, eventID, userID int64) (bool, error)
+}
+
+// ReminderStore — смещения напоминаний событий и журнал отправленных напоминаний.
+type ReminderStore interface {
+	ListActiveEvents(ctx context.Context) ([]Event, error)
+	ListSentReminders(ctx context.Context, eventID int64, eventDate time.Time) ([]time.Duration, error)
+	ClaimReminder(ctx context.Context, eventID int64, eventDate time.Time, offset time.Duration) (bool, error)
+	ReleaseReminder(ctx context.Context, eventID int64, eventDate time.Time, offset time.Duration) error
+	ListEventReminders(ctx context.Context, eventID int64) ([]time.Duration, error)
+	AddEventReminder(ctx context.Context, eventID int64, offset time.Duration) error
+	DeleteEventReminder(ctx context.Context, eventID int64, offset time.Duration) (bool, error)
+	ClearEventReminders(ctx context.Context, eventID int64) error
+}
+
+// ChatSettingsStore — настройки чатов и журнал отправленных дайджестов.
+type ChatSettingsStore interface {
+	GetChatSettings(ctx context.Context, chatID int64) (*ChatSettings, error)
+	SetChatTimezone(ctx context.Context, chatID int64, timezone string) error
+	SetChatDigest(ctx context.Context, chatID int64, d Digest) error
+	ListDigestChats(ctx context.Context) ([]ChatSettings, error)
+	ClaimDigest(ctx context.Context, chatID int64, slot time.Time) (bool, error)
+	ReleaseDigest(ctx context.Context, chatID int64, slot time.Time) error
+}
+
+// SessionStore — состояние многошаговых диалогов (календарь, ожидание ввода), общее для всех реплик бота.
+type SessionStore interface {
+	GetSession(ctx context.Context, chatID, userID int64, kind string) (*Session, error)
+	SetSession(ctx context.Context, sess *Session) error
+	DeleteSession(ctx context.Context, chatID, userID int64, kind string) error
+	DeleteExpiredSessions(ctx context.Context, now time.Time) ([]Session, error)
+	CountSessions(ctx context.Context) (map[string]int, error)
+}
+
+// PinStore — закреплённые сообщения с обратным отсчётом, которые обновляет фоновая задача.
+type PinStore interface {
+	SetPin(ctx context.Context, p *Pin) error
+	GetPin(ctx context.Context, eventID int64) (*Pin, error)
+	DeletePin(ctx context.Context, eventID int64) error
+	ClaimDuePins(ctx context.Context, now time.Time, lease time.Duration) ([]Pin, error)
+	ReschedulePin(ctx context.Context, eventID int64, next time.Time) error
+}
+
+// CollectionStore — опубликованные подборки событий и подписки чатов на них.
+type CollectionStore interface {
+	CreateCollection(ctx context.Context, c *Collection) (bool, error)
+	GetCollection(ctx context.Context, chatID int64) (*Collection, error)
+	GetCollectionByCode(ctx context.Context, code string) (*Collection, error)
+	DeleteCollection(ctx context.Context, chatID int64) (bool, error)
+	Subscribe(ctx context.Context, chatID, sourceChatID int64) (bool, error)
+	Unsubscribe(ctx context.Context, chatID, sourceChatID int64) (bool, error)
+	ListSubscriptions(ctx context.Context, chatID int64) ([]Collection, error)
+	FindSubscribedEvent(ctx context.Context, chatID int64, name string) (*Event, error)
+}
+
+// AdminStore — сводные данные по всем чатам для администратора бота.
+type AdminStore interface {
+	Stats(ctx context.Context) (*Stats, error)
+	ListChatStats(ctx context.Context, offset, limit int) ([]ChatStats, int, error)
+	ListKnownChats(ctx context.Context) ([]int64, error)
+	PurgeChat(ctx context.Context, chatID int64) (int, error)
+}
+
+// Store — полный контракт хранилища бота. Реализации: PostgresStorage, SQLiteStorage, MemoryStorage.
+type Store interface {
+	EventStore
+	ReminderStore
+	ChatSettingsStore
+	SessionStore
+	PinStore
+	CollectionStore
+	AdminStore
+
+	Ping(ctx context.Context) error
+	Close()
+}
+
+var (
+	_ Store = (*PostgresStorage)(nil)
+	_ Store = (*SQLiteStorage)(nil)
+	_ Store = (*MemoryStorage)(nil)
+)
+
+// Бэкенды хранилища, выбираемые по DATABASE_URL.
+const (
+	BackendPostgres = "postgres"
+	BackendSQLite   = "sqlite"
+	BackendMemory   = "memory"
+)
+
+// Backend возвращает бэкенд хранилища для строки подключения:
+// "memory://" — хранилище в памяти, "sqlite://" и "file:" — SQLite, всё остальное — PostgreSQL.
+func Backend(dbURL string) string {
+	switch {
+	case strings.HasPrefix(dbURL, "memory:"):
+		return BackendMemory
+	case strings.HasPrefix(dbURL, "sqlite:"), strings.HasPrefix(dbURL, "file:"):
+		return BackendSQLite
+	default:
+		return BackendPostgres
+	}
+}
+
+// Migrator — хранилище с версионируемой схемой (PostgreSQL и SQLite).
+type Migrator interface {
+	Migrate(ctx context.Context) ([]MigrationStatus, error)
+	MigrationStatus(ctx context.Context) ([]MigrationStatus, error)
+	PendingMigrations(ctx context.Context) (int, error)
+	Close()
+}
+
+var (
+	_ Migrator = (*PostgresStorage)(nil)
+	_ Migrator = (*SQLiteStorage)(nil)
+)
+
+// PoolStats — снимок состояния пула соединений с базой.
+type PoolStats struct {
+	MaxConns     int           // предел соединений
+	OpenConns    int           // открытые соединения (занятые и свободные)
+	InUseConns   int           // занятые запросами соединения
+	IdleConns    int           // свободные соединения
+	WaitCount    int64         // сколько раз запрос ждал свободного соединения
+	WaitDuration time.Duration // суммарное время этих ожиданий
+}
+
+// PoolStatter — хранилище с пулом соединений (PostgreSQL и SQLite).
+type PoolStatter interface {
+	PoolStats() PoolStats
+}
+
+var (
+	_ PoolStatter = (*PostgresStorage)(nil)
+	_ PoolStatter = (*SQLiteStorage)(nil)
+)
+
+// OpenMigrator подключается к базе без применения миграций (для команды migrate).
+// Для хранилища в памяти возвращает ошибку: схемы у него нет.
+func OpenMigrator(dbURL string, defaultLoc *time.Location) (Migrator, error) {
+	switch Backend(dbURL) {
+	case BackendMemory:
+		return nil, errors.New("in-memory storage has no schema to migrate")
+	case BackendSQLite:
+		return OpenSQLiteStorage(dbURL)
+	default:
+		return OpenPostgresStorage(dbURL, defaultLoc)
+	}
+}
+
+// Open открывает хранилище, выбранное по DATABASE_URL (см. Backend), и применяет миграции.
+// Хранилище в памяти теряет данные при перезапуске. defaultLoc — часовой пояс по умолчанию (config.Location).
+func Open(ctx context.Context, dbURL string, defaultLoc *time.Location) (Store, error) {
+	if dbURL == "" {
+		return nil, errors.New("DATABASE_URL is not set")
+	}
+
+	var s interface {
+		Store
+		Migrator
+	}
+	var err error
+	switch Backend(dbURL) {
+	case BackendMemory:
+		return NewMemoryStorage(), nil
+	case BackendSQLite:
+		s, err = OpenSQLiteStorage(dbURL)
+	default:
+		s, err = OpenPostgresStorage(dbURL, defaultLoc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Migrate(ctx); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
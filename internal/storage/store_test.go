@@ -0,0 +1,56 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TheReshkin/timer-bot/internal/storage"
+	"github.com/TheReshkin/timer-bot/internal/storage/storagetest"
+)
+
+// runContract прогоняет проверки контракта storagetest против s, каждую — отдельным подтестом.
+func runContract(t *testing.T, s storage.Store) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, r := range storagetest.Run(ctx, s) {
+		t.Run(r.Name, func(t *testing.T) {
+			if r.Err != nil {
+				t.Error(r.Err)
+			}
+		})
+	}
+}
+
+// openStore открывает хранилище по строке подключения с миграциями и закрывает его по окончании теста.
+func openStore(t *testing.T, dbURL string) storage.Store {
+	t.Helper()
+	s, err := storage.Open(context.Background(), dbURL, time.UTC)
+	if err != nil {
+		t.Fatalf("open %s: %v", storage.Backend(dbURL), err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestMemoryStorage(t *testing.T) {
+	runContract(t, storage.NewMemoryStorage())
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	runContract(t, openStore(t, "sqlite://"+filepath.Join(t.TempDir(), "bot.db")))
+}
+
+// TestPostgresStorage запускается, только если задан TEST_DATABASE_URL. Проверки работают
+// с синтетическими чатами и удаляют свои данные, но базу лучше выделить под тесты.
+func TestPostgresStorage(t *testing.T) {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL не задан")
+	}
+	runContract(t, openStore(t, dbURL))
+}